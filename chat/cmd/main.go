@@ -31,6 +31,9 @@ func main() {
 	if err := platform.AutoMigrate(dataObj.GetDB(), &user.User{}, &group.Group{}, &relation.Relation{}); err != nil {
 		log.Logger.Warn("database auto migrate failed, but continuing...", log.Any("error", err))
 	}
+	if err := platform.RunMigrations(dataObj.GetDB(), relation.GroupRolesMigration); err != nil {
+		log.Logger.Warn("data migration failed, but continuing...", log.Any("error", err))
+	}
 
 	userRepo := user.NewUserRepo(dataObj)
 	groupRepo := group.NewGroupRepo(dataObj)
@@ -45,7 +48,7 @@ func main() {
 
 	// Init Services
 	chatService := chat.NewService(chatRepo, relationRepo, groupRepo, userRepo, dataObj.GetRedisClient(), kafkaProducer)
-	userService := user.NewService(userRepo, dataObj.GetRedisClient())
+	userService := user.NewService(userRepo, dataObj.GetRedisClient(), relationRepo)
 	groupService := group.NewService(groupRepo, userRepo, relationRepo)
 	relationService := relation.NewService(relationRepo, userRepo, groupRepo, convCreator)
 
@@ -60,6 +63,13 @@ func main() {
 	syncConsumer := mq.InitConsumer(cfg.Kafka.Topics.Sync_request, "logic_sync_group")
 	go mq.StartConsumer(ctx, syncConsumer, chatService.ProcessSyncRequest)
 
+	// 瞬时信号消费者（正在输入）
+	signalConsumer := mq.InitConsumer(cfg.Kafka.Topics.Signal, "logic_signal_group")
+	go mq.StartConsumer(ctx, signalConsumer, chatService.ProcessSignal)
+
+	// 定时消息调度器
+	go chatService.RunScheduler(ctx)
+
 	// Init Router
 	uHandler := user.NewHandler(userService)
 	gHandler := group.NewHandler(groupService)
@@ -41,6 +41,7 @@ var ChatSet = wire.NewSet(
 // RelationSet 聚合 Relation 模块
 var RelationSet = wire.NewSet(
 	relation.NewRelationRepo,
+	wire.Bind(new(user.ContactFinder), new(relation.Repository)),
 	relation.NewService,
 	relation.NewHandler,
 )
@@ -52,6 +53,7 @@ func InitializeApp() (*gin.Engine, func(), error) {
 		config.GetConfig,
 		platform.NewData, // 返回 (*Data, func(), error)
 		mq.InitProducer,
+		wire.Bind(new(mq.MessageProducer), new(*mq.Producer)),
 
 		// 2. 从 Data 提取子依赖 (Helper functions)
 		// Wire 无法自动识别 data.GetRedisClient 这种方法，需要显式转换或提供 getter
@@ -0,0 +1,66 @@
+package chat
+
+import (
+	pb "MyGoChat/pkg/api/v1"
+	"MyGoChat/pkg/log"
+)
+
+// ackMessage 消息入库成功后向发送者推送 ACK，携带服务端分配的 ID、序号与时间
+// 只有携带 ClientMsgID 的消息（来自 WebSocket 客户端）才需要确认
+func (s *Service) ackMessage(msg *pb.Message) {
+	if msg.ClientMsgID == "" || msg.SenderUUID == "" {
+		return
+	}
+	s.sendAck(msg, pb.EventTypeAck, &pb.Ack{
+		ClientMsgID:    msg.ClientMsgID,
+		MessageID:      msg.Id,
+		ConversationID: msg.ConversationID,
+		Seq:            msg.Seq,
+		SendAt:         msg.SendAt,
+	})
+}
+
+// nackMessage 消息被拒绝（校验失败、入库失败等）时向发送者推送 NACK
+func (s *Service) nackMessage(msg *pb.Message, reason string) {
+	if msg.ClientMsgID == "" || msg.SenderUUID == "" {
+		return
+	}
+	s.sendAck(msg, pb.EventTypeNack, &pb.Ack{
+		ClientMsgID:    msg.ClientMsgID,
+		ConversationID: msg.ConversationID,
+		Reason:         reason,
+	})
+}
+
+// nackCommand WebSocket 命令（撤回、编辑等）执行失败时向发起命令的设备推送 NACK
+// requestID 作为 ClientMsgID 返回，客户端据此对应自己的请求
+func (s *Service) nackCommand(userUUID, deviceID, requestID, msgID string, err error) {
+	if requestID == "" {
+		return
+	}
+	s.sendAck(&pb.Message{SenderUUID: userUUID, SenderDeviceID: deviceID}, pb.EventTypeNack, &pb.Ack{
+		ClientMsgID: requestID,
+		MessageID:   msgID,
+		Reason:      err.Error(),
+	})
+}
+
+// ackCommand WebSocket 命令执行成功时向发起命令的设备推送 ACK，requestID 作为 ClientMsgID 返回
+func (s *Service) ackCommand(userUUID, deviceID, requestID string, ack *pb.Ack) {
+	if requestID == "" {
+		return
+	}
+	ack.ClientMsgID = requestID
+	s.sendAck(&pb.Message{SenderUUID: userUUID, SenderDeviceID: deviceID}, pb.EventTypeAck, ack)
+}
+
+func (s *Service) sendAck(msg *pb.Message, eventType int32, ack *pb.Ack) {
+	event, err := newEventMessage(msg.ConversationID, msg.SenderUUID, msg.MessageType, eventType, ack)
+	if err != nil {
+		log.Logger.Sugar().Errorf("Failed to build ack for %s: %v", msg.ClientMsgID, err)
+		return
+	}
+	// ACK 只发给发出消息的设备；离线时客户端会在重连后重发并重新获得确认
+	event.TargetDeviceID = msg.SenderDeviceID
+	s.routeToUser(msg.SenderUUID, event, false)
+}
@@ -0,0 +1,94 @@
+package chat
+
+import (
+	pb "MyGoChat/pkg/api/v1"
+	"MyGoChat/pkg/log"
+	"MyGoChat/pkg/route"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// newAckTestService 创建发送者 sender 的 phone 设备在线的 Service，推送给该设备的事件记录在 MockProducer 中
+func newAckTestService(t *testing.T, repo *MockRepository) (*Service, *MockProducer) {
+	if log.Logger == nil {
+		log.Logger = zap.NewNop()
+	}
+	ctx := context.Background()
+	_, rdb := newTestRedis(t)
+	require.NoError(t, route.Add(ctx, rdb, "sender", route.Route{GatewayID: "gw-1", Instance: "instance-1", DeviceID: "phone"}))
+	require.NoError(t, route.KeepAlive(ctx, rdb, "gw-1", "instance-1"))
+
+	producer := &MockProducer{}
+	producer.On("SendMessageWithKey", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	return &Service{repo: repo, redis: rdb, producer: producer}, producer
+}
+
+// newIngestMessage 构造经 Ingest Topic 到达的私聊文本消息，modify 可以在序列化前修改消息
+func newIngestMessage(t *testing.T, clientMsgID string, modify ...func(*pb.Message)) kafka.Message {
+	body, err := anypb.New(&pb.TextBody{Content: "hello"})
+	require.NoError(t, err)
+	msg := &pb.Message{
+		ConversationID: "conv-1",
+		SenderUUID:     "sender",
+		SenderName:     "sender",
+		SenderDeviceID: "phone",
+		RecipientUUID:  "recipient",
+		MessageType:    1,
+		ContentType:    1,
+		ClientMsgID:    clientMsgID,
+		Body:           body,
+	}
+	for _, m := range modify {
+		m(msg)
+	}
+	data, err := proto.Marshal(msg)
+	require.NoError(t, err)
+	return kafka.Message{Value: data}
+}
+
+// sentAcks 解析推送给发送设备的 ACK / NACK 事件
+func sentAcks(t *testing.T, producer *MockProducer) []*pb.Message {
+	var events []*pb.Message
+	for _, sent := range producer.SentMessages {
+		var event pb.Message
+		require.NoError(t, proto.Unmarshal(sent.Message, &event))
+		if event.EventType == pb.EventTypeAck || event.EventType == pb.EventTypeNack {
+			events = append(events, &event)
+		}
+	}
+	return events
+}
+
+// TestProcessMessage_NackOnInsertFailure 测试消息入库失败时向发送设备推送 NACK，且不做任何投递
+func TestProcessMessage_NackOnInsertFailure(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockRepo.On("AllocateSeq", mock.Anything, "conv-1").Return(int64(3), nil)
+	mockRepo.On("GetMessageTTL", mock.Anything, "conv-1").Return(int64(0), nil)
+	mockRepo.On("CreateMsg", mock.Anything, mock.Anything).Return(errors.New("mongo unavailable"))
+	service, producer := newAckTestService(t, mockRepo)
+
+	err := service.ProcessMessage(context.Background(), newIngestMessage(t, "client-1"))
+	assert.Error(t, err)
+
+	events := sentAcks(t, producer)
+	require.Len(t, events, 1)
+	assert.Equal(t, pb.EventTypeNack, events[0].EventType)
+	assert.Equal(t, "phone", events[0].TargetDeviceID)
+
+	var ack pb.Ack
+	require.NoError(t, events[0].Body.UnmarshalTo(&ack))
+	assert.Equal(t, "client-1", ack.ClientMsgID)
+	assert.NotEmpty(t, ack.Reason)
+	assert.Len(t, producer.SentMessages, 1, "failed messages must not be delivered")
+
+	mockRepo.AssertNotCalled(t, "UpdateLastMessage", mock.Anything, mock.Anything, mock.Anything)
+}
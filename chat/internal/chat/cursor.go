@@ -0,0 +1,61 @@
+package chat
+
+import (
+	"errors"
+	"strconv"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid message cursor")
+	ErrMixedCursor   = errors.New("before and after cursors must be of the same kind")
+)
+
+// MessageCursor 历史消息分页游标
+// 优先使用会话内序号 Seq；没有序号的历史数据可以退化为 ObjectID 游标
+type MessageCursor struct {
+	Seq int64
+	ID  primitive.ObjectID
+}
+
+// ParseMessageCursor 解析客户端传入的游标：纯数字视为 Seq，24 位十六进制视为 ObjectID，空串表示不限
+func ParseMessageCursor(raw string) (MessageCursor, error) {
+	if raw == "" {
+		return MessageCursor{}, nil
+	}
+
+	if seq, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		if seq <= 0 {
+			return MessageCursor{}, ErrInvalidCursor
+		}
+		return MessageCursor{Seq: seq}, nil
+	}
+
+	id, err := primitive.ObjectIDFromHex(raw)
+	if err != nil {
+		return MessageCursor{}, ErrInvalidCursor
+	}
+	return MessageCursor{ID: id}, nil
+}
+
+// IsZero 游标是否为空
+func (c MessageCursor) IsZero() bool {
+	return c.Seq == 0 && c.ID.IsZero()
+}
+
+// IsID 是否为 ObjectID 游标
+func (c MessageCursor) IsID() bool {
+	return !c.ID.IsZero()
+}
+
+// String 返回可直接回传给客户端的游标字符串
+func (c MessageCursor) String() string {
+	if c.IsID() {
+		return c.ID.Hex()
+	}
+	if c.Seq > 0 {
+		return strconv.FormatInt(c.Seq, 10)
+	}
+	return ""
+}
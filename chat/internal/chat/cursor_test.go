@@ -0,0 +1,153 @@
+package chat
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TestParseMessageCursor 测试游标解析：数字为 Seq，24 位十六进制为 ObjectID
+func TestParseMessageCursor(t *testing.T) {
+	empty, err := ParseMessageCursor("")
+	assert.NoError(t, err)
+	assert.True(t, empty.IsZero())
+
+	seqCursor, err := ParseMessageCursor("42")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(42), seqCursor.Seq)
+	assert.False(t, seqCursor.IsID())
+	assert.Equal(t, "42", seqCursor.String())
+
+	id := primitive.NewObjectID()
+	idCursor, err := ParseMessageCursor(id.Hex())
+	assert.NoError(t, err)
+	assert.True(t, idCursor.IsID())
+	assert.Equal(t, id.Hex(), idCursor.String())
+
+	_, err = ParseMessageCursor("0")
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+
+	_, err = ParseMessageCursor("not-a-cursor")
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+}
+
+// TestHistoryQuery_Validate 测试 before/after 游标类型必须一致
+func TestHistoryQuery_Validate(t *testing.T) {
+	query := HistoryQuery{
+		Before: MessageCursor{Seq: 10},
+		After:  MessageCursor{ID: primitive.NewObjectID()},
+	}
+	assert.ErrorIs(t, query.Validate(), ErrMixedCursor)
+
+	query.After = MessageCursor{Seq: 2}
+	assert.NoError(t, query.Validate())
+}
+
+// TestGetMessageHistory_NextCursor 测试分页时多取一条判断 has_more，并以最后一条的 Seq 作为下一页游标
+func TestGetMessageHistory_NextCursor(t *testing.T) {
+	ctx := context.Background()
+	convID := "conv-1"
+
+	mockRepo := new(MockRepository)
+	mockRepo.On("GetByConversation", mock.Anything, convID, HistoryQuery{Limit: 3}).
+		Return([]*Message{{Seq: 9}, {Seq: 8}, {Seq: 7}}, nil)
+
+	mockRepo.On("GetReactionSummaries", mock.Anything, mock.Anything, "user-1").
+		Return(map[string][]*ReactionSummary{}, nil)
+
+	service := &Service{repo: mockRepo}
+
+	page, err := service.GetMessageHistory(ctx, "user-1", convID, HistoryQuery{Limit: 2})
+	assert.NoError(t, err)
+	assert.Len(t, page.Messages, 2)
+	assert.True(t, page.HasMore)
+	assert.Equal(t, "8", page.NextCursor, "next cursor should be the seq of the last returned message")
+
+	mockRepo.AssertExpectations(t)
+}
+
+// TestGetMessageHistory_LastPage 测试最后一页不返回游标
+func TestGetMessageHistory_LastPage(t *testing.T) {
+	ctx := context.Background()
+	convID := "conv-1"
+	query := HistoryQuery{Before: MessageCursor{Seq: 3}, Limit: 5}
+
+	mockRepo := new(MockRepository)
+	mockRepo.On("GetByConversation", mock.Anything, convID, mock.Anything).
+		Return([]*Message{{Seq: 2}, {Seq: 1}}, nil)
+	mockRepo.On("GetUnsequenced", mock.Anything, convID, false, 4).
+		Return([]*Message{}, nil)
+
+	mockRepo.On("GetReactionSummaries", mock.Anything, mock.Anything, "user-1").
+		Return(map[string][]*ReactionSummary{}, nil)
+
+	service := &Service{repo: mockRepo}
+
+	page, err := service.GetMessageHistory(ctx, "user-1", convID, query)
+	assert.NoError(t, err)
+	assert.Len(t, page.Messages, 2)
+	assert.False(t, page.HasMore)
+	assert.Empty(t, page.NextCursor)
+}
+
+// TestGetMessageHistory_AfterSkipsUnsequenced 测试带 after 倒序翻页时，较新的消息不足一页也不补没有序号的历史消息
+func TestGetMessageHistory_AfterSkipsUnsequenced(t *testing.T) {
+	ctx := context.Background()
+	convID := "conv-1"
+	query := HistoryQuery{After: MessageCursor{Seq: 5}, Limit: 5}
+
+	mockRepo := new(MockRepository)
+	mockRepo.On("GetByConversation", mock.Anything, convID, mock.Anything).
+		Return([]*Message{{Seq: 7}, {Seq: 6}}, nil)
+	mockRepo.On("GetReactionSummaries", mock.Anything, mock.Anything, "user-1").
+		Return(map[string][]*ReactionSummary{}, nil)
+
+	service := &Service{repo: mockRepo}
+
+	page, err := service.GetMessageHistory(ctx, "user-1", convID, query)
+	assert.NoError(t, err)
+	assert.Equal(t, []*Message{{Seq: 7}, {Seq: 6}}, page.Messages)
+	assert.False(t, page.HasMore)
+
+	mockRepo.AssertNotCalled(t, "GetUnsequenced", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	mockRepo.AssertExpectations(t)
+}
+
+// TestGetMessageHistory_Unsequenced 测试有序号的消息翻完后补上没有序号的历史消息，并以 ObjectID 继续翻页
+func TestGetMessageHistory_Unsequenced(t *testing.T) {
+	ctx := context.Background()
+	convID := "conv-1"
+	legacy := []*Message{{ID: primitive.NewObjectID()}, {ID: primitive.NewObjectID()}}
+
+	mockRepo := new(MockRepository)
+	mockRepo.On("GetByConversation", mock.Anything, convID, HistoryQuery{Limit: 3}).
+		Return([]*Message{{Seq: 1}}, nil)
+	mockRepo.On("GetUnsequenced", mock.Anything, convID, false, 2).
+		Return(legacy, nil)
+	mockRepo.On("GetReactionSummaries", mock.Anything, mock.Anything, "user-1").
+		Return(map[string][]*ReactionSummary{}, nil)
+
+	service := &Service{repo: mockRepo}
+
+	page, err := service.GetMessageHistory(ctx, "user-1", convID, HistoryQuery{Limit: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, []*Message{{Seq: 1}, legacy[0]}, page.Messages)
+	assert.True(t, page.HasMore)
+	assert.Equal(t, legacy[0].ID.Hex(), page.NextCursor, "unsequenced messages continue with an ObjectID cursor")
+
+	// 不带 after 正序从头翻页时，历史消息排在最前
+	mockRepo.On("GetByConversation", mock.Anything, convID, HistoryQuery{Ascending: true, Limit: 3}).
+		Return([]*Message{{Seq: 1}, {Seq: 2}}, nil)
+	mockRepo.On("GetUnsequenced", mock.Anything, convID, true, 3).
+		Return(legacy, nil)
+
+	page, err = service.GetMessageHistory(ctx, "user-1", convID, HistoryQuery{Ascending: true, Limit: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, legacy, page.Messages)
+	assert.Equal(t, legacy[1].ID.Hex(), page.NextCursor)
+
+	mockRepo.AssertExpectations(t)
+}
@@ -0,0 +1,75 @@
+package chat
+
+import (
+	pb "MyGoChat/pkg/api/v1"
+	"MyGoChat/pkg/log"
+	"context"
+	"time"
+)
+
+// 幂等去重：Kafka 重放与客户端重发都可能让同一条消息多次进入 ProcessMessage。
+// 以 (SenderUUID, ClientMsgID) 为幂等键：
+//   - Redis "msg_dedup:{sender}:{clientMsgID}" -> 消息ID，作为快速路径，在分配序号前拦截重复消息
+//   - MongoDB (senderUUID, clientMsgID) 唯一索引兜底，Redis 失效或过期时由 CreateMsg 识别重复
+const (
+	dedupKeyPrefix = "msg_dedup:"
+	dedupTTL       = time.Hour
+)
+
+func dedupKey(senderUUID, clientMsgID string) string {
+	return dedupKeyPrefix + senderUUID + ":" + clientMsgID
+}
+
+// findProcessed 查找已经入库的同一条消息，未找到返回 nil
+func (s *Service) findProcessed(ctx context.Context, msg *pb.Message) *Message {
+	if msg.ClientMsgID == "" {
+		return nil
+	}
+
+	msgID, err := s.redis.Get(ctx, dedupKey(msg.SenderUUID, msg.ClientMsgID)).Result()
+	if err != nil {
+		return nil
+	}
+
+	existing, err := s.repo.GetMessageByID(ctx, msgID)
+	if err != nil {
+		return nil
+	}
+	return existing
+}
+
+// markProcessed 消息入库后记录幂等键
+func (s *Service) markProcessed(ctx context.Context, msg *pb.Message) {
+	if msg.ClientMsgID == "" {
+		return
+	}
+	if err := s.redis.Set(ctx, dedupKey(msg.SenderUUID, msg.ClientMsgID), msg.Id, dedupTTL).Err(); err != nil {
+		log.Logger.Sugar().Warnf("Failed to set dedup key for %s: %v", msg.ClientMsgID, err)
+	}
+}
+
+// resolveDuplicate CreateMsg 报告重复后，找回已入库的消息
+// 按消息 ID 找到的消息必须属于同一发送者，否则不能把他人的消息当作重复消息确认
+func (s *Service) resolveDuplicate(ctx context.Context, msg *pb.Message) *Message {
+	if msg.ClientMsgID != "" {
+		if existing, err := s.repo.GetMessageByClientMsgID(ctx, msg.SenderUUID, msg.ClientMsgID); err == nil {
+			return existing
+		}
+	}
+	if existing, err := s.repo.GetMessageByID(ctx, msg.Id); err == nil && existing.SenderUUID == msg.SenderUUID {
+		return existing
+	}
+	return nil
+}
+
+// ackDuplicate 重复消息不再入库与扇出，只按已入库的结果重新确认发送端
+func (s *Service) ackDuplicate(ctx context.Context, msg *pb.Message, existing *Message) {
+	msg.Id = existing.ID.Hex()
+	msg.Seq = existing.Seq
+	msg.SendAt = existing.SendAt
+
+	s.markProcessed(ctx, msg)
+	s.ackMessage(msg)
+	log.Logger.Sugar().Infof("Duplicate message ignored: sender=%s, clientMsgID=%s, id=%s",
+		msg.SenderUUID, msg.ClientMsgID, msg.Id)
+}
@@ -0,0 +1,83 @@
+package chat
+
+import (
+	pb "MyGoChat/pkg/api/v1"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TestProcessMessage_DuplicateReacked 测试已处理过的消息重新投递时只按原结果重新 ACK，不再分配序号
+func TestProcessMessage_DuplicateReacked(t *testing.T) {
+	ctx := context.Background()
+	existing := &Message{ID: primitive.NewObjectID(), ConversationID: "conv-1", SenderUUID: "sender", Seq: 5, SendAt: 100}
+
+	mockRepo := new(MockRepository)
+	mockRepo.On("GetMessageByID", mock.Anything, existing.ID.Hex()).Return(existing, nil)
+	service, producer := newAckTestService(t, mockRepo)
+	require.NoError(t, service.redis.Set(ctx, dedupKey("sender", "client-1"), existing.ID.Hex(), dedupTTL).Err())
+
+	assert.NoError(t, service.ProcessMessage(ctx, newIngestMessage(t, "client-1")))
+
+	events := sentAcks(t, producer)
+	require.Len(t, events, 1)
+	assert.Equal(t, pb.EventTypeAck, events[0].EventType)
+	var ack pb.Ack
+	require.NoError(t, events[0].Body.UnmarshalTo(&ack))
+	assert.Equal(t, existing.ID.Hex(), ack.MessageID)
+	assert.Equal(t, int64(5), ack.Seq)
+
+	mockRepo.AssertNotCalled(t, "AllocateSeq", mock.Anything, mock.Anything)
+	mockRepo.AssertNotCalled(t, "CreateMsg", mock.Anything, mock.Anything)
+}
+
+// TestProcessMessage_DuplicateOnInsert 测试 Redis 漏掉的重复消息由唯一索引识别，按已入库的消息 ACK 且不再投递
+func TestProcessMessage_DuplicateOnInsert(t *testing.T) {
+	ctx := context.Background()
+	existing := &Message{ID: primitive.NewObjectID(), ConversationID: "conv-1", SenderUUID: "sender", Seq: 5, SendAt: 100}
+
+	mockRepo := new(MockRepository)
+	mockRepo.On("AllocateSeq", mock.Anything, "conv-1").Return(int64(6), nil)
+	mockRepo.On("GetMessageTTL", mock.Anything, "conv-1").Return(int64(0), nil)
+	mockRepo.On("CreateMsg", mock.Anything, mock.Anything).Return(ErrDuplicateMessage)
+	mockRepo.On("GetMessageByClientMsgID", mock.Anything, "sender", "client-1").Return(existing, nil)
+	service, producer := newAckTestService(t, mockRepo)
+
+	assert.NoError(t, service.ProcessMessage(ctx, newIngestMessage(t, "client-1")))
+
+	events := sentAcks(t, producer)
+	require.Len(t, events, 1)
+	assert.Equal(t, pb.EventTypeAck, events[0].EventType)
+	var ack pb.Ack
+	require.NoError(t, events[0].Body.UnmarshalTo(&ack))
+	assert.Equal(t, int64(5), ack.Seq)
+	assert.Len(t, producer.SentMessages, 1, "duplicates must not be delivered again")
+
+	// 之后的重复投递走 Redis 快速路径
+	msgID, err := service.redis.Get(ctx, dedupKey("sender", "client-1")).Result()
+	assert.NoError(t, err)
+	assert.Equal(t, existing.ID.Hex(), msgID)
+	mockRepo.AssertNotCalled(t, "UpdateLastMessage", mock.Anything, mock.Anything, mock.Anything)
+}
+
+// TestResolveDuplicate_RequiresSameSender 测试按消息 ID 找到的他人消息不会被当作重复消息
+func TestResolveDuplicate_RequiresSameSender(t *testing.T) {
+	ctx := context.Background()
+	other := &Message{ID: primitive.NewObjectID(), SenderUUID: "someone-else", Seq: 9}
+
+	mockRepo := new(MockRepository)
+	mockRepo.On("GetMessageByClientMsgID", mock.Anything, "sender", "client-1").Return(nil, errors.New("not found"))
+	mockRepo.On("GetMessageByID", mock.Anything, other.ID.Hex()).Return(other, nil)
+	service := &Service{repo: mockRepo}
+
+	msg := &pb.Message{Id: other.ID.Hex(), SenderUUID: "sender", ClientMsgID: "client-1"}
+	assert.Nil(t, service.resolveDuplicate(ctx, msg))
+
+	other.SenderUUID = "sender"
+	assert.Equal(t, other, service.resolveDuplicate(ctx, msg))
+}
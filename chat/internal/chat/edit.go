@@ -0,0 +1,132 @@
+package chat
+
+import (
+	pb "MyGoChat/pkg/api/v1"
+	"MyGoChat/chat/internal/relation"
+	"MyGoChat/pkg/log"
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+var (
+	ErrEditForbidden      = errors.New("only the sender can edit the message")
+	ErrMessageNotEditable = errors.New("message cannot be edited")
+	ErrEmptyContent       = errors.New("content is required")
+)
+
+// checkEdit 校验编辑权限：只有发送者本人可以编辑自己未撤回的文本消息
+func checkEdit(msg *Message, senderUUID string) error {
+	if msg.SenderUUID != senderUUID {
+		return ErrEditForbidden
+	}
+	if msg.Recalled || msg.ContentType != 1 {
+		return ErrMessageNotEditable
+	}
+	return nil
+}
+
+// EditMessage 编辑文本消息：旧内容保存为历史版本，消息标记为已编辑，
+// 必要时更新会话的最后一条消息，然后向会话所有成员推送编辑事件
+func (s *Service) EditMessage(ctx context.Context, senderUUID, msgID, content string) (*Message, error) {
+	if strings.TrimSpace(content) == "" {
+		return nil, ErrEmptyContent
+	}
+
+	msg, err := s.repo.GetMessageByID(ctx, msgID)
+	if err != nil {
+		return nil, ErrMessageNotFound
+	}
+	if err := checkEdit(msg, senderUUID); err != nil {
+		return nil, err
+	}
+
+	rel, err := s.relRepo.GetRelationByConversation(ctx, senderUUID, msg.ConversationID)
+	if err != nil {
+		return nil, ErrNotMember
+	}
+
+	// 内容没有变化，不产生新版本
+	if current, ok := msg.Body.(string); ok && current == content {
+		return msg, nil
+	}
+
+	editedAt := time.Now().Unix()
+	if _, err := s.repo.EditMessage(ctx, msg.ID, senderUUID, content, editedAt); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			// 读取之后消息被撤回
+			return nil, ErrMessageNotEditable
+		}
+		return nil, err
+	}
+	msg.Body = content
+	msg.Edited = true
+	msg.EditedAt = editedAt
+
+	if err := s.repo.ReplaceLastMessage(ctx, msg.ConversationID, msgID, content); err != nil {
+		log.Logger.Sugar().Warnf("Failed to update last message of conversation %s: %v", msg.ConversationID, err)
+	}
+
+	s.pushEdit(ctx, rel, msg)
+
+	log.Logger.Sugar().Infof("Message edited: id=%s, sender=%s", msgID, senderUUID)
+	return msg, nil
+}
+
+// GetRevisions 获取消息的历史版本，只有会话成员可以查看
+func (s *Service) GetRevisions(ctx context.Context, userUUID, msgID string) ([]*MessageRevision, error) {
+	msg, err := s.repo.GetMessageByID(ctx, msgID)
+	if err != nil {
+		return nil, ErrMessageNotFound
+	}
+	if _, err := s.relRepo.GetRelationByConversation(ctx, userUUID, msg.ConversationID); err != nil {
+		return nil, ErrNotMember
+	}
+	return s.repo.GetRevisions(ctx, msgID)
+}
+
+// pushEdit 向会话所有成员（包括发送者的所有设备）推送编辑事件，客户端据此原地更新消息
+// 离线成员的编辑事件存入离线队列，排在原消息之后
+func (s *Service) pushEdit(ctx context.Context, rel *relation.Relation, msg *Message) {
+	targetUsers, err := s.conversationMembers(ctx, rel)
+	if err != nil {
+		log.Logger.Sugar().Errorf("pushEdit: failed to get members of %s: %v", msg.ConversationID, err)
+		return
+	}
+
+	body, err := anypb.New(&pb.TextBody{Content: msg.Body.(string)})
+	if err != nil {
+		log.Logger.Sugar().Errorf("pushEdit: failed to pack body: %v", err)
+		return
+	}
+	edit := &pb.MessageEdit{
+		ConversationID: msg.ConversationID,
+		MessageID:      msg.ID.Hex(),
+		Seq:            msg.Seq,
+		SenderUUID:     msg.SenderUUID,
+		Body:           body,
+		EditedAt:       msg.EditedAt,
+	}
+	for _, userUUID := range targetUsers {
+		event, err := newEventMessage(msg.ConversationID, userUUID, int32(rel.Type), pb.EventTypeEdit, edit)
+		if err != nil {
+			log.Logger.Sugar().Errorf("pushEdit: failed to build edit event: %v", err)
+			return
+		}
+		s.routeToUser(userUUID, event, true)
+	}
+}
+
+// editFromDevice 处理 WebSocket 编辑命令，结果以 ACK / NACK 推送给发起命令的设备
+func (s *Service) editFromDevice(ctx context.Context, userUUID, deviceID, requestID, msgID, content string) {
+	if _, err := s.EditMessage(ctx, userUUID, msgID, content); err != nil {
+		log.Logger.Sugar().Warnf("Failed to edit message %s for user %s: %v", msgID, userUUID, err)
+		s.nackCommand(userUUID, deviceID, requestID, msgID, err)
+		return
+	}
+	s.ackCommand(userUUID, deviceID, requestID, &pb.Ack{MessageID: msgID})
+}
@@ -0,0 +1,20 @@
+package chat
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCheckEdit 测试只有发送者可以编辑未撤回的文本消息
+func TestCheckEdit(t *testing.T) {
+	msg := &Message{SenderUUID: "sender-uuid", ContentType: 1, Body: "hello"}
+	assert.NoError(t, checkEdit(msg, "sender-uuid"))
+	assert.ErrorIs(t, checkEdit(msg, "other-uuid"), ErrEditForbidden)
+
+	image := &Message{SenderUUID: "sender-uuid", ContentType: 2}
+	assert.ErrorIs(t, checkEdit(image, "sender-uuid"), ErrMessageNotEditable)
+
+	recalled := &Message{SenderUUID: "sender-uuid", ContentType: 1, Recalled: true}
+	assert.ErrorIs(t, checkEdit(recalled, "sender-uuid"), ErrMessageNotEditable)
+}
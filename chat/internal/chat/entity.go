@@ -15,8 +15,33 @@ type Conversation struct {
 	// 会话信息
 	LastMessage          interface{} `bson:"lastMessage,omitempty" json:"LastMessage,omitempty"` // 最后一条消息内容
 	LastMessageTimestamp int64       `bson:"lastMessageTimestamp" json:"LastMessageTimestamp"`   // 最后消息时间戳
+	MaxSeq               int64       `bson:"maxSeq" json:"MaxSeq"`                               // 当前已分配的最大消息序号
+	UnreadCount          int64       `bson:"-" json:"UnreadCount"`                               // 当前用户的未读数，不存入MongoDB
 	CreatedAt            time.Time   `bson:"createdAt" json:"CreatedAt"`                         // 会话创建时间
 	UpdatedAt            time.Time   `bson:"updatedAt" json:"UpdatedAt"`                         // 会话更新时间
+
+	// 最后一条消息的 ID 与内容类型；撤回消息时据此判断是否需要更新 LastMessage
+	LastMessageID   string `bson:"lastMessageID,omitempty" json:"LastMessageID,omitempty"`
+	LastMessageType int16  `bson:"lastMessageType,omitempty" json:"LastMessageType,omitempty"`
+
+	// 置顶消息，按置顶先后排列
+	Pins []*Pin `bson:"pins,omitempty" json:"Pins,omitempty"`
+
+	// 消息自动删除时长（秒），0 表示关闭；只影响设置之后发送的消息
+	MessageTTL int64 `bson:"messageTTL,omitempty" json:"MessageTTL,omitempty"`
+}
+
+// Pin 会话中的一条置顶消息
+type Pin struct {
+	MessageID string `bson:"messageID" json:"MessageID"`
+	PinnedBy  string `bson:"pinnedBy" json:"PinnedBy"` // 执行置顶的用户
+	PinnedAt  int64  `bson:"pinnedAt" json:"PinnedAt"`
+}
+
+// PinnedMessage 置顶信息及被置顶的消息
+type PinnedMessage struct {
+	Pin
+	Message *Message `json:"Message"`
 }
 
 type Message struct {
@@ -25,10 +50,117 @@ type Message struct {
 	SenderUUID     string             `bson:"senderUUID" json:"SenderUUID"`         // 发送者 UUID
 	SenderName     string             `bson:"senderName" json:"SenderName"`         // 发送者用户名
 	SendAt         int64              `bson:"sendAt" json:"SendAt"`                 // 发送时间戳
-	ContentType    int16              `bson:"contentType" json:"ContentType"`       // 1=text, 2=image, 3=file, 4=voice
+	Seq            int64              `bson:"seq" json:"Seq"`                       // 会话内单调递增序号，可作为同步游标
+	ContentType    int16              `bson:"contentType" json:"ContentType"`       // 1=text, 2=image, 3=file, 4=voice, 5=系统通知
 	Body           any                `bson:"body" json:"Body"`                     // 消息内容
 	Metadata       *MessageMetadata   `bson:"metadata,omitempty" json:"Metadata,omitempty"`
 	DeletedAt      *time.Time         `bson:"deletedAt,omitempty" json:"DeletedAt,omitempty"`
+
+	// 客户端生成的消息ID，与 SenderUUID 一起作为幂等键；HTTP 发送的消息没有该字段
+	ClientMsgID string `bson:"clientMsgID,omitempty" json:"ClientMsgID,omitempty"`
+
+	// 撤回信息：撤回后消息体被清空，只保留占位
+	Recalled   bool   `bson:"recalled,omitempty" json:"Recalled,omitempty"`
+	RecalledAt int64  `bson:"recalledAt,omitempty" json:"RecalledAt,omitempty"`
+	RecalledBy string `bson:"recalledBy,omitempty" json:"RecalledBy,omitempty"` // 执行撤回的用户（发送者本人或群管理员）
+
+	// 编辑信息：只有文本消息可以编辑，编辑前的版本保存在 message_revisions 集合
+	Edited   bool  `bson:"edited,omitempty" json:"Edited,omitempty"`
+	EditedAt int64 `bson:"editedAt,omitempty" json:"EditedAt,omitempty"`
+
+	// 表情回应汇总，查询时从 message_reactions 集合聚合，不存入MongoDB
+	Reactions []*ReactionSummary `bson:"-" json:"Reactions,omitempty"`
+
+	// 话题信息：只有话题根消息才有，每条话题回复入库后更新
+	ThreadReplyCount   int64    `bson:"threadReplyCount,omitempty" json:"ThreadReplyCount,omitempty"`
+	ThreadLastReplyAt  int64    `bson:"threadLastReplyAt,omitempty" json:"ThreadLastReplyAt,omitempty"`
+	ThreadParticipants []string `bson:"threadParticipants,omitempty" json:"-"` // 根消息发送者、回复过或在回复中被 @ 的成员
+
+	// 过期时间：会话开启了消息自动删除时由发送时间加上会话的 MessageTTL 得到，到期后由 TTL 索引删除
+	ExpireAt *time.Time `bson:"expireAt,omitempty" json:"ExpireAt,omitempty"`
+}
+
+// ContentTypeSystem 系统通知（如修改消息自动删除设置），消息体与文本消息相同，只能由服务端生成
+const ContentTypeSystem = 5
+
+// IsThreadReply 是否为话题回复
+func (m *Message) IsThreadReply() bool {
+	return m.Metadata != nil && m.Metadata.ThreadRootID != ""
+}
+
+// Reaction 用户对消息的一个表情回应，同一用户对同一消息的同一表情只有一条
+type Reaction struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"ID"`
+	MessageID      string             `bson:"messageID" json:"MessageID"`
+	ConversationID string             `bson:"conversationID" json:"ConversationID"`
+	UserUUID       string             `bson:"userUUID" json:"UserUUID"`
+	Emoji          string             `bson:"emoji" json:"Emoji"`
+	CreatedAt      time.Time          `bson:"createdAt" json:"CreatedAt"`
+}
+
+// ReactionSummary 消息上某个表情的回应汇总
+type ReactionSummary struct {
+	Emoji       string `bson:"emoji" json:"Emoji"`
+	Count       int64  `bson:"count" json:"Count"`
+	ReactedByMe bool   `bson:"reactedByMe" json:"ReactedByMe"` // 当前用户是否回应了该表情
+}
+
+// MessageRevision 消息被编辑前的一个历史版本
+type MessageRevision struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"ID"`
+	MessageID      string             `bson:"messageID" json:"MessageID"`
+	ConversationID string             `bson:"conversationID" json:"ConversationID"`
+	Body           any                `bson:"body" json:"Body"`             // 该版本的消息内容
+	CreatedAt      int64              `bson:"createdAt" json:"CreatedAt"`   // 该版本生效的时间（发送时间或上一次编辑时间）
+	ReplacedAt     int64              `bson:"replacedAt" json:"ReplacedAt"` // 被新版本替换的时间
+}
+
+// 定时消息的状态
+const (
+	SchedulePending  = 1 // 等待发送，可以修改或取消
+	ScheduleSending  = 2 // 已被某个实例领取，正在投递
+	ScheduleSent     = 3 // 已投递到 Ingest Topic
+	ScheduleCanceled = 4 // 已取消
+	ScheduleFailed   = 5 // 到期时校验失败（如已不是会话成员），不再重试
+)
+
+// ScheduledMessage 定时消息，到期后由调度器按普通消息投递，消息 ID 与定时消息 ID 相同
+type ScheduledMessage struct {
+	ID             primitive.ObjectID     `bson:"_id,omitempty" json:"ID"`
+	SenderUUID     string                 `bson:"senderUUID" json:"SenderUUID"`
+	ConversationID string                 `bson:"conversationID" json:"ConversationID"`
+	TargetName     string                 `bson:"targetName" json:"TargetName"`   // 好友用户名或群号
+	MessageType    int32                  `bson:"messageType" json:"MessageType"` // 1=私聊, 2=群聊
+	ContentType    int32                  `bson:"contentType" json:"ContentType"`
+	Body           map[string]interface{} `bson:"body" json:"Body"` // 与 HTTP 发送消息的 body 格式相同
+	ReplyToMsgID   string                 `bson:"replyToMsgID,omitempty" json:"ReplyToMsgID,omitempty"`
+	MentionUUIDs   []string               `bson:"mentionUUIDs,omitempty" json:"MentionUUIDs,omitempty"`
+	MentionAll     bool                   `bson:"mentionAll,omitempty" json:"MentionAll,omitempty"`
+	ThreadRootID   string                 `bson:"threadRootID,omitempty" json:"ThreadRootID,omitempty"`
+	SendAt         int64                  `bson:"sendAt" json:"SendAt"` // 计划发送时间
+	Status         int                    `bson:"status" json:"Status"`
+	Error          string                 `bson:"error,omitempty" json:"Error,omitempty"` // 投递失败的原因
+	ClaimedAt      int64                  `bson:"claimedAt,omitempty" json:"-"`           // 被调度器领取的时间
+	CreatedAt      int64                  `bson:"createdAt" json:"CreatedAt"`
+	UpdatedAt      int64                  `bson:"updatedAt" json:"UpdatedAt"`
+}
+
+// RecalledMessageText 消息撤回后会话列表中展示的最后一条消息
+const RecalledMessageText = "[消息已撤回]"
+
+// ReadCursor 用户在某个会话中的已读位置，seq 小于等于 LastReadSeq 的消息均视为已读
+type ReadCursor struct {
+	UserUUID       string    `bson:"userUUID" json:"UserUUID"`
+	ConversationID string    `bson:"conversationID" json:"ConversationID"`
+	LastReadSeq    int64     `bson:"lastReadSeq" json:"LastReadSeq"`                         // 已读到的消息序号
+	LastReadMsgID  string    `bson:"lastReadMsgID,omitempty" json:"LastReadMsgID,omitempty"` // 已读到的消息 ID
+	UpdatedAt      time.Time `bson:"updatedAt" json:"UpdatedAt"`
+}
+
+// UnreadCount 会话中已读位置之后的未读统计
+type UnreadCount struct {
+	Count  int64 // 未读消息数
+	MaxSeq int64 // 统计到的最大消息序号
 }
 
 // FileAttachment 用于 Body 字段 (当 ContentType 不是 text 时)
@@ -39,7 +171,77 @@ type FileAttachment struct {
 	MimeType string `bson:"mimeType" json:"mimeType"` // "image/jpeg"
 }
 
-// MessageMetadata 用于存储回复、@ 等元数据
+// MessageMetadata 用于存储回复、@、话题、转发等元数据
 type MessageMetadata struct {
-	ReplyToMsgID string `bson:"replyToMsgID,omitempty"` // 回复的消息 ID (用 string 存 ObjectID)
+	ReplyToMsgID string        `bson:"replyToMsgID,omitempty"`                               // 回复的消息 ID (用 string 存 ObjectID)
+	Reply        *ReplySnippet `bson:"-" json:"Reply,omitempty"`                             // 被回复消息的摘要，查询时填充，不存入MongoDB
+	MentionUUIDs []string      `bson:"mentionUUIDs,omitempty" json:"MentionUUIDs,omitempty"` // @ 的群成员
+	MentionAll   bool          `bson:"mentionAll,omitempty" json:"MentionAll,omitempty"`     // @所有人
+	ThreadRootID string        `bson:"threadRootID,omitempty" json:"ThreadRootID,omitempty"` // 话题根消息 ID，非空时为话题回复
+
+	ForwardedFrom *ForwardInfo `bson:"forwardedFrom,omitempty" json:"ForwardedFrom,omitempty"` // 转发来源
+}
+
+// ForwardInfo 被转发消息的来源，多次转发时保留最初的来源
+type ForwardInfo struct {
+	MessageID      string `bson:"messageID" json:"MessageID"`
+	ConversationID string `bson:"conversationID" json:"ConversationID"`
+	SenderUUID     string `bson:"senderUUID" json:"SenderUUID"`
+	SenderName     string `bson:"senderName" json:"SenderName"`
+	SendAt         int64  `bson:"sendAt" json:"SendAt"`
+}
+
+// ReplySnippet 被回复消息的摘要，随回复消息一起返回，客户端无需再单独查询
+type ReplySnippet struct {
+	MessageID   string `json:"MessageID"`
+	SenderUUID  string `json:"SenderUUID"`
+	SenderName  string `json:"SenderName"`
+	ContentType int16  `json:"ContentType"`
+	Preview     string `json:"Preview"` // 内容预览，已撤回时为空
+	Recalled    bool   `json:"Recalled"`
+	Seq         int64  `json:"Seq"`
+}
+
+// HistoryQuery 历史消息的游标查询条件
+type HistoryQuery struct {
+	Before    MessageCursor // 只返回早于该游标的消息，零值表示不限
+	After     MessageCursor // 只返回晚于该游标的消息，零值表示不限
+	Ascending bool          // true=从旧到新，false=从新到旧
+	Limit     int           // 单页最大条数
+}
+
+// ByID 是否按 ObjectID 分页（否则按 Seq 分页）
+func (q HistoryQuery) ByID() bool {
+	return q.Before.IsID() || q.After.IsID()
+}
+
+// Validate 校验 before/after 游标类型一致
+func (q HistoryQuery) Validate() error {
+	if !q.Before.IsZero() && !q.After.IsZero() && q.Before.IsID() != q.After.IsID() {
+		return ErrMixedCursor
+	}
+	return nil
+}
+
+// HistoryPage 一页历史消息
+type HistoryPage struct {
+	Messages   []*Message
+	HasMore    bool
+	NextCursor string // 继续翻页时使用的游标：倒序时作为 before，正序时作为 after
+}
+
+// SyncRequest 增量同步请求
+type SyncRequest struct {
+	Conversations map[string]int64 // 会话ID -> 客户端已有的最大序号；未列出的会话从已读位置开始同步
+	Limit         int              // 每个会话最多返回的消息数
+}
+
+// ConversationSync 单个会话的增量同步结果
+type ConversationSync struct {
+	ConversationID string     `json:"conversation_id"`
+	Type           int        `json:"type"`
+	Messages       []*Message `json:"messages"`
+	LastSeq        int64      `json:"last_seq"` // 本次返回的最大序号，下次同步以此为游标
+	MaxSeq         int64      `json:"max_seq"`
+	HasMore        bool       `json:"has_more"`
 }
@@ -0,0 +1,103 @@
+package chat
+
+import (
+	pb "MyGoChat/pkg/api/v1"
+	"MyGoChat/chat/internal/relation"
+	"MyGoChat/pkg/log"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// 消息自动删除可选的时长
+var messageTTLOptions = map[string]time.Duration{
+	"off": 0,
+	"1h":  time.Hour,
+	"1d":  24 * time.Hour,
+	"7d":  7 * 24 * time.Hour,
+}
+
+var (
+	ErrInvalidMessageTTL   = errors.New("message TTL must be one of off, 1h, 1d, 7d")
+	ErrMessageTTLForbidden = errors.New("no permission to change message TTL of this conversation")
+)
+
+// ParseMessageTTL 解析消息自动删除时长（off / 1h / 1d / 7d）
+func ParseMessageTTL(s string) (time.Duration, error) {
+	ttl, ok := messageTTLOptions[s]
+	if !ok {
+		return 0, ErrInvalidMessageTTL
+	}
+	return ttl, nil
+}
+
+// messageTTLLabel 系统通知中展示的时长
+func messageTTLLabel(ttl time.Duration) string {
+	if ttl%(24*time.Hour) == 0 {
+		return fmt.Sprintf("%d天", int64(ttl/(24*time.Hour)))
+	}
+	return fmt.Sprintf("%d小时", int64(ttl/time.Hour))
+}
+
+// messageTTL 获取会话的消息自动删除时长，查询失败时按关闭处理，不影响消息投递
+func (s *Service) messageTTL(ctx context.Context, conversationID string) time.Duration {
+	ttl, err := s.repo.GetMessageTTL(ctx, conversationID)
+	if err != nil {
+		log.Logger.Sugar().Warnf("Failed to get message TTL of %s: %v", conversationID, err)
+		return 0
+	}
+	return time.Duration(ttl) * time.Second
+}
+
+// SetMessageTTL 修改会话的消息自动删除时长，ttl 为 0 表示关闭
+// 设置只影响之后发送的消息；设置发生变化时在会话中发送一条系统通知
+func (s *Service) SetMessageTTL(ctx context.Context, userUUID, conversationID string, ttl time.Duration) (bool, error) {
+	rel, err := s.relRepo.GetRelationByConversation(ctx, userUUID, conversationID)
+	if err != nil {
+		return false, ErrNotMember
+	}
+	if !canManageConversation(rel) {
+		return false, ErrMessageTTLForbidden
+	}
+
+	changed, err := s.repo.SetMessageTTL(ctx, conversationID, int64(ttl/time.Second))
+	if err != nil || !changed {
+		return false, err
+	}
+
+	operatorName, _ := s.userRepo.GetUsernameByUUID(ctx, userUUID)
+	content := fmt.Sprintf("%s 关闭了消息自动删除", operatorName)
+	if ttl > 0 {
+		content = fmt.Sprintf("%s 开启了消息自动删除，新消息将在 %s 后删除", operatorName, messageTTLLabel(ttl))
+	}
+	if err := s.sendSystemNotice(ctx, rel, operatorName, content); err != nil {
+		// 设置已经生效，通知发送失败只记录日志
+		log.Logger.Sugar().Warnf("Failed to send message TTL notice to %s: %v", conversationID, err)
+	}
+
+	log.Logger.Sugar().Infof("Message TTL of %s set to %s by %s", conversationID, ttl, userUUID)
+	return true, nil
+}
+
+// sendSystemNotice 以操作者的名义在会话中发送系统通知，与普通消息一样经 Ingest Topic 入库和投递
+func (s *Service) sendSystemNotice(ctx context.Context, rel *relation.Relation, operatorName, content string) error {
+	body, err := anypb.New(&pb.TextBody{Content: content})
+	if err != nil {
+		return err
+	}
+	return s.EnqueueMessage(ctx, &pb.Message{
+		Id:             primitive.NewObjectID().Hex(),
+		ConversationID: rel.ConversationID,
+		SenderUUID:     rel.UserUUID,
+		SenderName:     operatorName,
+		RecipientUUID:  rel.TargetUUID, // 私聊是对方UUID，群聊是群UUID
+		MessageType:    int32(rel.Type),
+		ContentType:    ContentTypeSystem,
+		Body:           body,
+		SendAt:         time.Now().Unix(),
+	})
+}
@@ -0,0 +1,46 @@
+package chat
+
+import (
+	pb "MyGoChat/pkg/api/v1"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestParseMessageTTL 测试消息自动删除时长只能是 off / 1h / 1d / 7d
+func TestParseMessageTTL(t *testing.T) {
+	for s, want := range map[string]time.Duration{
+		"off": 0,
+		"1h":  time.Hour,
+		"1d":  24 * time.Hour,
+		"7d":  7 * 24 * time.Hour,
+	} {
+		ttl, err := ParseMessageTTL(s)
+		assert.NoError(t, err)
+		assert.Equal(t, want, ttl)
+		if ttl > 0 {
+			assert.NotEmpty(t, messageTTLLabel(ttl))
+		}
+	}
+
+	for _, s := range []string{"", "2h", "3600", "OFF"} {
+		_, err := ParseMessageTTL(s)
+		assert.ErrorIs(t, err, ErrInvalidMessageTTL)
+	}
+}
+
+// TestValidateSystemNotice 测试客户端不能发送系统通知
+func TestValidateSystemNotice(t *testing.T) {
+	s := &Service{}
+	msg := &pb.Message{
+		SenderUUID:     "user-a",
+		ConversationID: "group-1",
+		MessageType:    2,
+		ContentType:    ContentTypeSystem,
+	}
+	assert.NoError(t, s.validateMessage(msg))
+
+	msg.SenderDeviceID = "web"
+	assert.Error(t, s.validateMessage(msg))
+}
@@ -0,0 +1,169 @@
+package chat
+
+import (
+	pb "MyGoChat/pkg/api/v1"
+	"MyGoChat/pkg/common/request"
+	"MyGoChat/pkg/log"
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// 单次转发的消息数与目标会话数上限
+const (
+	maxForwardMessages = 50
+	maxForwardTargets  = 20
+)
+
+var (
+	ErrTooManyForwards  = errors.New("too many messages or targets to forward")
+	ErrNotForwardable   = errors.New("message cannot be forwarded")
+	ErrForwardForbidden = errors.New("no permission to send to the target conversation")
+)
+
+// ForwardMessages 将已有消息按顺序转发到一个或多个会话
+// 转发者必须是源消息所在会话与目标会话的成员；转发出的消息与普通消息一样经 Ingest Topic 入库与投递，
+// 元数据中记录最初的来源
+func (s *Service) ForwardMessages(ctx context.Context, userUUID string, req *request.ForwardMessageRequest) ([]*pb.Message, error) {
+	if len(req.MessageIDs) > maxForwardMessages || len(req.Targets) > maxForwardTargets {
+		return nil, ErrTooManyForwards
+	}
+
+	sources, err := s.loadForwardSources(ctx, userUUID, req.MessageIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	type target struct {
+		messageType    int32
+		targetUUID     string
+		conversationID string
+	}
+	targets := make([]target, 0, len(req.Targets))
+	for _, t := range req.Targets {
+		targetUUID, conversationID, err := s.resolveTarget(ctx, userUUID, t.MessageType, t.TargetName)
+		if err != nil {
+			return nil, err
+		}
+		// 只能转发到自己所在的会话（好友或已加入的群）
+		if _, err := s.relRepo.GetRelationByConversation(ctx, userUUID, conversationID); err != nil {
+			return nil, ErrForwardForbidden
+		}
+		targets = append(targets, target{t.MessageType, targetUUID, conversationID})
+	}
+
+	senderName, _ := s.userRepo.GetUsernameByUUID(ctx, userUUID)
+
+	var forwarded []*pb.Message
+	for _, t := range targets {
+		for _, src := range sources {
+			body, err := packStoredBody(src.ContentType, src.Body)
+			if err != nil {
+				return forwarded, err
+			}
+			msg := &pb.Message{
+				Id:             primitive.NewObjectID().Hex(),
+				ConversationID: t.conversationID,
+				SenderUUID:     userUUID,
+				SenderName:     senderName,
+				RecipientUUID:  t.targetUUID,
+				MessageType:    t.messageType,
+				ContentType:    int32(src.ContentType),
+				Body:           body,
+				SendAt:         time.Now().Unix(),
+				Metadata:       &pb.MessageMetadata{ForwardedFrom: forwardInfoOf(src).toProto()},
+			}
+			if err := s.EnqueueMessage(ctx, msg); err != nil {
+				return forwarded, err
+			}
+			forwarded = append(forwarded, msg)
+		}
+	}
+
+	log.Logger.Sugar().Infof("Forwarded %d messages to %d conversations for %s", len(sources), len(targets), userUUID)
+	return forwarded, nil
+}
+
+// loadForwardSources 按请求顺序获取要转发的消息，并校验转发者可以读取它们
+func (s *Service) loadForwardSources(ctx context.Context, userUUID string, msgIDs []string) ([]*Message, error) {
+	messages, err := s.repo.GetMessagesByIDs(ctx, msgIDs)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]*Message, len(messages))
+	for _, m := range messages {
+		byID[m.ID.Hex()] = m
+	}
+
+	readable := make(map[string]bool)
+	sources := make([]*Message, 0, len(msgIDs))
+	for _, id := range msgIDs {
+		m, ok := byID[id]
+		if !ok {
+			return nil, ErrMessageNotFound
+		}
+		if err := checkForwardable(m); err != nil {
+			return nil, err
+		}
+		if !readable[m.ConversationID] {
+			if _, err := s.relRepo.GetRelationByConversation(ctx, userUUID, m.ConversationID); err != nil {
+				return nil, ErrNotMember
+			}
+			readable[m.ConversationID] = true
+		}
+		sources = append(sources, m)
+	}
+	return sources, nil
+}
+
+// checkForwardable 只有未撤回的文本和文件类消息可以转发
+func checkForwardable(m *Message) error {
+	if m.Recalled || m.ContentType < 1 || m.ContentType > 4 {
+		return ErrNotForwardable
+	}
+	return nil
+}
+
+// forwardInfoOf 生成转发来源；源消息本身是转发来的时沿用最初的来源
+func forwardInfoOf(m *Message) *ForwardInfo {
+	if m.Metadata != nil && m.Metadata.ForwardedFrom != nil {
+		return m.Metadata.ForwardedFrom
+	}
+	return &ForwardInfo{
+		MessageID:      m.ID.Hex(),
+		ConversationID: m.ConversationID,
+		SenderUUID:     m.SenderUUID,
+		SenderName:     m.SenderName,
+		SendAt:         m.SendAt,
+	}
+}
+
+// toProto 转换为推送给客户端的 pb.ForwardInfo
+func (f *ForwardInfo) toProto() *pb.ForwardInfo {
+	if f == nil {
+		return nil
+	}
+	return &pb.ForwardInfo{
+		MessageID:      f.MessageID,
+		ConversationID: f.ConversationID,
+		SenderUUID:     f.SenderUUID,
+		SenderName:     f.SenderName,
+		SendAt:         f.SendAt,
+	}
+}
+
+// forwardInfoFromProto 将消息中的转发来源转换为入库结构
+func forwardInfoFromProto(f *pb.ForwardInfo) *ForwardInfo {
+	if f == nil {
+		return nil
+	}
+	return &ForwardInfo{
+		MessageID:      f.MessageID,
+		ConversationID: f.ConversationID,
+		SenderUUID:     f.SenderUUID,
+		SenderName:     f.SenderName,
+		SendAt:         f.SendAt,
+	}
+}
@@ -0,0 +1,85 @@
+package chat
+
+import (
+	pb "MyGoChat/pkg/api/v1"
+	"MyGoChat/pkg/route"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/protobuf/proto"
+)
+
+// TestForwardInfoOf 测试转发来源：多次转发保留最初的来源
+func TestForwardInfoOf(t *testing.T) {
+	original := &Message{
+		ID:             primitive.NewObjectID(),
+		ConversationID: "conv-1",
+		SenderUUID:     "alice-uuid",
+		SenderName:     "alice",
+		SendAt:         1700000000,
+	}
+	info := forwardInfoOf(original)
+	assert.Equal(t, original.ID.Hex(), info.MessageID)
+	assert.Equal(t, "alice", info.SenderName)
+
+	forwarded := &Message{
+		ID:             primitive.NewObjectID(),
+		ConversationID: "conv-2",
+		SenderUUID:     "bob-uuid",
+		Metadata:       &MessageMetadata{ForwardedFrom: info},
+	}
+	assert.Equal(t, info, forwardInfoOf(forwarded))
+}
+
+// TestCheckForwardable 测试只有未撤回的文本和文件类消息可以转发
+func TestCheckForwardable(t *testing.T) {
+	assert.NoError(t, checkForwardable(&Message{ContentType: 1}))
+	assert.NoError(t, checkForwardable(&Message{ContentType: 3}))
+	assert.ErrorIs(t, checkForwardable(&Message{ContentType: 1, Recalled: true}), ErrNotForwardable)
+	assert.ErrorIs(t, checkForwardable(&Message{ContentType: 9}), ErrNotForwardable)
+}
+
+// TestProcessMessage_IgnoresClientForwardInfo 测试客户端经 Gateway 发来的转发来源和回复摘要不会入库或推送
+func TestProcessMessage_IgnoresClientForwardInfo(t *testing.T) {
+	ctx := context.Background()
+	var stored *Message
+	mockRepo := new(MockRepository)
+	mockRepo.On("AllocateSeq", mock.Anything, "conv-1").Return(int64(1), nil)
+	mockRepo.On("GetMessageTTL", mock.Anything, "conv-1").Return(int64(0), nil)
+	mockRepo.On("CreateMsg", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { stored = args.Get(1).(*Message) }).Return(nil)
+	mockRepo.On("UpdateLastMessage", mock.Anything, "conv-1", mock.Anything).Return(nil)
+	mockRepo.On("GetUserReadSeqs", mock.Anything, "sender", []string{"conv-1"}).Return(map[string]int64{}, nil)
+	mockRepo.On("AdvanceReadCursor", mock.Anything, "sender", "conv-1", int64(1), mock.Anything).Return(int64(0), true, nil)
+
+	service, producer := newAckTestService(t, mockRepo)
+	service.relRepo = &stubRelationRepo{}
+	require.NoError(t, route.Add(ctx, service.redis, "recipient", route.Route{GatewayID: "gw-1", Instance: "instance-1", DeviceID: "web"}))
+
+	err := service.ProcessMessage(ctx, newIngestMessage(t, "client-1", func(msg *pb.Message) {
+		msg.Metadata = &pb.MessageMetadata{
+			ForwardedFrom: &pb.ForwardInfo{MessageID: "forged", SenderName: "ceo"},
+			Reply:         &pb.ReplySnippet{MessageID: "forged", Preview: "fake quote"},
+		}
+	}))
+	require.NoError(t, err)
+
+	require.NotNil(t, stored)
+	assert.Nil(t, stored.Metadata.ForwardedFrom)
+
+	var delivered *pb.Message
+	for _, sent := range producer.SentMessages {
+		var msg pb.Message
+		require.NoError(t, proto.Unmarshal(sent.Message, &msg))
+		if msg.EventType == pb.EventTypeMessage && msg.RecipientUUID == "recipient" {
+			delivered = &msg
+		}
+	}
+	require.NotNil(t, delivered)
+	assert.Nil(t, delivered.Metadata.ForwardedFrom)
+	assert.Nil(t, delivered.Metadata.Reply)
+}
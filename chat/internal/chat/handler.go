@@ -4,6 +4,8 @@ import (
 	"MyGoChat/pkg/common/request"
 	"MyGoChat/pkg/common/response"
 	"MyGoChat/pkg/log"
+	"errors"
+	"io"
 	"net/http"
 	"strconv"
 
@@ -84,6 +86,183 @@ func (h *Handler) Send(c *gin.Context) {
 	}))
 }
 
+// Forward 转发消息到一个或多个会话
+// 请求体：{"message_ids": ["..."], "targets": [{"target_name": "...", "message_type": 1}]}
+func (h *Handler) Forward(c *gin.Context) {
+	var req request.ForwardMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.FailMsg("请求参数错误: "+err.Error()))
+		return
+	}
+	if len(req.MessageIDs) == 0 || len(req.Targets) == 0 {
+		c.JSON(http.StatusBadRequest, response.FailMsg("message_ids 和 targets 不能为空"))
+		return
+	}
+	for _, t := range req.Targets {
+		if t.MessageType != 1 && t.MessageType != 2 {
+			c.JSON(http.StatusBadRequest, response.FailMsg("无效的消息类型，必须为 1(私聊) 或 2(群聊)"))
+			return
+		}
+	}
+
+	userUUID := c.GetString("useruuid")
+	if userUUID == "" {
+		c.JSON(http.StatusUnauthorized, response.FailMsg("未授权：无法获取用户身份"))
+		return
+	}
+
+	messages, err := h.service.ForwardMessages(c.Request.Context(), userUUID, &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrTooManyForwards):
+			c.JSON(http.StatusBadRequest, response.FailMsg("转发的消息或目标过多"))
+		case errors.Is(err, ErrMessageNotFound):
+			c.JSON(http.StatusNotFound, response.FailMsg("消息不存在"))
+		case errors.Is(err, ErrNotForwardable):
+			c.JSON(http.StatusBadRequest, response.FailMsg("消息不能转发"))
+		case errors.Is(err, ErrNotMember):
+			c.JSON(http.StatusForbidden, response.FailMsg("不是源消息所在会话的成员"))
+		case errors.Is(err, ErrForwardForbidden):
+			c.JSON(http.StatusForbidden, response.FailMsg("不能向该会话转发消息"))
+		default:
+			log.Logger.Error("Forward: failed to forward messages",
+				zap.String("userUUID", userUUID),
+				zap.Int("forwarded", len(messages)),
+				zap.Error(err),
+			)
+			c.JSON(http.StatusInternalServerError, response.FailMsg(err.Error()))
+		}
+		return
+	}
+
+	forwarded := make([]gin.H, 0, len(messages))
+	for _, msg := range messages {
+		forwarded = append(forwarded, gin.H{
+			"message_id":      msg.Id,
+			"conversation_id": msg.ConversationID,
+			"send_at":         msg.SendAt,
+		})
+	}
+	c.JSON(http.StatusOK, response.SuccessMsg(gin.H{"messages": forwarded}))
+}
+
+// Schedule 创建定时消息
+// 请求体与发送消息相同，另加 send_at（Unix 秒）
+func (h *Handler) Schedule(c *gin.Context) {
+	var req request.ScheduleMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.FailMsg("请求参数错误: "+err.Error()))
+		return
+	}
+	if req.MessageType != 1 && req.MessageType != 2 {
+		c.JSON(http.StatusBadRequest, response.FailMsg("无效的消息类型，必须为 1(私聊) 或 2(群聊)"))
+		return
+	}
+	if req.ContentType < 1 || req.ContentType > 4 {
+		c.JSON(http.StatusBadRequest, response.FailMsg("无效的内容类型，必须为 1-4"))
+		return
+	}
+
+	userUUID := c.GetString("useruuid")
+	if userUUID == "" {
+		c.JSON(http.StatusUnauthorized, response.FailMsg("未授权：无法获取用户身份"))
+		return
+	}
+
+	sm, err := h.service.ScheduleMessage(c.Request.Context(), userUUID, &req.SendMessageRequest, req.SendAt)
+	if err != nil {
+		h.scheduleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, response.SuccessMsg(sm))
+}
+
+// ListScheduled 获取待发送的定时消息
+func (h *Handler) ListScheduled(c *gin.Context) {
+	userUUID := c.GetString("useruuid")
+	if userUUID == "" {
+		c.JSON(http.StatusUnauthorized, response.FailMsg("未授权：无法获取用户身份"))
+		return
+	}
+
+	messages, err := h.service.ListScheduled(c.Request.Context(), userUUID)
+	if err != nil {
+		log.Logger.Error("ListScheduled: failed to list scheduled messages",
+			zap.String("userUUID", userUUID),
+			zap.Error(err),
+		)
+		c.JSON(http.StatusInternalServerError, response.FailMsg("获取定时消息失败"))
+		return
+	}
+	if messages == nil {
+		messages = []*ScheduledMessage{}
+	}
+	c.JSON(http.StatusOK, response.SuccessMsg(gin.H{"messages": messages}))
+}
+
+// UpdateScheduled 修改待发送定时消息的内容或发送时间
+// 请求体：{"content_type": 1, "body": {...}, "send_at": 1700000000}，字段均可选
+func (h *Handler) UpdateScheduled(c *gin.Context) {
+	var req request.UpdateScheduledMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.FailMsg("请求参数错误: "+err.Error()))
+		return
+	}
+
+	var body map[string]interface{}
+	if req.Body != nil {
+		var ok bool
+		if body, ok = req.Body.(map[string]interface{}); !ok {
+			c.JSON(http.StatusBadRequest, response.FailMsg("无效的消息内容"))
+			return
+		}
+	}
+
+	userUUID := c.GetString("useruuid")
+	if userUUID == "" {
+		c.JSON(http.StatusUnauthorized, response.FailMsg("未授权：无法获取用户身份"))
+		return
+	}
+
+	sm, err := h.service.UpdateScheduled(c.Request.Context(), userUUID, c.Param("id"), req.ContentType, body, req.SendAt)
+	if err != nil {
+		h.scheduleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, response.SuccessMsg(sm))
+}
+
+// CancelScheduled 取消待发送的定时消息
+func (h *Handler) CancelScheduled(c *gin.Context) {
+	userUUID := c.GetString("useruuid")
+	if userUUID == "" {
+		c.JSON(http.StatusUnauthorized, response.FailMsg("未授权：无法获取用户身份"))
+		return
+	}
+
+	if err := h.service.CancelScheduled(c.Request.Context(), userUUID, c.Param("id")); err != nil {
+		h.scheduleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, response.SuccessMsg(gin.H{"id": c.Param("id"), "status": ScheduleCanceled}))
+}
+
+func (h *Handler) scheduleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrScheduleNotFound):
+		c.JSON(http.StatusNotFound, response.FailMsg("定时消息不存在"))
+	case errors.Is(err, ErrScheduleNotPending):
+		c.JSON(http.StatusConflict, response.FailMsg("定时消息已发送或已取消"))
+	case errors.Is(err, ErrInvalidSendTime):
+		c.JSON(http.StatusBadRequest, response.FailMsg("发送时间必须在当前时间之后的 30 天内"))
+	case errors.Is(err, ErrNotMember):
+		c.JSON(http.StatusForbidden, response.FailMsg("不是该会话的成员"))
+	default:
+		log.Logger.Error("Scheduled message operation failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, response.FailMsg(err.Error()))
+	}
+}
+
 // SendMessage 发送消息（私聊/群聊）- 保留旧方法名以保持兼容性
 func (h *Handler) SendMessage(c *gin.Context) {
 	h.Send(c)
@@ -98,8 +277,8 @@ func (h *Handler) GetHistory(c *gin.Context) {
 	}
 
 	// 解析分页参数
+	// before/after: 游标（Seq 或消息 ObjectID），order: desc=从新到旧（默认），asc=从旧到新
 	limitStr := c.DefaultQuery("limit", "20")
-	offsetStr := c.DefaultQuery("offset", "0")
 
 	limit, err := strconv.Atoi(limitStr)
 	if err != nil || limit <= 0 {
@@ -109,14 +288,37 @@ func (h *Handler) GetHistory(c *gin.Context) {
 		limit = 100 // 限制最大返回数量
 	}
 
-	offset, err := strconv.Atoi(offsetStr)
-	if err != nil || offset < 0 {
-		offset = 0
+	before, err := ParseMessageCursor(c.Query("before"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.FailMsg("无效的 before 游标"))
+		return
+	}
+	after, err := ParseMessageCursor(c.Query("after"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.FailMsg("无效的 after 游标"))
+		return
+	}
+
+	order := c.DefaultQuery("order", "desc")
+	if order != "desc" && order != "asc" {
+		c.JSON(http.StatusBadRequest, response.FailMsg("无效的排序方式，必须为 desc 或 asc"))
+		return
+	}
+
+	query := HistoryQuery{
+		Before:    before,
+		After:     after,
+		Ascending: order == "asc",
+		Limit:     limit,
 	}
 
 	// 获取消息历史
-	messages, err := h.service.GetMessageHistory(conversationID, limit, offset)
+	page, err := h.service.GetMessageHistory(c.Request.Context(), c.GetString("useruuid"), conversationID, query)
 	if err != nil {
+		if errors.Is(err, ErrMixedCursor) {
+			c.JSON(http.StatusBadRequest, response.FailMsg("before 与 after 游标类型必须一致"))
+			return
+		}
 		log.Logger.Error("GetHistory: failed to get message history",
 			zap.String("conversationID", conversationID),
 			zap.Error(err),
@@ -126,6 +328,7 @@ func (h *Handler) GetHistory(c *gin.Context) {
 	}
 
 	// 如果没有消息，返回空数组而非 nil
+	messages := page.Messages
 	if messages == nil {
 		messages = []*Message{}
 	}
@@ -133,7 +336,9 @@ func (h *Handler) GetHistory(c *gin.Context) {
 	c.JSON(http.StatusOK, response.SuccessMsg(gin.H{
 		"messages":        messages,
 		"limit":           limit,
-		"offset":          offset,
+		"order":           order,
+		"has_more":        page.HasMore,
+		"next_cursor":     page.NextCursor,
 		"conversation_id": conversationID,
 	}))
 }
@@ -144,7 +349,9 @@ func (h *Handler) GetMessageHistory(c *gin.Context) {
 	h.GetHistory(c)
 }
 
-// Sync 同步离线消息
+// Sync 按序号增量同步消息
+// 请求体：{"conversations": {"会话ID": 客户端已有的最大序号}, "limit": 50}，可为空
+// 未上报的会话从用户的已读位置开始同步
 func (h *Handler) Sync(c *gin.Context) {
 	// 从 JWT 中间件中获取用户 UUID
 	userUUID, exists := c.Get("useruuid")
@@ -161,22 +368,62 @@ func (h *Handler) Sync(c *gin.Context) {
 		return
 	}
 
-	err := h.service.SyncOfflineMessages(userUUIDStr)
+	var req struct {
+		Conversations map[string]int64 `json:"conversations"`
+		Limit         int              `json:"limit"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, response.FailMsg("请求参数错误: "+err.Error()))
+		return
+	}
+
+	results, err := h.service.SyncConversations(c.Request.Context(), userUUIDStr, SyncRequest{
+		Conversations: req.Conversations,
+		Limit:         req.Limit,
+	})
 	if err != nil {
-		log.Logger.Error("Sync: failed to sync offline messages",
+		log.Logger.Error("Sync: failed to sync conversations",
 			zap.String("userUUID", userUUIDStr),
 			zap.Error(err),
 		)
-		c.JSON(http.StatusInternalServerError, response.FailMsg("同步离线消息失败"))
+		c.JSON(http.StatusInternalServerError, response.FailMsg("同步消息失败"))
 		return
 	}
 
-	log.Logger.Info("Sync: offline messages synced successfully",
-		zap.String("userUUID", userUUIDStr),
-	)
+	c.JSON(http.StatusOK, response.SuccessMsg(gin.H{
+		"conversations": results,
+	}))
+}
+
+// AckOffline 确认已收到离线消息，服务端收到确认后才删除
+func (h *Handler) AckOffline(c *gin.Context) {
+	var req struct {
+		MessageIDs []string `json:"message_ids" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.FailMsg("请求参数错误: "+err.Error()))
+		return
+	}
+
+	userUUID := c.GetString("useruuid")
+	if userUUID == "" {
+		c.JSON(http.StatusUnauthorized, response.FailMsg("未授权：无法获取用户身份"))
+		return
+	}
+
+	removed, err := h.service.AckOfflineMessages(c.Request.Context(), userUUID, "", req.MessageIDs)
+	if err != nil {
+		log.Logger.Error("AckOffline: failed to ack offline messages",
+			zap.String("userUUID", userUUID),
+			zap.Error(err),
+		)
+		c.JSON(http.StatusInternalServerError, response.FailMsg("确认离线消息失败"))
+		return
+	}
 
 	c.JSON(http.StatusOK, response.SuccessMsg(gin.H{
-		"message": "离线消息同步完成",
+		"acked": removed,
 	}))
 }
 
@@ -229,10 +476,336 @@ func (h *Handler) GetConversations(c *gin.Context) {
 	}))
 }
 
-// MarkAsRead 标记消息为已读
+// GetUnread 获取用户的总未读数以及各会话的未读数
+func (h *Handler) GetUnread(c *gin.Context) {
+	userUUID := c.GetString("useruuid")
+	if userUUID == "" {
+		c.JSON(http.StatusUnauthorized, response.FailMsg("未授权：无法获取用户身份"))
+		return
+	}
+
+	total, counts, err := h.service.GetUnreadSummary(c.Request.Context(), userUUID)
+	if err != nil {
+		log.Logger.Error("GetUnread: failed to get unread counts",
+			zap.String("userUUID", userUUID),
+			zap.Error(err),
+		)
+		c.JSON(http.StatusInternalServerError, response.FailMsg("获取未读数失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, response.SuccessMsg(gin.H{
+		"total":         total,
+		"conversations": counts,
+	}))
+}
+
+// MarkAsRead 推进用户在会话中的已读位置
+// 请求体：{"conversation_id": "...", "seq": 123} 或 {"conversation_id": "...", "message_id": "..."}
 func (h *Handler) MarkAsRead(c *gin.Context) {
 	var req struct {
-		MessageIDs []string `json:"message_ids" binding:"required"`
+		ConversationID string `json:"conversation_id" binding:"required"`
+		Seq            int64  `json:"seq"`
+		MessageID      string `json:"message_id"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.FailMsg("请求参数错误: "+err.Error()))
+		return
+	}
+
+	if req.Seq <= 0 && req.MessageID == "" {
+		c.JSON(http.StatusBadRequest, response.FailMsg("seq 和 message_id 不能同时为空"))
+		return
+	}
+
+	userUUID := c.GetString("useruuid")
+	if userUUID == "" {
+		c.JSON(http.StatusUnauthorized, response.FailMsg("未授权：无法获取用户身份"))
+		return
+	}
+
+	result, err := h.service.MarkConversationRead(c.Request.Context(), userUUID, req.ConversationID, req.Seq, req.MessageID)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrNotMember):
+			c.JSON(http.StatusForbidden, response.FailMsg("不是该会话的成员"))
+		case errors.Is(err, ErrInvalidReadTo):
+			c.JSON(http.StatusBadRequest, response.FailMsg("无效的已读位置"))
+		default:
+			log.Logger.Error("MarkAsRead: failed to advance read cursor",
+				zap.String("conversationID", req.ConversationID),
+				zap.Error(err),
+			)
+			c.JSON(http.StatusInternalServerError, response.FailMsg("标记已读失败"))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, response.SuccessMsg(gin.H{
+		"conversation_id": result.ConversationID,
+		"last_read_seq":   result.LastReadSeq,
+		"advanced":        result.Advanced,
+	}))
+}
+
+// Recall 撤回消息，发送者本人或群管理员可以在撤回时间窗口内撤回
+// 请求体：{"message_id": "..."}
+func (h *Handler) Recall(c *gin.Context) {
+	var req struct {
+		MessageID string `json:"message_id" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.FailMsg("请求参数错误: "+err.Error()))
+		return
+	}
+
+	userUUID := c.GetString("useruuid")
+	if userUUID == "" {
+		c.JSON(http.StatusUnauthorized, response.FailMsg("未授权：无法获取用户身份"))
+		return
+	}
+
+	msg, err := h.service.RecallMessage(c.Request.Context(), userUUID, req.MessageID)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrMessageNotFound):
+			c.JSON(http.StatusNotFound, response.FailMsg("消息不存在"))
+		case errors.Is(err, ErrNotMember), errors.Is(err, ErrRecallForbidden):
+			c.JSON(http.StatusForbidden, response.FailMsg("无权撤回该消息"))
+		case errors.Is(err, ErrRecallExpired):
+			c.JSON(http.StatusBadRequest, response.FailMsg("已超过可撤回时间"))
+		case errors.Is(err, ErrAlreadyRecalled):
+			c.JSON(http.StatusConflict, response.FailMsg("消息已被撤回"))
+		default:
+			log.Logger.Error("Recall: failed to recall message",
+				zap.String("messageID", req.MessageID),
+				zap.Error(err),
+			)
+			c.JSON(http.StatusInternalServerError, response.FailMsg("撤回消息失败"))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, response.SuccessMsg(gin.H{
+		"message_id":      req.MessageID,
+		"conversation_id": msg.ConversationID,
+		"seq":             msg.Seq,
+		"recalled_at":     msg.RecalledAt,
+	}))
+}
+
+// Edit 编辑文本消息，只有发送者本人可以编辑
+// 请求体：{"message_id": "...", "content": "..."}
+func (h *Handler) Edit(c *gin.Context) {
+	var req struct {
+		MessageID string `json:"message_id" binding:"required"`
+		Content   string `json:"content" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.FailMsg("请求参数错误: "+err.Error()))
+		return
+	}
+
+	userUUID := c.GetString("useruuid")
+	if userUUID == "" {
+		c.JSON(http.StatusUnauthorized, response.FailMsg("未授权：无法获取用户身份"))
+		return
+	}
+
+	msg, err := h.service.EditMessage(c.Request.Context(), userUUID, req.MessageID, req.Content)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrMessageNotFound):
+			c.JSON(http.StatusNotFound, response.FailMsg("消息不存在"))
+		case errors.Is(err, ErrNotMember), errors.Is(err, ErrEditForbidden):
+			c.JSON(http.StatusForbidden, response.FailMsg("无权编辑该消息"))
+		case errors.Is(err, ErrMessageNotEditable):
+			c.JSON(http.StatusBadRequest, response.FailMsg("只能编辑未撤回的文本消息"))
+		case errors.Is(err, ErrEmptyContent):
+			c.JSON(http.StatusBadRequest, response.FailMsg("消息内容不能为空"))
+		default:
+			log.Logger.Error("Edit: failed to edit message",
+				zap.String("messageID", req.MessageID),
+				zap.Error(err),
+			)
+			c.JSON(http.StatusInternalServerError, response.FailMsg("编辑消息失败"))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, response.SuccessMsg(msg))
+}
+
+// GetRevisions 获取消息的编辑历史（按时间顺序，不含当前版本）
+func (h *Handler) GetRevisions(c *gin.Context) {
+	messageID := c.Param("messageId")
+	if messageID == "" {
+		c.JSON(http.StatusBadRequest, response.FailMsg("消息ID不能为空"))
+		return
+	}
+
+	userUUID := c.GetString("useruuid")
+	if userUUID == "" {
+		c.JSON(http.StatusUnauthorized, response.FailMsg("未授权：无法获取用户身份"))
+		return
+	}
+
+	revisions, err := h.service.GetRevisions(c.Request.Context(), userUUID, messageID)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrMessageNotFound):
+			c.JSON(http.StatusNotFound, response.FailMsg("消息不存在"))
+		case errors.Is(err, ErrNotMember):
+			c.JSON(http.StatusForbidden, response.FailMsg("不是该会话的成员"))
+		default:
+			log.Logger.Error("GetRevisions: failed to get message revisions",
+				zap.String("messageID", messageID),
+				zap.Error(err),
+			)
+			c.JSON(http.StatusInternalServerError, response.FailMsg("获取编辑历史失败"))
+		}
+		return
+	}
+
+	if revisions == nil {
+		revisions = []*MessageRevision{}
+	}
+
+	c.JSON(http.StatusOK, response.SuccessMsg(gin.H{
+		"message_id": messageID,
+		"revisions":  revisions,
+	}))
+}
+
+// GetMentions 获取 @ 我的消息（按时间倒序，参数 before/limit）
+func (h *Handler) GetMentions(c *gin.Context) {
+	userUUID := c.GetString("useruuid")
+	if userUUID == "" {
+		c.JSON(http.StatusUnauthorized, response.FailMsg("未授权：无法获取用户身份"))
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	before, err := ParseMessageCursor(c.Query("before"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.FailMsg("无效的 before 游标"))
+		return
+	}
+
+	page, err := h.service.GetMentions(c.Request.Context(), userUUID, before, limit)
+	if err != nil {
+		if errors.Is(err, ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, response.FailMsg("before 必须为消息ID"))
+			return
+		}
+		log.Logger.Error("GetMentions: failed to get mentions",
+			zap.String("userUUID", userUUID),
+			zap.Error(err),
+		)
+		c.JSON(http.StatusInternalServerError, response.FailMsg("获取@我的消息失败"))
+		return
+	}
+
+	messages := page.Messages
+	if messages == nil {
+		messages = []*Message{}
+	}
+
+	c.JSON(http.StatusOK, response.SuccessMsg(gin.H{
+		"messages":    messages,
+		"limit":       limit,
+		"has_more":    page.HasMore,
+		"next_cursor": page.NextCursor,
+	}))
+}
+
+// GetThread 获取话题根消息及话题回复
+// 查询参数：before（消息ID，可选）、limit（默认 20，最大 100）
+func (h *Handler) GetThread(c *gin.Context) {
+	userUUID := c.GetString("useruuid")
+	if userUUID == "" {
+		c.JSON(http.StatusUnauthorized, response.FailMsg("未授权：无法获取用户身份"))
+		return
+	}
+
+	rootID := c.Param("messageId")
+	if rootID == "" {
+		c.JSON(http.StatusBadRequest, response.FailMsg("消息ID不能为空"))
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	before, err := ParseMessageCursor(c.Query("before"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.FailMsg("无效的 before 游标"))
+		return
+	}
+
+	root, page, err := h.service.GetThread(c.Request.Context(), userUUID, rootID, before, limit)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidCursor):
+			c.JSON(http.StatusBadRequest, response.FailMsg("before 必须为消息ID"))
+		case errors.Is(err, ErrMessageNotFound), errors.Is(err, ErrInvalidThreadRoot):
+			c.JSON(http.StatusNotFound, response.FailMsg("话题不存在"))
+		case errors.Is(err, ErrNotMember):
+			c.JSON(http.StatusForbidden, response.FailMsg("不是该会话的成员"))
+		default:
+			log.Logger.Error("GetThread: failed to get thread replies",
+				zap.String("rootID", rootID),
+				zap.Error(err),
+			)
+			c.JSON(http.StatusInternalServerError, response.FailMsg("获取话题失败"))
+		}
+		return
+	}
+
+	messages := page.Messages
+	if messages == nil {
+		messages = []*Message{}
+	}
+
+	c.JSON(http.StatusOK, response.SuccessMsg(gin.H{
+		"root":        root,
+		"messages":    messages,
+		"limit":       limit,
+		"has_more":    page.HasMore,
+		"next_cursor": page.NextCursor,
+	}))
+}
+
+// Pin 置顶消息
+// 请求体：{"message_id": "..."}
+func (h *Handler) Pin(c *gin.Context) {
+	h.setPinned(c, true)
+}
+
+// Unpin 取消置顶消息
+// 请求体：{"message_id": "..."}
+func (h *Handler) Unpin(c *gin.Context) {
+	h.setPinned(c, false)
+}
+
+func (h *Handler) setPinned(c *gin.Context, pinned bool) {
+	var req struct {
+		MessageID string `json:"message_id" binding:"required"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -240,24 +813,217 @@ func (h *Handler) MarkAsRead(c *gin.Context) {
 		return
 	}
 
-	if len(req.MessageIDs) == 0 {
-		c.JSON(http.StatusBadRequest, response.FailMsg("消息ID列表不能为空"))
+	userUUID := c.GetString("useruuid")
+	if userUUID == "" {
+		c.JSON(http.StatusUnauthorized, response.FailMsg("未授权：无法获取用户身份"))
 		return
 	}
 
-	err := h.service.MarkMessagesAsRead(req.MessageIDs)
+	changed, err := h.service.SetPinned(c.Request.Context(), userUUID, req.MessageID, pinned)
 	if err != nil {
-		log.Logger.Error("MarkAsRead: failed to mark messages as read",
-			zap.Strings("messageIDs", req.MessageIDs),
+		switch {
+		case errors.Is(err, ErrMessageNotFound):
+			c.JSON(http.StatusNotFound, response.FailMsg("消息不存在"))
+		case errors.Is(err, ErrNotMember):
+			c.JSON(http.StatusForbidden, response.FailMsg("不是该会话的成员"))
+		case errors.Is(err, ErrPinForbidden):
+			c.JSON(http.StatusForbidden, response.FailMsg("只有群管理员可以置顶消息"))
+		case errors.Is(err, ErrAlreadyRecalled):
+			c.JSON(http.StatusBadRequest, response.FailMsg("消息已被撤回"))
+		case errors.Is(err, ErrPinLimitReached):
+			c.JSON(http.StatusConflict, response.FailMsg("置顶消息已达上限"))
+		default:
+			log.Logger.Error("SetPinned: failed to update pin",
+				zap.String("messageID", req.MessageID),
+				zap.Error(err),
+			)
+			c.JSON(http.StatusInternalServerError, response.FailMsg("置顶操作失败"))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, response.SuccessMsg(gin.H{
+		"message_id": req.MessageID,
+		"pinned":     pinned,
+		"changed":    changed,
+	}))
+}
+
+// GetPins 获取会话的置顶消息
+func (h *Handler) GetPins(c *gin.Context) {
+	conversationID := c.Param("conversationId")
+	if conversationID == "" {
+		c.JSON(http.StatusBadRequest, response.FailMsg("会话ID不能为空"))
+		return
+	}
+
+	userUUID := c.GetString("useruuid")
+	if userUUID == "" {
+		c.JSON(http.StatusUnauthorized, response.FailMsg("未授权：无法获取用户身份"))
+		return
+	}
+
+	pins, err := h.service.GetPinnedMessages(c.Request.Context(), userUUID, conversationID)
+	if err != nil {
+		if errors.Is(err, ErrNotMember) {
+			c.JSON(http.StatusForbidden, response.FailMsg("不是该会话的成员"))
+			return
+		}
+		log.Logger.Error("GetPins: failed to get pinned messages",
+			zap.String("conversationID", conversationID),
 			zap.Error(err),
 		)
-		c.JSON(http.StatusInternalServerError, response.FailMsg("标记已读失败"))
+		c.JSON(http.StatusInternalServerError, response.FailMsg("获取置顶消息失败"))
 		return
 	}
 
 	c.JSON(http.StatusOK, response.SuccessMsg(gin.H{
-		"message": "标记已读成功",
-		"count":   len(req.MessageIDs),
+		"conversation_id": conversationID,
+		"pins":            pins,
+	}))
+}
+
+// SetMessageTTL 修改会话的消息自动删除设置
+// 请求体：{"ttl": "off" | "1h" | "1d" | "7d"}
+func (h *Handler) SetMessageTTL(c *gin.Context) {
+	conversationID := c.Param("conversationId")
+	if conversationID == "" {
+		c.JSON(http.StatusBadRequest, response.FailMsg("会话ID不能为空"))
+		return
+	}
+
+	var req struct {
+		TTL string `json:"ttl" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.FailMsg("请求参数错误: "+err.Error()))
+		return
+	}
+	ttl, err := ParseMessageTTL(req.TTL)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.FailMsg("ttl 只能是 off、1h、1d 或 7d"))
+		return
+	}
+
+	userUUID := c.GetString("useruuid")
+	if userUUID == "" {
+		c.JSON(http.StatusUnauthorized, response.FailMsg("未授权：无法获取用户身份"))
+		return
+	}
+
+	changed, err := h.service.SetMessageTTL(c.Request.Context(), userUUID, conversationID, ttl)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrNotMember):
+			c.JSON(http.StatusForbidden, response.FailMsg("不是该会话的成员"))
+		case errors.Is(err, ErrMessageTTLForbidden):
+			c.JSON(http.StatusForbidden, response.FailMsg("只有群管理员可以修改消息自动删除设置"))
+		default:
+			log.Logger.Error("SetMessageTTL: failed to update message TTL",
+				zap.String("conversationID", conversationID),
+				zap.Error(err),
+			)
+			c.JSON(http.StatusInternalServerError, response.FailMsg("修改消息自动删除设置失败"))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, response.SuccessMsg(gin.H{
+		"conversation_id": conversationID,
+		"ttl":             req.TTL,
+		"changed":         changed,
+	}))
+}
+
+// AddReaction 添加表情回应
+// 请求体：{"message_id": "...", "emoji": "👍"}
+func (h *Handler) AddReaction(c *gin.Context) {
+	h.react(c, false)
+}
+
+// RemoveReaction 取消表情回应
+// 请求体：{"message_id": "...", "emoji": "👍"}
+func (h *Handler) RemoveReaction(c *gin.Context) {
+	h.react(c, true)
+}
+
+func (h *Handler) react(c *gin.Context, remove bool) {
+	var req struct {
+		MessageID string `json:"message_id" binding:"required"`
+		Emoji     string `json:"emoji" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.FailMsg("请求参数错误: "+err.Error()))
+		return
+	}
+
+	userUUID := c.GetString("useruuid")
+	if userUUID == "" {
+		c.JSON(http.StatusUnauthorized, response.FailMsg("未授权：无法获取用户身份"))
+		return
+	}
+
+	result, err := h.service.React(c.Request.Context(), userUUID, req.MessageID, req.Emoji, remove)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidEmoji):
+			c.JSON(http.StatusBadRequest, response.FailMsg("无效的表情"))
+		case errors.Is(err, ErrMessageNotFound):
+			c.JSON(http.StatusNotFound, response.FailMsg("消息不存在"))
+		case errors.Is(err, ErrNotMember):
+			c.JSON(http.StatusForbidden, response.FailMsg("不是该会话的成员"))
+		case errors.Is(err, ErrAlreadyRecalled):
+			c.JSON(http.StatusBadRequest, response.FailMsg("消息已被撤回"))
+		default:
+			log.Logger.Error("React: failed to update reaction",
+				zap.String("messageID", req.MessageID),
+				zap.Error(err),
+			)
+			c.JSON(http.StatusInternalServerError, response.FailMsg("表情回应失败"))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, response.SuccessMsg(gin.H{
+		"message_id": result.MessageID,
+		"emoji":      result.Emoji,
+		"count":      result.Count,
+		"changed":    result.Changed,
+	}))
+}
+
+// GetReadReceipts 获取会话中各成员的已读位置
+func (h *Handler) GetReadReceipts(c *gin.Context) {
+	conversationID := c.Param("conversationId")
+	if conversationID == "" {
+		c.JSON(http.StatusBadRequest, response.FailMsg("会话ID不能为空"))
+		return
+	}
+
+	userUUID := c.GetString("useruuid")
+	if userUUID == "" {
+		c.JSON(http.StatusUnauthorized, response.FailMsg("未授权：无法获取用户身份"))
+		return
+	}
+
+	cursors, err := h.service.GetReadReceipts(c.Request.Context(), userUUID, conversationID)
+	if err != nil {
+		if errors.Is(err, ErrNotMember) {
+			c.JSON(http.StatusForbidden, response.FailMsg("不是该会话的成员"))
+			return
+		}
+		log.Logger.Error("GetReadReceipts: failed to get read cursors",
+			zap.String("conversationID", conversationID),
+			zap.Error(err),
+		)
+		c.JSON(http.StatusInternalServerError, response.FailMsg("获取已读状态失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, response.SuccessMsg(gin.H{
+		"conversation_id": conversationID,
+		"read_cursors":    cursors,
 	}))
 }
 
@@ -0,0 +1,120 @@
+package chat
+
+import (
+	pb "MyGoChat/pkg/api/v1"
+	"context"
+	"errors"
+)
+
+// 单条消息最多 @ 的成员数
+const maxMentions = 100
+
+var (
+	ErrMentionNotAllowed   = errors.New("mentions are only allowed in group chats")
+	ErrTooManyMentions     = errors.New("too many mentions")
+	ErrInvalidMention      = errors.New("mentioned user is not a member of the group")
+	ErrMentionAllForbidden = errors.New("only group admins can mention everyone")
+)
+
+// validateMentions 校验并规范化消息中的 @：只允许在群聊中使用，@ 的成员必须在群内，@所有人 仅群管理员和群主可用
+// 校验通过后 MentionUUIDs 会被去重
+func (s *Service) validateMentions(ctx context.Context, msg *pb.Message) error {
+	md := msg.GetMetadata()
+	if len(md.GetMentionUUIDs()) == 0 && !md.GetMentionAll() {
+		return nil
+	}
+	if msg.MessageType != 2 {
+		return ErrMentionNotAllowed
+	}
+	if md.MentionAll {
+		rel, err := s.relRepo.GetRelationByConversation(ctx, msg.SenderUUID, msg.ConversationID)
+		if err != nil || !rel.IsGroupAdmin() {
+			return ErrMentionAllForbidden
+		}
+	}
+	if len(md.MentionUUIDs) == 0 {
+		return nil
+	}
+
+	members, err := s.relRepo.GetGroupMemberUUIDs(ctx, msg.ConversationID)
+	if err != nil {
+		return err
+	}
+	mentions, err := checkMentions(md.MentionUUIDs, members)
+	if err != nil {
+		return err
+	}
+	md.MentionUUIDs = mentions
+	return nil
+}
+
+// checkMentions 去重并校验 @ 的用户都是群成员
+func checkMentions(mentions, members []string) ([]string, error) {
+	isMember := make(map[string]bool, len(members))
+	for _, uuid := range members {
+		isMember[uuid] = true
+	}
+
+	var result []string
+	seen := make(map[string]bool, len(mentions))
+	for _, uuid := range mentions {
+		if seen[uuid] {
+			continue
+		}
+		if !isMember[uuid] {
+			return nil, ErrInvalidMention
+		}
+		seen[uuid] = true
+		result = append(result, uuid)
+	}
+	if len(result) > maxMentions {
+		return nil, ErrTooManyMentions
+	}
+	return result, nil
+}
+
+// isMentioned 判断用户是否被消息 @（包括 @所有人）
+func isMentioned(md *pb.MessageMetadata, userUUID string) bool {
+	if md.GetMentionAll() {
+		return true
+	}
+	for _, uuid := range md.GetMentionUUIDs() {
+		if uuid == userUUID {
+			return true
+		}
+	}
+	return false
+}
+
+// GetMentions 按时间倒序分页获取 @ 了用户的消息
+func (s *Service) GetMentions(ctx context.Context, userUUID string, before MessageCursor, limit int) (*HistoryPage, error) {
+	// 跨会话查询没有统一的序号，只支持 ObjectID 游标
+	if !before.IsZero() && !before.IsID() {
+		return nil, ErrInvalidCursor
+	}
+	if limit <= 0 {
+		limit = 20
+	}
+
+	convIDs, err := s.relRepo.GetUserConversationIDs(ctx, userUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 多取一条，用于判断是否还有下一页
+	messages, err := s.repo.GetMentions(ctx, userUUID, convIDs, before.ID, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &HistoryPage{Messages: messages}
+	if len(messages) > limit {
+		page.Messages = messages[:limit]
+		page.HasMore = true
+		page.NextCursor = MessageCursor{ID: page.Messages[limit-1].ID}.String()
+	}
+
+	s.attachReplySnippets(ctx, page.Messages)
+	s.attachReactions(ctx, userUUID, page.Messages)
+	return page, nil
+}
@@ -0,0 +1,68 @@
+package chat
+
+import (
+	"MyGoChat/chat/internal/relation"
+	pb "MyGoChat/pkg/api/v1"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCheckMentions 测试 @ 的成员去重并校验群成员身份
+func TestCheckMentions(t *testing.T) {
+	members := []string{"user-a", "user-b", "user-c"}
+
+	mentions, err := checkMentions([]string{"user-a", "user-b", "user-a"}, members)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"user-a", "user-b"}, mentions)
+
+	_, err = checkMentions([]string{"user-a", "stranger"}, members)
+	assert.ErrorIs(t, err, ErrInvalidMention)
+}
+
+// TestIsMentioned 测试接收者是否被 @
+func TestIsMentioned(t *testing.T) {
+	md := &pb.MessageMetadata{MentionUUIDs: []string{"user-a"}}
+	assert.True(t, isMentioned(md, "user-a"))
+	assert.False(t, isMentioned(md, "user-b"))
+	assert.True(t, isMentioned(&pb.MessageMetadata{MentionAll: true}, "user-b"))
+	assert.False(t, isMentioned(nil, "user-a"))
+}
+
+// TestValidateMentionsPrivateChat 测试私聊中不允许 @
+func TestValidateMentionsPrivateChat(t *testing.T) {
+	s := &Service{}
+	msg := &pb.Message{
+		MessageType: 1,
+		Metadata:    &pb.MessageMetadata{MentionUUIDs: []string{"user-a"}},
+	}
+	assert.ErrorIs(t, s.validateMentions(context.Background(), msg), ErrMentionNotAllowed)
+
+	// 没有 @ 的消息直接通过
+	assert.NoError(t, s.validateMentions(context.Background(), &pb.Message{MessageType: 1}))
+}
+
+// TestValidateMentions_MentionAll 测试 @所有人 按群内角色校验：管理员和群主可用，普通成员不可用
+func TestValidateMentions_MentionAll(t *testing.T) {
+	service := &Service{relRepo: &stubRelationRepo{relations: map[string]*relation.Relation{
+		"owner":  {ConversationID: "group-1", Type: relation.TypeGroup, Status: relation.GroupRoleOwner},
+		"admin":  {ConversationID: "group-1", Type: relation.TypeGroup, Status: relation.GroupRoleAdmin},
+		"member": {ConversationID: "group-1", Type: relation.TypeGroup, Status: relation.GroupRoleMember},
+	}}}
+
+	for sender, allowed := range map[string]bool{"owner": true, "admin": true, "member": false, "stranger": false} {
+		msg := &pb.Message{
+			SenderUUID:     sender,
+			ConversationID: "group-1",
+			MessageType:    2,
+			Metadata:       &pb.MessageMetadata{MentionAll: true},
+		}
+		err := service.validateMentions(context.Background(), msg)
+		if allowed {
+			assert.NoError(t, err, sender)
+		} else {
+			assert.ErrorIs(t, err, ErrMentionAllForbidden, sender)
+		}
+	}
+}
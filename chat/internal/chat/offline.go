@@ -0,0 +1,168 @@
+package chat
+
+import (
+	pb "MyGoChat/pkg/api/v1"
+	"MyGoChat/pkg/config"
+	"MyGoChat/pkg/log"
+	"MyGoChat/pkg/offline"
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"google.golang.org/protobuf/proto"
+)
+
+// 离线消息的存储结构见 pkg/offline。
+// 消息只有在客户端回复 offline_ack 后才会删除。推送采用滑动窗口：
+// 未确认的消息最多 offlineWindow 条，确认后继续推送后续消息；
+// 用户重连时，或窗口超时（offline.PendingTimeout）后 Gateway 请求续推时，从队首重新推送所有未确认的消息。
+// 离线队列只是上线时的快速通道，消息完整性以 MongoDB 为准（见 SyncConversations）
+const offlineWindow = 100
+
+// storeOfflineMessage 存储离线消息
+func (s *Service) storeOfflineMessage(userUUID string, msg *pb.Message) {
+	if err := offline.Store(context.Background(), s.redis, userUUID, msg); err != nil {
+		log.Logger.Sugar().Errorf("Failed to store offline message: %v", err)
+	}
+}
+
+// SyncOfflineMessages 用户上线时同步离线消息，推送给刚连接的设备
+// 重连意味着之前推送中的消息可能已经丢失，因此清空窗口，从队首重新推送
+func (s *Service) SyncOfflineMessages(userUUID, deviceID string) error {
+	ctx := context.Background()
+	if err := s.redis.Del(ctx, offline.PendingKey(userUUID)).Err(); err != nil {
+		return err
+	}
+	return s.pushOfflineWindow(ctx, userUUID, deviceID)
+}
+
+// ResumeOfflineMessages Gateway 发现离线队列停滞（窗口超时仍未确认）时请求续推
+// 不清空窗口：窗口已超时则从队首重新推送，期间已被其他设备重新同步时只推送窗口剩余的空间，避免重复推送
+func (s *Service) ResumeOfflineMessages(userUUID, deviceID string) error {
+	return s.pushOfflineWindow(context.Background(), userUUID, deviceID)
+}
+
+// AckOfflineMessages 客户端确认已收到离线消息，删除后继续向该设备推送窗口内的后续消息
+// deviceID 为空时（如 HTTP 确认）推送给用户所有在线设备
+func (s *Service) AckOfflineMessages(ctx context.Context, userUUID, deviceID string, msgIDs []string) (int64, error) {
+	if len(msgIDs) == 0 {
+		return 0, nil
+	}
+
+	removed, err := s.deleteOfflineMessages(ctx, userUUID, msgIDs)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := s.pushOfflineWindow(ctx, userUUID, deviceID); err != nil {
+		log.Logger.Sugar().Warnf("Failed to push next offline window for user %s: %v", userUUID, err)
+	}
+	return removed, nil
+}
+
+// deleteOfflineMessages 从离线队列中删除消息，返回实际删除的条数
+func (s *Service) deleteOfflineMessages(ctx context.Context, userUUID string, msgIDs []string) (int64, error) {
+	members := make([]interface{}, len(msgIDs))
+	for i, id := range msgIDs {
+		members[i] = id
+	}
+
+	pipe := s.redis.TxPipeline()
+	removed := pipe.ZRem(ctx, offline.QueueKey(userUUID), members...)
+	pipe.HDel(ctx, offline.BodyKey(userUUID), msgIDs...)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return removed.Val(), nil
+}
+
+// pushOfflineWindow 在窗口允许的范围内推送尚未推送过的离线消息
+func (s *Service) pushOfflineWindow(ctx context.Context, userUUID, deviceID string) error {
+	// 获取目标设备所在的网关
+	gateways := routeGateways(s.getUserRoutes(userUUID), deviceID, "")
+	if len(gateways) == 0 {
+		return nil // 用户不在线，跳过
+	}
+
+	queueKey := offline.QueueKey(userUUID)
+	pendingKey := offline.PendingKey(userUUID)
+
+	// 窗口内已推送但未确认的消息数
+	pendingMax, err := s.redis.Get(ctx, pendingKey).Int64()
+	if err != nil && err != redis.Nil {
+		return err
+	}
+	inflight, err := s.redis.ZCount(ctx, queueKey, "-inf", formatScore(pendingMax)).Result()
+	if err != nil {
+		return err
+	}
+	room := offlineWindow - inflight
+	if room <= 0 {
+		return nil
+	}
+
+	entries, err := s.redis.ZRangeByScoreWithScores(ctx, queueKey, &redis.ZRangeBy{
+		Min:   "(" + formatScore(pendingMax),
+		Max:   "+inf",
+		Count: room,
+	}).Result()
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+
+	msgIDs := make([]string, len(entries))
+	for i, e := range entries {
+		msgIDs[i] = e.Member.(string)
+	}
+	bodies, err := s.redis.HMGet(ctx, offline.BodyKey(userUUID), msgIDs...).Result()
+	if err != nil {
+		return err
+	}
+
+	// 推送离线消息到网关
+	cfg := config.GetConfig()
+	now := time.Now().Unix()
+	var orphans []string
+	for i, body := range bodies {
+		msgData, ok := body.(string)
+		var msg pb.Message
+		if !ok || proto.Unmarshal([]byte(msgData), &msg) != nil {
+			// 消息体已丢失（如过期）或无法解析，队列中的记录无法投递，直接清理
+			orphans = append(orphans, msgIDs[i])
+			continue
+		}
+		if msg.ExpireAt > 0 && msg.ExpireAt <= now {
+			// 会话开启了消息自动删除，消息在用户离线期间已经过期
+			orphans = append(orphans, msgIDs[i])
+			continue
+		}
+		msg.TargetDeviceID = deviceID
+		for _, gatewayID := range gateways {
+			s.publishToKafka(cfg.Kafka.Topics.Delivery+gatewayID, &msg)
+		}
+	}
+	if len(orphans) > 0 {
+		s.deleteOfflineMessages(ctx, userUUID, orphans)
+	}
+
+	// 记录窗口位置；超时未确认时窗口失效，下次同步从队首重新推送
+	last := int64(entries[len(entries)-1].Score)
+	if err := s.redis.Set(ctx, pendingKey, last, offline.PendingTimeout).Err(); err != nil {
+		return err
+	}
+	if len(orphans) == len(entries) {
+		// 窗口内的消息都已清理，客户端不会确认，直接推送下一个窗口
+		return s.pushOfflineWindow(ctx, userUUID, deviceID)
+	}
+
+	log.Logger.Sugar().Infof("Pushed %d offline messages for user: %s", len(entries), userUUID)
+	return nil
+}
+
+func formatScore(score int64) string {
+	return strconv.FormatInt(score, 10)
+}
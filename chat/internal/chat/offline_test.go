@@ -0,0 +1,45 @@
+package chat
+
+import (
+	pb "MyGoChat/pkg/api/v1"
+	"MyGoChat/pkg/offline"
+	"MyGoChat/pkg/route"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+// TestResumeOfflineMessages 测试推送窗口超时仍未确认时续推会重新推送，窗口仍有效时不重复推送
+func TestResumeOfflineMessages(t *testing.T) {
+	ctx := context.Background()
+	service, producer := newAckTestService(t, new(MockRepository))
+	require.NoError(t, route.Add(ctx, service.redis, "recipient", route.Route{GatewayID: "gw-1", Instance: "instance-1", DeviceID: "web"}))
+
+	for _, id := range []string{"msg-1", "msg-2"} {
+		require.NoError(t, offline.Store(ctx, service.redis, "recipient", &pb.Message{Id: id, RecipientUUID: "recipient"}))
+	}
+
+	require.NoError(t, service.SyncOfflineMessages("recipient", "web"))
+	assert.Len(t, producer.SentMessages, 2)
+
+	// 窗口仍有效（如刚由重连同步过），续推不重复推送
+	require.NoError(t, service.ResumeOfflineMessages("recipient", "web"))
+	assert.Len(t, producer.SentMessages, 2)
+
+	// 确认了一条，另一条在窗口超时后重新推送
+	_, err := service.AckOfflineMessages(ctx, "recipient", "web", []string{"msg-1"})
+	require.NoError(t, err)
+	assert.Len(t, producer.SentMessages, 2)
+
+	producer.SentMessages = nil
+	require.NoError(t, service.redis.Del(ctx, offline.PendingKey("recipient")).Err())
+	require.NoError(t, service.ResumeOfflineMessages("recipient", "web"))
+	require.Len(t, producer.SentMessages, 1)
+	var resent pb.Message
+	require.NoError(t, proto.Unmarshal(producer.SentMessages[0].Message, &resent))
+	assert.Equal(t, "msg-2", resent.Id)
+	assert.Equal(t, "web", resent.TargetDeviceID)
+}
@@ -0,0 +1,138 @@
+package chat
+
+import (
+	pb "MyGoChat/pkg/api/v1"
+	"MyGoChat/chat/internal/relation"
+	"MyGoChat/pkg/config"
+	"MyGoChat/pkg/log"
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// 未配置 Chat.maxPins 时每个会话最多置顶的消息数
+const defaultMaxPins = 10
+
+var (
+	ErrPinForbidden    = errors.New("no permission to pin messages in this conversation")
+	ErrPinLimitReached = errors.New("too many pinned messages in this conversation")
+)
+
+// maxPins 返回配置的置顶上限
+func maxPins() int {
+	if n := config.GetConfig().Chat.MaxPins; n > 0 {
+		return n
+	}
+	return defaultMaxPins
+}
+
+// canManageConversation 是否可以修改会话设置（置顶消息、消息自动删除）：私聊双方都可以，群聊只有管理员和群主可以
+func canManageConversation(rel *relation.Relation) bool {
+	if rel.Type == relation.TypeGroup {
+		return rel.IsGroupAdmin()
+	}
+	return rel.Status == 1
+}
+
+// SetPinned 置顶（pinned=true）或取消置顶（pinned=false）消息
+// 置顶状态发生变化时向会话所有在线成员推送置顶事件，离线成员通过会话列表中的 Pins 获取
+func (s *Service) SetPinned(ctx context.Context, userUUID, msgID string, pinned bool) (bool, error) {
+	msg, err := s.repo.GetMessageByID(ctx, msgID)
+	if err != nil {
+		return false, ErrMessageNotFound
+	}
+	if msg.Recalled && pinned {
+		return false, ErrAlreadyRecalled
+	}
+
+	rel, err := s.relRepo.GetRelationByConversation(ctx, userUUID, msg.ConversationID)
+	if err != nil {
+		return false, ErrNotMember
+	}
+	if !canManageConversation(rel) {
+		return false, ErrPinForbidden
+	}
+
+	now := time.Now().Unix()
+	var changed bool
+	if pinned {
+		changed, err = s.repo.PinMessage(ctx, msg.ConversationID, &Pin{
+			MessageID: msgID,
+			PinnedBy:  userUUID,
+			PinnedAt:  now,
+		}, maxPins())
+	} else {
+		changed, err = s.repo.UnpinMessage(ctx, msg.ConversationID, msgID)
+	}
+	if err != nil || !changed {
+		return false, err
+	}
+
+	s.pushPin(ctx, rel, &pb.PinEvent{
+		ConversationID: msg.ConversationID,
+		MessageID:      msgID,
+		OperatorUUID:   userUUID,
+		Pinned:         pinned,
+		PinnedAt:       now,
+	})
+	return true, nil
+}
+
+// pushPin 向会话所有在线成员推送置顶事件
+func (s *Service) pushPin(ctx context.Context, rel *relation.Relation, event *pb.PinEvent) {
+	members, err := s.conversationMembers(ctx, rel)
+	if err != nil {
+		log.Logger.Sugar().Errorf("pushPin: failed to get members of %s: %v", event.ConversationID, err)
+		return
+	}
+	for _, memberUUID := range members {
+		pushMsg, err := newEventMessage(event.ConversationID, memberUUID, int32(rel.Type), pb.EventTypePin, event)
+		if err != nil {
+			log.Logger.Sugar().Errorf("pushPin: failed to build pin event: %v", err)
+			return
+		}
+		s.routeToUser(memberUUID, pushMsg, false)
+	}
+}
+
+// GetPinnedMessages 获取会话的置顶消息，最近置顶的在前
+func (s *Service) GetPinnedMessages(ctx context.Context, userUUID, conversationID string) ([]*PinnedMessage, error) {
+	if _, err := s.relRepo.GetRelationByConversation(ctx, userUUID, conversationID); err != nil {
+		return nil, ErrNotMember
+	}
+
+	pins, err := s.repo.GetPins(ctx, conversationID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		// 会话还没有消息时文档不存在，视为没有置顶
+		return []*PinnedMessage{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	msgIDs := make([]string, len(pins))
+	for i, p := range pins {
+		msgIDs[i] = p.MessageID
+	}
+	messages, err := s.repo.GetMessagesByIDs(ctx, msgIDs)
+	if err != nil {
+		return nil, err
+	}
+	s.attachReplySnippets(ctx, messages)
+	s.attachReactions(ctx, userUUID, messages)
+
+	byID := make(map[string]*Message, len(messages))
+	for _, m := range messages {
+		byID[m.ID.Hex()] = m
+	}
+
+	result := make([]*PinnedMessage, 0, len(pins))
+	for i := len(pins) - 1; i >= 0; i-- {
+		if m, ok := byID[pins[i].MessageID]; ok {
+			result = append(result, &PinnedMessage{Pin: *pins[i], Message: m})
+		}
+	}
+	return result, nil
+}
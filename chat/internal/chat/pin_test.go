@@ -0,0 +1,19 @@
+package chat
+
+import (
+	"MyGoChat/chat/internal/relation"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCanManageConversation 测试会话设置权限：私聊双方都可以，群聊只有管理员和群主可以
+func TestCanManageConversation(t *testing.T) {
+	assert.True(t, canManageConversation(&relation.Relation{Type: relation.TypePrivate, Status: 1}))
+	// 已拉黑的私聊关系不能修改
+	assert.False(t, canManageConversation(&relation.Relation{Type: relation.TypePrivate, Status: 2}))
+
+	assert.False(t, canManageConversation(&relation.Relation{Type: relation.TypeGroup, Status: relation.GroupRoleMember}))
+	assert.True(t, canManageConversation(&relation.Relation{Type: relation.TypeGroup, Status: relation.GroupRoleAdmin}))
+	assert.True(t, canManageConversation(&relation.Relation{Type: relation.TypeGroup, Status: relation.GroupRoleOwner}))
+}
@@ -0,0 +1,28 @@
+package chat
+
+import (
+	pb "MyGoChat/pkg/api/v1"
+	"context"
+)
+
+// PushPresence 将用户对其他人可见的在线状态推送给在线的好友，离线好友上线后通过在线状态接口查询
+func (s *Service) PushPresence(ctx context.Context, userUUID, status string, lastSeen int64) error {
+	friends, err := s.relRepo.GetFriendUUIDs(ctx, userUUID)
+	if err != nil {
+		return err
+	}
+
+	event := &pb.PresenceEvent{
+		UserUUID: userUUID,
+		Status:   status,
+		LastSeen: lastSeen,
+	}
+	for _, friendUUID := range friends {
+		pushMsg, err := newEventMessage("", friendUUID, 1, pb.EventTypePresence, event)
+		if err != nil {
+			return err
+		}
+		s.routeToUser(friendUUID, pushMsg, false)
+	}
+	return nil
+}
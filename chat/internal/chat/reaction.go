@@ -0,0 +1,131 @@
+package chat
+
+import (
+	pb "MyGoChat/pkg/api/v1"
+	"MyGoChat/pkg/log"
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+// 表情的最大长度（字节），组合表情由多个码点组成
+const maxEmojiLength = 64
+
+var ErrInvalidEmoji = errors.New("invalid emoji")
+
+// ReactionResult 表情回应变化后的结果
+type ReactionResult struct {
+	MessageID string
+	Emoji     string
+	Count     int64 // 变化后该表情的回应人数
+	Changed   bool  // 重复添加或取消不存在的回应时为 false
+}
+
+// validateEmoji 校验表情：不能为空、不能包含空白且长度有限
+func validateEmoji(emoji string) error {
+	if emoji == "" || len(emoji) > maxEmojiLength || strings.TrimSpace(emoji) != emoji {
+		return ErrInvalidEmoji
+	}
+	return nil
+}
+
+// React 添加（remove=false）或取消（remove=true）对消息的表情回应
+// 回应发生变化时向会话所有在线成员推送回应事件；离线成员通过历史消息中的汇总获取
+func (s *Service) React(ctx context.Context, userUUID, msgID, emoji string, remove bool) (*ReactionResult, error) {
+	if err := validateEmoji(emoji); err != nil {
+		return nil, err
+	}
+
+	msg, err := s.repo.GetMessageByID(ctx, msgID)
+	if err != nil {
+		return nil, ErrMessageNotFound
+	}
+	if msg.Recalled && !remove {
+		return nil, ErrAlreadyRecalled
+	}
+
+	rel, err := s.relRepo.GetRelationByConversation(ctx, userUUID, msg.ConversationID)
+	if err != nil {
+		return nil, ErrNotMember
+	}
+
+	var changed bool
+	if remove {
+		changed, err = s.repo.RemoveReaction(ctx, msgID, userUUID, emoji)
+	} else {
+		changed, err = s.repo.AddReaction(ctx, &Reaction{
+			MessageID:      msgID,
+			ConversationID: msg.ConversationID,
+			UserUUID:       userUUID,
+			Emoji:          emoji,
+			CreatedAt:      time.Now(),
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := s.repo.CountReactions(ctx, msgID, emoji)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ReactionResult{MessageID: msgID, Emoji: emoji, Count: count, Changed: changed}
+	if !changed {
+		return result, nil
+	}
+
+	event := &pb.ReactionEvent{
+		ConversationID: msg.ConversationID,
+		MessageID:      msgID,
+		UserUUID:       userUUID,
+		Emoji:          emoji,
+		Added:          !remove,
+		Count:          count,
+	}
+	members, err := s.conversationMembers(ctx, rel)
+	if err != nil {
+		log.Logger.Sugar().Errorf("React: failed to get members of %s: %v", msg.ConversationID, err)
+		return result, nil
+	}
+	for _, memberUUID := range members {
+		pushMsg, err := newEventMessage(msg.ConversationID, memberUUID, int32(rel.Type), pb.EventTypeReaction, event)
+		if err != nil {
+			log.Logger.Sugar().Errorf("React: failed to build reaction event: %v", err)
+			break
+		}
+		s.routeToUser(memberUUID, pushMsg, false)
+	}
+	return result, nil
+}
+
+// attachReactions 为消息批量填充表情回应汇总，ReactedByMe 相对于 userUUID
+func (s *Service) attachReactions(ctx context.Context, userUUID string, messages []*Message) {
+	if len(messages) == 0 {
+		return
+	}
+	msgIDs := make([]string, len(messages))
+	for i, m := range messages {
+		msgIDs[i] = m.ID.Hex()
+	}
+
+	summaries, err := s.repo.GetReactionSummaries(ctx, msgIDs, userUUID)
+	if err != nil {
+		log.Logger.Sugar().Warnf("Failed to load reactions: %v", err)
+		return
+	}
+	for _, m := range messages {
+		m.Reactions = summaries[m.ID.Hex()]
+	}
+}
+
+// reactFromDevice 处理 WebSocket 表情回应命令，结果以 ACK / NACK 推送给发起命令的设备
+func (s *Service) reactFromDevice(ctx context.Context, userUUID, deviceID, requestID, msgID, emoji string, remove bool) {
+	if _, err := s.React(ctx, userUUID, msgID, emoji, remove); err != nil {
+		log.Logger.Sugar().Warnf("Failed to react to message %s for user %s: %v", msgID, userUUID, err)
+		s.nackCommand(userUUID, deviceID, requestID, msgID, err)
+		return
+	}
+	s.ackCommand(userUUID, deviceID, requestID, &pb.Ack{MessageID: msgID})
+}
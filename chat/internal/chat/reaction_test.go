@@ -0,0 +1,18 @@
+package chat
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestValidateEmoji 测试表情校验：允许组合表情，拒绝空白和过长的表情
+func TestValidateEmoji(t *testing.T) {
+	assert.NoError(t, validateEmoji("👍"))
+	assert.NoError(t, validateEmoji("👨‍👩‍👧‍👦"))
+	assert.NoError(t, validateEmoji(":+1:"))
+
+	assert.ErrorIs(t, validateEmoji(""), ErrInvalidEmoji)
+	assert.ErrorIs(t, validateEmoji(" 👍"), ErrInvalidEmoji)
+	assert.ErrorIs(t, validateEmoji(string(make([]byte, maxEmojiLength+1))), ErrInvalidEmoji)
+}
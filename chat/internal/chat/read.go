@@ -0,0 +1,155 @@
+package chat
+
+import (
+	pb "MyGoChat/pkg/api/v1"
+	"MyGoChat/chat/internal/relation"
+	"MyGoChat/pkg/log"
+	"context"
+	"errors"
+	"time"
+)
+
+// 单次已读最多回溯的消息数，避免一次性读完大量积压消息时扫描整个会话
+const readReceiptScanWindow = 500
+
+var (
+	ErrNotMember     = errors.New("not a member of the conversation")
+	ErrInvalidReadTo = errors.New("read position is required")
+)
+
+// ReadResult 已读游标推进结果
+type ReadResult struct {
+	ConversationID string
+	LastReadSeq    int64
+	Advanced       bool
+}
+
+// MarkConversationRead 将用户在会话中的已读位置推进到 seq（或 msgID 对应的消息）
+// 游标只进不退；推进成功后向区间内消息的发送者推送已读回执
+func (s *Service) MarkConversationRead(ctx context.Context, userUUID, conversationID string, seq int64, msgID string) (*ReadResult, error) {
+	rel, err := s.relRepo.GetRelationByConversation(ctx, userUUID, conversationID)
+	if err != nil || (rel.Type == relation.TypePrivate && rel.Status == 2) {
+		return nil, ErrNotMember
+	}
+
+	// 只给了消息 ID 时换算为序号
+	if seq <= 0 && msgID != "" {
+		msg, err := s.repo.GetMessageByID(ctx, msgID)
+		if err != nil || msg.ConversationID != conversationID {
+			return nil, ErrInvalidReadTo
+		}
+		seq = msg.Seq
+	}
+	if seq <= 0 {
+		return nil, ErrInvalidReadTo
+	}
+
+	conv, err := s.repo.GetConversationByID(ctx, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	// 不允许读到尚未分配的序号
+	if seq > conv.MaxSeq {
+		seq = conv.MaxSeq
+	}
+
+	prevSeq, advanced, err := s.repo.AdvanceReadCursor(ctx, userUUID, conversationID, seq, msgID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ReadResult{ConversationID: conversationID, LastReadSeq: seq, Advanced: advanced}
+	if !advanced {
+		return result, nil
+	}
+
+	count, countedSeq := s.unreadAfter(ctx, conv, seq)
+	s.setUnread(ctx, userUUID, conversationID, count, countedSeq)
+
+	s.pushReadReceipts(ctx, userUUID, conv, prevSeq, seq)
+	return result, nil
+}
+
+// markSenderRead 发送者自己发出的消息视为已读
+// 游标已经不落后于该消息时（如已读位置由其他设备先推进）跳过写入
+func (s *Service) markSenderRead(ctx context.Context, msg *pb.Message) {
+	readSeqs, err := s.repo.GetUserReadSeqs(ctx, msg.SenderUUID, []string{msg.ConversationID})
+	if err == nil && readSeqs[msg.ConversationID] >= msg.Seq {
+		return
+	}
+	if _, _, err := s.repo.AdvanceReadCursor(ctx, msg.SenderUUID, msg.ConversationID, msg.Seq, msg.Id); err != nil {
+		log.Logger.Sugar().Warnf("Failed to advance read cursor for sender %s: %v", msg.SenderUUID, err)
+		return
+	}
+	s.setUnread(ctx, msg.SenderUUID, msg.ConversationID, 0, msg.Seq)
+}
+
+// readFromDevice 处理 WebSocket 已读命令，结果以 ACK / NACK 推送给发起命令的设备，ACK 携带推进后的已读序号
+func (s *Service) readFromDevice(ctx context.Context, userUUID, deviceID, requestID, conversationID string, seq int64, msgID string) {
+	result, err := s.MarkConversationRead(ctx, userUUID, conversationID, seq, msgID)
+	if err != nil {
+		log.Logger.Sugar().Warnf("Failed to mark %s read for user %s: %v", conversationID, userUUID, err)
+		s.nackCommand(userUUID, deviceID, requestID, msgID, err)
+		return
+	}
+	s.ackCommand(userUUID, deviceID, requestID, &pb.Ack{
+		MessageID:      msgID,
+		ConversationID: result.ConversationID,
+		Seq:            result.LastReadSeq,
+	})
+}
+
+// pushReadReceipts 向 (fromSeq, toSeq] 区间内消息的发送者推送已读回执
+// 私聊回执告知对方"已读"，群聊回执携带该发送者最新一条消息的已读人数
+// 回执只推给在线用户，离线用户上线后可通过 GetReadReceipts 拉取
+func (s *Service) pushReadReceipts(ctx context.Context, readerUUID string, conv *Conversation, fromSeq, toSeq int64) {
+	if toSeq-fromSeq > readReceiptScanWindow {
+		fromSeq = toSeq - readReceiptScanWindow
+	}
+
+	senders, err := s.repo.GetSendersInRange(ctx, conv.ID, fromSeq, toSeq)
+	if err != nil {
+		log.Logger.Sugar().Errorf("pushReadReceipts: failed to get senders for %s: %v", conv.ID, err)
+		return
+	}
+
+	readAt := time.Now().Unix()
+	for senderUUID, senderSeq := range senders {
+		if senderUUID == readerUUID {
+			continue
+		}
+
+		receipt := &pb.ReadReceipt{
+			ConversationID: conv.ID,
+			ReaderUUID:     readerUUID,
+			Seq:            toSeq,
+			ReadCount:      1,
+			ReadAt:         readAt,
+		}
+		if conv.Type == 2 {
+			// 群聊统计时排除发送者本人
+			count, err := s.repo.CountReaders(ctx, conv.ID, senderSeq)
+			if err != nil {
+				log.Logger.Sugar().Warnf("pushReadReceipts: failed to count readers for %s: %v", conv.ID, err)
+				continue
+			}
+			receipt.Seq = senderSeq
+			receipt.ReadCount = count - 1
+		}
+
+		event, err := newEventMessage(conv.ID, senderUUID, int32(conv.Type), pb.EventTypeReadReceipt, receipt)
+		if err != nil {
+			log.Logger.Sugar().Errorf("pushReadReceipts: failed to build receipt: %v", err)
+			continue
+		}
+		s.routeToUser(senderUUID, event, false)
+	}
+}
+
+// GetReadReceipts 获取会话中各成员的已读游标，用于客户端渲染"已读/已读人数"
+func (s *Service) GetReadReceipts(ctx context.Context, userUUID, conversationID string) ([]*ReadCursor, error) {
+	if _, err := s.relRepo.GetRelationByConversation(ctx, userUUID, conversationID); err != nil {
+		return nil, ErrNotMember
+	}
+	return s.repo.GetReadCursors(ctx, conversationID)
+}
@@ -0,0 +1,112 @@
+package chat
+
+import (
+	pb "MyGoChat/pkg/api/v1"
+	"MyGoChat/chat/internal/relation"
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// newTestRedis 启动内存 Redis，测试结束时自动关闭
+func newTestRedis(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	mr := miniredis.RunT(t)
+	return mr, redis.NewClient(&redis.Options{Addr: mr.Addr()})
+}
+
+// stubRelationRepo 只实现测试用到的 relation.Repository 方法，其余方法调用时 panic
+type stubRelationRepo struct {
+	relation.Repository
+	relations map[string]*relation.Relation // userUUID -> 关系
+}
+
+func (r *stubRelationRepo) GetRelationByConversation(ctx context.Context, userUUID, conversationID string) (*relation.Relation, error) {
+	rel, ok := r.relations[userUUID]
+	if !ok || rel.ConversationID != conversationID {
+		return nil, ErrNotMember
+	}
+	return rel, nil
+}
+
+func newReadTestService(t *testing.T, repo *MockRepository) (*Service, *miniredis.Miniredis) {
+	mr, rdb := newTestRedis(t)
+	relRepo := &stubRelationRepo{relations: map[string]*relation.Relation{
+		"reader": {UserUUID: "reader", ConversationID: "conv-1", Type: relation.TypeGroup},
+	}}
+	return &Service{repo: repo, relRepo: relRepo, redis: rdb}, mr
+}
+
+// TestMarkConversationRead_OnlyForward 测试已读游标只进不退：游标没有推进时不重置未读数、不推送回执
+func TestMarkConversationRead_OnlyForward(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(MockRepository)
+	mockRepo.On("GetConversationByID", mock.Anything, "conv-1").
+		Return(&Conversation{ID: "conv-1", Type: 2, MaxSeq: 10}, nil)
+	mockRepo.On("AdvanceReadCursor", mock.Anything, "reader", "conv-1", int64(4), "").
+		Return(int64(8), false, nil)
+
+	service, mr := newReadTestService(t, mockRepo)
+	mr.HSet(unreadKeyPrefix+"reader", "conv-1", "2")
+
+	result, err := service.MarkConversationRead(ctx, "reader", "conv-1", 4, "")
+	assert.NoError(t, err)
+	assert.False(t, result.Advanced)
+	assert.Equal(t, "2", mr.HGet(unreadKeyPrefix+"reader", "conv-1"), "unread must not change when the cursor stays")
+
+	mockRepo.AssertNotCalled(t, "GetSendersInRange", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	mockRepo.AssertExpectations(t)
+}
+
+// TestMarkConversationRead_ResetsUnread 测试推进已读游标后未读数重置为 MaxSeq - 已读序号，且不能读到尚未分配的序号
+func TestMarkConversationRead_ResetsUnread(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(MockRepository)
+	mockRepo.On("GetConversationByID", mock.Anything, "conv-1").
+		Return(&Conversation{ID: "conv-1", Type: 2, MaxSeq: 10}, nil)
+	mockRepo.On("AdvanceReadCursor", mock.Anything, "reader", "conv-1", int64(10), "").
+		Return(int64(3), true, nil)
+	mockRepo.On("GetSendersInRange", mock.Anything, "conv-1", int64(3), int64(10)).
+		Return(map[string]int64{}, nil)
+
+	service, mr := newReadTestService(t, mockRepo)
+	mr.HSet(unreadKeyPrefix+"reader", "conv-1", "7")
+
+	result, err := service.MarkConversationRead(ctx, "reader", "conv-1", 50, "")
+	assert.NoError(t, err)
+	assert.True(t, result.Advanced)
+	assert.Equal(t, int64(10), result.LastReadSeq, "read position is capped at MaxSeq")
+	assert.Equal(t, "0", mr.HGet(unreadKeyPrefix+"reader", "conv-1"))
+
+	_, err = service.MarkConversationRead(ctx, "stranger", "conv-1", 5, "")
+	assert.ErrorIs(t, err, ErrNotMember)
+
+	mockRepo.AssertExpectations(t)
+}
+
+// TestMarkSenderRead 测试发送者的已读游标已经不落后于新消息时跳过写入
+func TestMarkSenderRead(t *testing.T) {
+	ctx := context.Background()
+	msg := &pb.Message{Id: "msg-1", SenderUUID: "reader", ConversationID: "conv-1", Seq: 6}
+
+	mockRepo := new(MockRepository)
+	mockRepo.On("GetUserReadSeqs", mock.Anything, "reader", []string{"conv-1"}).
+		Return(map[string]int64{"conv-1": 6}, nil).Once()
+	service, mr := newReadTestService(t, mockRepo)
+
+	service.markSenderRead(ctx, msg)
+	mockRepo.AssertNotCalled(t, "AdvanceReadCursor", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+	mockRepo.On("GetUserReadSeqs", mock.Anything, "reader", []string{"conv-1"}).
+		Return(map[string]int64{"conv-1": 5}, nil).Once()
+	mockRepo.On("AdvanceReadCursor", mock.Anything, "reader", "conv-1", int64(6), "msg-1").
+		Return(int64(5), true, nil)
+	mr.HSet(unreadKeyPrefix+"reader", "conv-1", "1")
+
+	service.markSenderRead(ctx, msg)
+	assert.Equal(t, "0", mr.HGet(unreadKeyPrefix+"reader", "conv-1"))
+	mockRepo.AssertExpectations(t)
+}
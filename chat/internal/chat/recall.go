@@ -0,0 +1,130 @@
+package chat
+
+import (
+	pb "MyGoChat/pkg/api/v1"
+	"MyGoChat/chat/internal/relation"
+	"MyGoChat/pkg/config"
+	"MyGoChat/pkg/log"
+	"context"
+	"errors"
+	"time"
+)
+
+// 未配置 Chat.recallWindow 时允许撤回的时间窗口
+const defaultRecallWindow = 2 * time.Minute
+
+var (
+	ErrMessageNotFound = errors.New("message not found")
+	ErrAlreadyRecalled = errors.New("message already recalled")
+	ErrRecallExpired   = errors.New("recall window has expired")
+	ErrRecallForbidden = errors.New("no permission to recall the message")
+)
+
+// recallWindow 返回配置的撤回时间窗口
+func recallWindow() time.Duration {
+	if minutes := config.GetConfig().Chat.RecallWindow; minutes > 0 {
+		return time.Duration(minutes) * time.Minute
+	}
+	return defaultRecallWindow
+}
+
+// checkRecall 校验撤回权限：发送者本人或群管理员可以撤回，且消息必须在撤回窗口内
+func checkRecall(msg *Message, operatorUUID string, isGroupAdmin bool, now time.Time, window time.Duration) error {
+	if msg.Recalled {
+		return ErrAlreadyRecalled
+	}
+	if msg.SenderUUID != operatorUUID && !isGroupAdmin {
+		return ErrRecallForbidden
+	}
+	if now.Sub(time.Unix(msg.SendAt, 0)) > window {
+		return ErrRecallExpired
+	}
+	return nil
+}
+
+// RecallMessage 撤回消息：标记为已撤回并清空消息体，必要时更新会话的最后一条消息，
+// 然后向会话所有成员推送撤回事件（离线成员存入离线队列）
+func (s *Service) RecallMessage(ctx context.Context, operatorUUID, msgID string) (*Message, error) {
+	msg, err := s.repo.GetMessageByID(ctx, msgID)
+	if err != nil {
+		return nil, ErrMessageNotFound
+	}
+
+	rel, err := s.relRepo.GetRelationByConversation(ctx, operatorUUID, msg.ConversationID)
+	if err != nil {
+		return nil, ErrNotMember
+	}
+
+	// 群聊中群管理员和群主可以撤回其他成员的消息
+	now := time.Now()
+	if err := checkRecall(msg, operatorUUID, rel.IsGroupAdmin(), now, recallWindow()); err != nil {
+		return nil, err
+	}
+
+	recalled, err := s.repo.RecallMessage(ctx, msg.ID, operatorUUID, now.Unix())
+	if err != nil {
+		return nil, err
+	}
+	if !recalled {
+		return nil, ErrAlreadyRecalled
+	}
+	msg.Recalled = true
+	msg.RecalledAt = now.Unix()
+	msg.RecalledBy = operatorUUID
+	msg.Body = nil
+
+	if err := s.repo.ReplaceLastMessage(ctx, msg.ConversationID, msgID, RecalledMessageText); err != nil {
+		log.Logger.Sugar().Warnf("Failed to update last message of conversation %s: %v", msg.ConversationID, err)
+	}
+
+	// 被撤回的消息同时取消置顶，客户端收到撤回事件后自行移除
+	if _, err := s.repo.UnpinMessage(ctx, msg.ConversationID, msgID); err != nil {
+		log.Logger.Sugar().Warnf("Failed to unpin recalled message %s: %v", msgID, err)
+	}
+
+	s.pushRecall(ctx, rel, msg)
+
+	log.Logger.Sugar().Infof("Message recalled: id=%s, operator=%s", msgID, operatorUUID)
+	return msg, nil
+}
+
+// pushRecall 向会话所有成员（包括操作者的所有设备）推送撤回事件
+// 原消息还在成员离线队列中时一并删除，避免上线后先收到原消息
+func (s *Service) pushRecall(ctx context.Context, rel *relation.Relation, msg *Message) {
+	targetUsers, err := s.conversationMembers(ctx, rel)
+	if err != nil {
+		log.Logger.Sugar().Errorf("pushRecall: failed to get members of %s: %v", msg.ConversationID, err)
+		return
+	}
+
+	recall := &pb.MessageRecall{
+		ConversationID: msg.ConversationID,
+		MessageID:      msg.ID.Hex(),
+		Seq:            msg.Seq,
+		SenderUUID:     msg.SenderUUID,
+		OperatorUUID:   msg.RecalledBy,
+		RecalledAt:     msg.RecalledAt,
+	}
+	for _, userUUID := range targetUsers {
+		if _, err := s.deleteOfflineMessages(ctx, userUUID, []string{recall.MessageID}); err != nil {
+			log.Logger.Sugar().Warnf("pushRecall: failed to remove offline message for %s: %v", userUUID, err)
+		}
+
+		event, err := newEventMessage(msg.ConversationID, userUUID, int32(rel.Type), pb.EventTypeRecall, recall)
+		if err != nil {
+			log.Logger.Sugar().Errorf("pushRecall: failed to build recall event: %v", err)
+			return
+		}
+		s.routeToUser(userUUID, event, true)
+	}
+}
+
+// recallFromDevice 处理 WebSocket 撤回命令，结果以 ACK / NACK 推送给发起命令的设备
+func (s *Service) recallFromDevice(ctx context.Context, userUUID, deviceID, requestID, msgID string) {
+	if _, err := s.RecallMessage(ctx, userUUID, msgID); err != nil {
+		log.Logger.Sugar().Warnf("Failed to recall message %s for user %s: %v", msgID, userUUID, err)
+		s.nackCommand(userUUID, deviceID, requestID, msgID, err)
+		return
+	}
+	s.ackCommand(userUUID, deviceID, requestID, &pb.Ack{MessageID: msgID})
+}
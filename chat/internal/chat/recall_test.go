@@ -0,0 +1,37 @@
+package chat
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TestCheckRecall 测试撤回权限与时间窗口校验
+func TestCheckRecall(t *testing.T) {
+	now := time.Now()
+	window := 2 * time.Minute
+	msg := &Message{
+		ID:         primitive.NewObjectID(),
+		SenderUUID: "sender-uuid",
+		SendAt:     now.Add(-time.Minute).Unix(),
+		Seq:        3,
+	}
+
+	// 发送者本人在窗口内可以撤回
+	assert.NoError(t, checkRecall(msg, "sender-uuid", false, now, window))
+
+	// 其他成员不能撤回，群主可以
+	assert.ErrorIs(t, checkRecall(msg, "other-uuid", false, now, window), ErrRecallForbidden)
+	assert.NoError(t, checkRecall(msg, "admin-uuid", true, now, window))
+
+	// 超过窗口后任何人都不能撤回
+	expired := now.Add(3 * time.Minute)
+	assert.ErrorIs(t, checkRecall(msg, "sender-uuid", false, expired, window), ErrRecallExpired)
+	assert.ErrorIs(t, checkRecall(msg, "admin-uuid", true, expired, window), ErrRecallExpired)
+
+	// 已撤回的消息不能重复撤回
+	msg.Recalled = true
+	assert.ErrorIs(t, checkRecall(msg, "sender-uuid", false, now, window), ErrAlreadyRecalled)
+}
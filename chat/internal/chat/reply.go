@@ -0,0 +1,108 @@
+package chat
+
+import (
+	pb "MyGoChat/pkg/api/v1"
+	"MyGoChat/pkg/log"
+	"context"
+	"errors"
+)
+
+// 回复摘要中文本预览的最大字符数
+const replyPreviewLength = 50
+
+var ErrInvalidReplyTarget = errors.New("reply target not found in this conversation")
+
+// loadReplyTarget 获取被回复的消息，必须与回复属于同一会话
+func (s *Service) loadReplyTarget(ctx context.Context, conversationID, replyToMsgID string) (*Message, error) {
+	target, err := s.repo.GetMessageByID(ctx, replyToMsgID)
+	if err != nil || target.ConversationID != conversationID {
+		return nil, ErrInvalidReplyTarget
+	}
+	return target, nil
+}
+
+// attachReplySnippets 为回复消息批量填充被回复消息的摘要
+// 摘要在查询时生成，因此总能反映被回复消息最新的撤回、编辑状态
+func (s *Service) attachReplySnippets(ctx context.Context, messages []*Message) {
+	var replyIDs []string
+	for _, m := range messages {
+		if m.Metadata != nil && m.Metadata.ReplyToMsgID != "" {
+			replyIDs = append(replyIDs, m.Metadata.ReplyToMsgID)
+		}
+	}
+	if len(replyIDs) == 0 {
+		return
+	}
+
+	targets, err := s.repo.GetMessagesByIDs(ctx, replyIDs)
+	if err != nil {
+		log.Logger.Sugar().Warnf("Failed to load reply targets: %v", err)
+		return
+	}
+	byID := make(map[string]*Message, len(targets))
+	for _, t := range targets {
+		byID[t.ID.Hex()] = t
+	}
+
+	for _, m := range messages {
+		if m.Metadata == nil || m.Metadata.ReplyToMsgID == "" {
+			continue
+		}
+		if target, ok := byID[m.Metadata.ReplyToMsgID]; ok {
+			m.Metadata.Reply = newReplySnippet(target)
+		}
+	}
+}
+
+// newReplySnippet 生成被回复消息的摘要
+func newReplySnippet(m *Message) *ReplySnippet {
+	return &ReplySnippet{
+		MessageID:   m.ID.Hex(),
+		SenderUUID:  m.SenderUUID,
+		SenderName:  m.SenderName,
+		ContentType: m.ContentType,
+		Preview:     replyPreview(m),
+		Recalled:    m.Recalled,
+		Seq:         m.Seq,
+	}
+}
+
+// replyPreview 生成消息内容预览：文本按字符截断，文件类消息使用占位描述
+func replyPreview(m *Message) string {
+	if m.Recalled {
+		return ""
+	}
+	switch m.ContentType {
+	case 1: // Text
+		content, _ := m.Body.(string)
+		runes := []rune(content)
+		if len(runes) > replyPreviewLength {
+			return string(runes[:replyPreviewLength]) + "…"
+		}
+		return content
+	case 2: // Image
+		return "[图片]"
+	case 3: // File
+		if file, err := storedFileAttachment(m.Body); err == nil && file.FileName != "" {
+			return "[文件] " + file.FileName
+		}
+		return "[文件]"
+	case 4: // Voice
+		return "[语音]"
+	default:
+		return ""
+	}
+}
+
+// toProto 转换为推送给客户端的 pb.ReplySnippet
+func (r *ReplySnippet) toProto() *pb.ReplySnippet {
+	return &pb.ReplySnippet{
+		MessageID:   r.MessageID,
+		SenderUUID:  r.SenderUUID,
+		SenderName:  r.SenderName,
+		ContentType: int32(r.ContentType),
+		Preview:     r.Preview,
+		Recalled:    r.Recalled,
+		Seq:         r.Seq,
+	}
+}
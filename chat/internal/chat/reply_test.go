@@ -0,0 +1,50 @@
+package chat
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TestReplyPreview 测试被回复消息的内容预览
+func TestReplyPreview(t *testing.T) {
+	assert.Equal(t, "hello", replyPreview(&Message{ContentType: 1, Body: "hello"}))
+
+	// 长文本按字符截断
+	long := strings.Repeat("你好", 40)
+	preview := replyPreview(&Message{ContentType: 1, Body: long})
+	assert.Equal(t, replyPreviewLength+1, len([]rune(preview)))
+	assert.True(t, strings.HasSuffix(preview, "…"))
+
+	// 已撤回的消息不暴露内容
+	assert.Empty(t, replyPreview(&Message{ContentType: 1, Body: "secret", Recalled: true}))
+
+	assert.Equal(t, "[图片]", replyPreview(&Message{ContentType: 2}))
+	file := &Message{ContentType: 3, Body: bson.D{{Key: "fileName", Value: "report.pdf"}}}
+	assert.Equal(t, "[文件] report.pdf", replyPreview(file))
+}
+
+// TestNewReplySnippet 测试摘要携带撤回状态
+func TestNewReplySnippet(t *testing.T) {
+	target := &Message{
+		ID:          primitive.NewObjectID(),
+		SenderUUID:  "sender-uuid",
+		SenderName:  "alice",
+		ContentType: 1,
+		Seq:         9,
+		Recalled:    true,
+	}
+
+	snippet := newReplySnippet(target)
+	assert.Equal(t, target.ID.Hex(), snippet.MessageID)
+	assert.Equal(t, "alice", snippet.SenderName)
+	assert.True(t, snippet.Recalled)
+	assert.Empty(t, snippet.Preview)
+
+	pbSnippet := snippet.toProto()
+	assert.Equal(t, int64(9), pbSnippet.Seq)
+	assert.True(t, pbSnippet.Recalled)
+}
@@ -4,6 +4,8 @@ import (
 	"MyGoChat/chat/internal/platform"
 	"MyGoChat/pkg/log"
 	"context"
+	"errors"
+	"fmt"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -15,28 +17,81 @@ import (
 
 type Repository interface {
 	CreateMsg(ctx context.Context, msg *Message) error
-	GetByConversation(ctx context.Context, convID string, limit, offset int) ([]*Message, error)
-	MarkAsRead(ctx context.Context, msgIDs []string) error
+	GetByConversation(ctx context.Context, convID string, query HistoryQuery) ([]*Message, error)
+	GetUnsequenced(ctx context.Context, convID string, ascending bool, limit int) ([]*Message, error)
+	AllocateSeq(ctx context.Context, conversationID string) (int64, error)
+	GetMessageByID(ctx context.Context, msgID string) (*Message, error)
+	GetMessagesByIDs(ctx context.Context, msgIDs []string) ([]*Message, error)
+	GetMessageByClientMsgID(ctx context.Context, senderUUID, clientMsgID string) (*Message, error)
+	GetSendersInRange(ctx context.Context, convID string, fromSeq, toSeq int64) (map[string]int64, error)
+	GetMentions(ctx context.Context, userUUID string, convIDs []string, before primitive.ObjectID, limit int) ([]*Message, error)
+	RecallMessage(ctx context.Context, msgID primitive.ObjectID, operatorUUID string, recalledAt int64) (bool, error)
+	EditMessage(ctx context.Context, msgID primitive.ObjectID, senderUUID string, body any, editedAt int64) (*Message, error)
+	GetRevisions(ctx context.Context, msgID string) ([]*MessageRevision, error)
+	GetThreadReplies(ctx context.Context, rootID string, before primitive.ObjectID, limit int) ([]*Message, error)
+	UpdateThreadSummary(ctx context.Context, rootID primitive.ObjectID, repliedAt int64, participants []string) (*Message, error)
+
+	AddReaction(ctx context.Context, reaction *Reaction) (bool, error)
+	RemoveReaction(ctx context.Context, msgID, userUUID, emoji string) (bool, error)
+	CountReactions(ctx context.Context, msgID, emoji string) (int64, error)
+	GetReactionSummaries(ctx context.Context, msgIDs []string, userUUID string) (map[string][]*ReactionSummary, error)
+
+	AdvanceReadCursor(ctx context.Context, userUUID, convID string, seq int64, msgID string) (prevSeq int64, advanced bool, err error)
+	GetReadCursors(ctx context.Context, convID string) ([]*ReadCursor, error)
+	GetUserReadSeqs(ctx context.Context, userUUID string, convIDs []string) (map[string]int64, error)
+	CountReaders(ctx context.Context, convID string, seq int64) (int64, error)
 
 	CreateConversation(ctx context.Context, conv *Conversation) error
 	GetConversationByGroupNumber(ctx context.Context, groupNumber string) (*Conversation, error)
 	GetConversationsByUserID(ctx context.Context, userID string, limit int64) ([]*Conversation, error)
 	GetConversationByID(ctx context.Context, conversationID string) (*Conversation, error)
 	UpdateLastMessage(ctx context.Context, conversationID string, message *Message) error
+	ReplaceLastMessage(ctx context.Context, conversationID, msgID string, body any) error
+	CountUnread(ctx context.Context, readSeqs map[string]int64) (map[string]UnreadCount, error)
+	PinMessage(ctx context.Context, conversationID string, pin *Pin, maxPins int) (bool, error)
+	UnpinMessage(ctx context.Context, conversationID, msgID string) (bool, error)
+	GetPins(ctx context.Context, conversationID string) ([]*Pin, error)
+	GetMessageTTL(ctx context.Context, conversationID string) (int64, error)
+	SetMessageTTL(ctx context.Context, conversationID string, ttl int64) (bool, error)
+
+	CreateScheduled(ctx context.Context, sm *ScheduledMessage) error
+	GetScheduled(ctx context.Context, id string) (*ScheduledMessage, error)
+	ListPendingScheduled(ctx context.Context, senderUUID string) ([]*ScheduledMessage, error)
+	UpdatePendingScheduled(ctx context.Context, sm *ScheduledMessage) (bool, error)
+	CancelScheduled(ctx context.Context, id primitive.ObjectID, senderUUID string) (bool, error)
+	ClaimDueScheduled(ctx context.Context, now, staleBefore int64) (*ScheduledMessage, error)
+	FinishScheduled(ctx context.Context, id primitive.ObjectID, status int, reason string) error
+	GetConversationsByIDs(ctx context.Context, convIDs []string) ([]*Conversation, error)
 }
 
+// ErrDuplicateMessage 消息已经入库（重复投递）
+var ErrDuplicateMessage = errors.New("duplicate message")
+
 type repository struct {
-	msgColl  *mongo.Collection
-	convColl *mongo.Collection
+	msgColl   *mongo.Collection
+	convColl  *mongo.Collection
+	readColl  *mongo.Collection
+	revColl   *mongo.Collection
+	reacColl  *mongo.Collection
+	schedColl *mongo.Collection
 }
 
 func NewChatRepo(data *platform.Data) Repository {
 	r := &repository{
-		msgColl:  data.Mdb.Collection("messages"),
-		convColl: data.Mdb.Collection("conversations"),
+		msgColl:   data.Mdb.Collection("messages"),
+		convColl:  data.Mdb.Collection("conversations"),
+		readColl:  data.Mdb.Collection("read_cursors"),
+		revColl:   data.Mdb.Collection("message_revisions"),
+		reacColl:  data.Mdb.Collection("message_reactions"),
+		schedColl: data.Mdb.Collection("scheduled_messages"),
 	}
 
 	r.initConversationIndexes()
+	r.initMessageIndexes()
+	r.initReadCursorIndexes()
+	r.initRevisionIndexes()
+	r.initReactionIndexes()
+	r.initScheduledIndexes()
 
 	return r
 }
@@ -61,17 +116,230 @@ func (r *repository) initConversationIndexes() {
 	}
 }
 
+func (r *repository) initMessageIndexes() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	indexes := []mongo.IndexModel{
+		{
+			// 同一会话内序号唯一；历史数据没有 seq 字段，用部分索引跳过
+			Keys: bson.D{
+				{Key: "conversationID", Value: 1},
+				{Key: "seq", Value: 1},
+			},
+			Options: options.Index().
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{"seq": bson.M{"$gt": 0}}),
+		},
+		{
+			// 幂等键：同一发送者的 clientMsgID 只能入库一次；没有 clientMsgID 的消息（HTTP 发送）不参与
+			Keys: bson.D{
+				{Key: "senderUUID", Value: 1},
+				{Key: "clientMsgID", Value: 1},
+			},
+			Options: options.Index().
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{"clientMsgID": bson.M{"$exists": true}}),
+		},
+		{
+			// 查询 @ 我的消息
+			Keys: bson.D{
+				{Key: "metadata.mentionUUIDs", Value: 1},
+				{Key: "_id", Value: -1},
+			},
+			Options: options.Index().
+				SetPartialFilterExpression(bson.M{"metadata.mentionUUIDs": bson.M{"$exists": true}}),
+		},
+		{
+			// 查询 @所有人 的消息
+			Keys: bson.D{
+				{Key: "conversationID", Value: 1},
+				{Key: "metadata.mentionAll", Value: 1},
+				{Key: "_id", Value: -1},
+			},
+			Options: options.Index().
+				SetPartialFilterExpression(bson.M{"metadata.mentionAll": true}),
+		},
+		{
+			// 话题回复分页
+			Keys: bson.D{
+				{Key: "metadata.threadRootID", Value: 1},
+				{Key: "_id", Value: -1},
+			},
+			Options: options.Index().
+				SetPartialFilterExpression(bson.M{"metadata.threadRootID": bson.M{"$exists": true}}),
+		},
+		{
+			// ObjectID 游标分页（兼容没有 seq 的历史数据）
+			Keys: bson.D{
+				{Key: "conversationID", Value: 1},
+				{Key: "_id", Value: -1},
+			},
+		},
+		{
+			// 消息自动删除：到达 expireAt 后由 MongoDB 后台任务删除，没有该字段的消息不受影响
+			Keys:    bson.D{{Key: "expireAt", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}
+
+	if _, err := r.msgColl.Indexes().CreateMany(ctx, indexes); err != nil {
+		log.Logger.Error("Failed to create message indexes", zap.Error(err))
+	}
+}
+
+func (r *repository) initReadCursorIndexes() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	indexes := []mongo.IndexModel{
+		{
+			// 每个用户在每个会话中只有一个已读游标
+			Keys: bson.D{
+				{Key: "userUUID", Value: 1},
+				{Key: "conversationID", Value: 1},
+			},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			// 统计群消息已读人数
+			Keys: bson.D{
+				{Key: "conversationID", Value: 1},
+				{Key: "lastReadSeq", Value: -1},
+			},
+		},
+	}
+
+	if _, err := r.readColl.Indexes().CreateMany(ctx, indexes); err != nil {
+		log.Logger.Error("Failed to create read cursor indexes", zap.Error(err))
+	}
+}
+
+func (r *repository) initRevisionIndexes() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	indexes := []mongo.IndexModel{
+		{
+			// 按时间顺序查询某条消息的历史版本
+			Keys: bson.D{
+				{Key: "messageID", Value: 1},
+				{Key: "replacedAt", Value: 1},
+			},
+		},
+	}
+
+	if _, err := r.revColl.Indexes().CreateMany(ctx, indexes); err != nil {
+		log.Logger.Error("Failed to create message revision indexes", zap.Error(err))
+	}
+}
+
+func (r *repository) initReactionIndexes() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	indexes := []mongo.IndexModel{
+		{
+			// 同一用户对同一消息的同一表情只能回应一次，同时用于按消息聚合
+			Keys: bson.D{
+				{Key: "messageID", Value: 1},
+				{Key: "emoji", Value: 1},
+				{Key: "userUUID", Value: 1},
+			},
+			Options: options.Index().SetUnique(true),
+		},
+	}
+
+	if _, err := r.reacColl.Indexes().CreateMany(ctx, indexes); err != nil {
+		log.Logger.Error("Failed to create message reaction indexes", zap.Error(err))
+	}
+}
+
+func (r *repository) initScheduledIndexes() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	indexes := []mongo.IndexModel{
+		{
+			// 调度器按状态与计划时间领取到期的定时消息
+			Keys: bson.D{
+				{Key: "status", Value: 1},
+				{Key: "sendAt", Value: 1},
+			},
+		},
+		{
+			// 查询用户待发送的定时消息
+			Keys: bson.D{
+				{Key: "senderUUID", Value: 1},
+				{Key: "status", Value: 1},
+				{Key: "sendAt", Value: 1},
+			},
+		},
+	}
+
+	if _, err := r.schedColl.Indexes().CreateMany(ctx, indexes); err != nil {
+		log.Logger.Error("Failed to create scheduled message indexes", zap.Error(err))
+	}
+}
+
+// CreateMsg 插入消息；消息 ID 或 (senderUUID, clientMsgID) 重复时返回 ErrDuplicateMessage
 func (r *repository) CreateMsg(ctx context.Context, msg *Message) error {
 	_, err := r.msgColl.InsertOne(ctx, msg)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicateMessage
+	}
 	return err
 }
 
-func (r *repository) GetByConversation(ctx context.Context, convID string, limit, offset int) ([]*Message, error) {
+// GetMessageByClientMsgID 根据发送者与客户端消息ID查找已入库的消息
+func (r *repository) GetMessageByClientMsgID(ctx context.Context, senderUUID, clientMsgID string) (*Message, error) {
+	var msg Message
+	err := r.msgColl.FindOne(ctx, bson.M{
+		"senderUUID":  senderUUID,
+		"clientMsgID": clientMsgID,
+	}).Decode(&msg)
+	if err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
 
-	// TODO: 实现分页查询和排序
-	cursor, err := r.msgColl.Find(ctx, map[string]interface{}{
-		"conversationID": convID,
-	})
+// GetByConversation 按游标分页查询会话主时间线的消息
+// Seq 模式只覆盖已分配序号的消息，$gt 下界同时让查询命中 (conversationID, seq) 部分索引；
+// ObjectID 模式覆盖全部消息，走 (conversationID, _id) 索引，需要排除没有序号的话题回复
+func (r *repository) GetByConversation(ctx context.Context, convID string, query HistoryQuery) ([]*Message, error) {
+	filter := excludeExpired(bson.M{"conversationID": convID})
+	sortKey := "seq"
+
+	if query.ByID() {
+		sortKey = "_id"
+		idRange := bson.M{}
+		if query.Before.IsID() {
+			idRange["$lt"] = query.Before.ID
+		}
+		if query.After.IsID() {
+			idRange["$gt"] = query.After.ID
+		}
+		filter["_id"] = idRange
+		filter["metadata.threadRootID"] = bson.M{"$exists": false}
+	} else {
+		seqRange := bson.M{"$gt": query.After.Seq}
+		if query.Before.Seq > 0 {
+			seqRange["$lt"] = query.Before.Seq
+		}
+		filter["seq"] = seqRange
+	}
+
+	direction := -1
+	if query.Ascending {
+		direction = 1
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: sortKey, Value: direction}}).
+		SetLimit(int64(query.Limit))
+
+	cursor, err := r.msgColl.Find(ctx, filter, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -89,9 +357,352 @@ func (r *repository) GetByConversation(ctx context.Context, convID string, limit
 	return messages, nil
 }
 
-func (r *repository) MarkAsRead(ctx context.Context, msgIDs []string) error {
-	// TODO: 实现消息已读标记
-	return nil
+// GetUnsequenced 按 ObjectID 顺序查询会话主时间线上没有序号的历史消息（引入序号之前入库的消息）
+// 这些消息都早于序号最小的消息，只在它之前的 _id 区间内查找，避免扫描整个会话
+func (r *repository) GetUnsequenced(ctx context.Context, convID string, ascending bool, limit int) ([]*Message, error) {
+	filter := excludeExpired(bson.M{
+		"conversationID":        convID,
+		"seq":                   bson.M{"$exists": false},
+		"metadata.threadRootID": bson.M{"$exists": false},
+	})
+
+	var first Message
+	err := r.msgColl.FindOne(ctx,
+		bson.M{"conversationID": convID, "seq": bson.M{"$gt": 0}},
+		options.FindOne().SetSort(bson.D{{Key: "seq", Value: 1}}).SetProjection(bson.M{"_id": 1}),
+	).Decode(&first)
+	if err == nil {
+		filter["_id"] = bson.M{"$lt": first.ID}
+	} else if !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, err
+	}
+
+	direction := -1
+	if ascending {
+		direction = 1
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "_id", Value: direction}}).
+		SetLimit(int64(limit))
+
+	cursor, err := r.msgColl.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []*Message
+	if err := cursor.All(ctx, &messages); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// excludeExpired 在查询条件中排除已过期的消息
+// TTL 索引由 MongoDB 约每 60 秒清理一次，过期后尚未删除的消息不应再返回；没有 expireAt 的消息同样匹配 $not
+func excludeExpired(filter bson.M) bson.M {
+	filter["expireAt"] = bson.M{"$not": bson.M{"$lte": time.Now()}}
+	return filter
+}
+
+// GetMessageByID 根据消息 ID 获取消息
+func (r *repository) GetMessageByID(ctx context.Context, msgID string) (*Message, error) {
+	objID, err := primitive.ObjectIDFromHex(msgID)
+	if err != nil {
+		return nil, err
+	}
+
+	var msg Message
+	if err := r.msgColl.FindOne(ctx, bson.M{"_id": objID}).Decode(&msg); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+// RecallMessage 将消息标记为已撤回并清空消息体
+// 消息已被撤回时返回 false，保证并发撤回只有一次生效
+func (r *repository) RecallMessage(ctx context.Context, msgID primitive.ObjectID, operatorUUID string, recalledAt int64) (bool, error) {
+	filter := bson.M{"_id": msgID, "recalled": bson.M{"$ne": true}}
+	update := bson.M{
+		"$set": bson.M{
+			"recalled":   true,
+			"recalledAt": recalledAt,
+			"recalledBy": operatorUUID,
+		},
+		"$unset": bson.M{"body": ""},
+	}
+	result, err := r.msgColl.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// EditMessage 替换文本消息的内容并将旧版本写入 message_revisions，返回编辑前的消息
+// 只匹配发送者本人未撤回的文本消息，找不到时返回 mongo.ErrNoDocuments
+func (r *repository) EditMessage(ctx context.Context, msgID primitive.ObjectID, senderUUID string, body any, editedAt int64) (*Message, error) {
+	filter := bson.M{
+		"_id":         msgID,
+		"senderUUID":  senderUUID,
+		"contentType": int16(1),
+		"recalled":    bson.M{"$ne": true},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"body":     body,
+			"edited":   true,
+			"editedAt": editedAt,
+		},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.Before)
+
+	var prev Message
+	if err := r.msgColl.FindOneAndUpdate(ctx, filter, update, opts).Decode(&prev); err != nil {
+		return nil, err
+	}
+
+	createdAt := prev.SendAt
+	if prev.Edited {
+		createdAt = prev.EditedAt
+	}
+	revision := &MessageRevision{
+		MessageID:      msgID.Hex(),
+		ConversationID: prev.ConversationID,
+		Body:           prev.Body,
+		CreatedAt:      createdAt,
+		ReplacedAt:     editedAt,
+	}
+	if _, err := r.revColl.InsertOne(ctx, revision); err != nil {
+		// 消息已经更新，历史版本写入失败只记录日志
+		log.Logger.Error("Failed to save message revision", zap.String("messageID", msgID.Hex()), zap.Error(err))
+	}
+	return &prev, nil
+}
+
+// GetRevisions 按时间顺序获取消息的历史版本
+func (r *repository) GetRevisions(ctx context.Context, msgID string) ([]*MessageRevision, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "replacedAt", Value: 1}})
+	cursor, err := r.revColl.Find(ctx, bson.M{"messageID": msgID}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var revisions []*MessageRevision
+	if err := cursor.All(ctx, &revisions); err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+// GetMentions 按时间倒序查询 @ 了用户的消息（包括所在会话中的 @所有人），不含已撤回的消息和用户自己发的消息
+// before 非零时只返回早于该消息的记录
+func (r *repository) GetMentions(ctx context.Context, userUUID string, convIDs []string, before primitive.ObjectID, limit int) ([]*Message, error) {
+	filter := bson.M{
+		"$or": []bson.M{
+			{"metadata.mentionUUIDs": userUUID},
+			{"conversationID": bson.M{"$in": convIDs}, "metadata.mentionAll": true},
+		},
+		"senderUUID": bson.M{"$ne": userUUID},
+		"recalled":   bson.M{"$ne": true},
+	}
+	excludeExpired(filter)
+	if !before.IsZero() {
+		filter["_id"] = bson.M{"$lt": before}
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "_id", Value: -1}}).
+		SetLimit(int64(limit))
+
+	cursor, err := r.msgColl.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []*Message
+	if err := cursor.All(ctx, &messages); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// GetThreadReplies 按时间倒序查询话题回复，before 非零时只返回早于该消息的记录
+func (r *repository) GetThreadReplies(ctx context.Context, rootID string, before primitive.ObjectID, limit int) ([]*Message, error) {
+	filter := excludeExpired(bson.M{"metadata.threadRootID": rootID})
+	if !before.IsZero() {
+		filter["_id"] = bson.M{"$lt": before}
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "_id", Value: -1}}).
+		SetLimit(int64(limit))
+
+	cursor, err := r.msgColl.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []*Message
+	if err := cursor.All(ctx, &messages); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// UpdateThreadSummary 话题回复入库后更新根消息的回复数、最后回复时间和参与者，返回更新后的根消息
+func (r *repository) UpdateThreadSummary(ctx context.Context, rootID primitive.ObjectID, repliedAt int64, participants []string) (*Message, error) {
+	update := bson.M{
+		"$inc":      bson.M{"threadReplyCount": 1},
+		"$max":      bson.M{"threadLastReplyAt": repliedAt},
+		"$addToSet": bson.M{"threadParticipants": bson.M{"$each": participants}},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var root Message
+	if err := r.msgColl.FindOneAndUpdate(ctx, bson.M{"_id": rootID}, update, opts).Decode(&root); err != nil {
+		return nil, err
+	}
+	return &root, nil
+}
+
+// GetMessagesByIDs 批量获取消息，无效或不存在的 ID 会被忽略
+func (r *repository) GetMessagesByIDs(ctx context.Context, msgIDs []string) ([]*Message, error) {
+	objIDs := make([]primitive.ObjectID, 0, len(msgIDs))
+	for _, id := range msgIDs {
+		if objID, err := primitive.ObjectIDFromHex(id); err == nil {
+			objIDs = append(objIDs, objID)
+		}
+	}
+	if len(objIDs) == 0 {
+		return nil, nil
+	}
+
+	cursor, err := r.msgColl.Find(ctx, excludeExpired(bson.M{"_id": bson.M{"$in": objIDs}}))
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []*Message
+	if err := cursor.All(ctx, &messages); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// GetSendersInRange 统计 (fromSeq, toSeq] 区间内的消息发送者，返回 发送者UUID -> 其在区间内最大的消息序号
+func (r *repository) GetSendersInRange(ctx context.Context, convID string, fromSeq, toSeq int64) (map[string]int64, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"conversationID": convID,
+			"seq":            bson.M{"$gt": fromSeq, "$lte": toSeq},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":    "$senderUUID",
+			"maxSeq": bson.M{"$max": "$seq"},
+		}}},
+	}
+
+	cursor, err := r.msgColl.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	senders := make(map[string]int64)
+	for cursor.Next(ctx) {
+		var row struct {
+			SenderUUID string `bson:"_id"`
+			MaxSeq     int64  `bson:"maxSeq"`
+		}
+		if err := cursor.Decode(&row); err != nil {
+			continue
+		}
+		senders[row.SenderUUID] = row.MaxSeq
+	}
+	return senders, cursor.Err()
+}
+
+// AdvanceReadCursor 将用户在会话中的已读游标推进到 seq（只进不退）
+// 返回推进前的序号以及本次是否真正推进：游标已经不小于 seq 时 advanced 为 false
+func (r *repository) AdvanceReadCursor(ctx context.Context, userUUID, convID string, seq int64, msgID string) (int64, bool, error) {
+	// 只匹配落后于 seq 的游标；游标不存在时 Upsert 新建。
+	// 若游标已经不小于 seq，Upsert 会与唯一索引冲突，说明无需推进
+	filter := bson.M{
+		"userUUID":       userUUID,
+		"conversationID": convID,
+		"lastReadSeq":    bson.M{"$lt": seq},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"lastReadSeq":   seq,
+			"lastReadMsgID": msgID,
+			"updatedAt":     time.Now(),
+		},
+	}
+	opts := options.FindOneAndUpdate().
+		SetUpsert(true).
+		SetReturnDocument(options.Before)
+
+	var err error
+	// 两个请求同时首次创建游标时可能出现一次唯一索引冲突，重试一次即可区分
+	for attempt := 0; attempt < 2; attempt++ {
+		var prev ReadCursor
+		err = r.readColl.FindOneAndUpdate(ctx, filter, update, opts).Decode(&prev)
+		if err == nil {
+			return prev.LastReadSeq, true, nil
+		}
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			// 新建的游标，之前没有已读记录
+			return 0, true, nil
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			return 0, false, err
+		}
+	}
+	return 0, false, nil
+}
+
+// GetReadCursors 获取会话中所有成员的已读游标
+func (r *repository) GetReadCursors(ctx context.Context, convID string) ([]*ReadCursor, error) {
+	cursor, err := r.readColl.Find(ctx, bson.M{"conversationID": convID})
+	if err != nil {
+		return nil, err
+	}
+
+	var cursors []*ReadCursor
+	if err := cursor.All(ctx, &cursors); err != nil {
+		return nil, err
+	}
+	return cursors, nil
+}
+
+// GetUserReadSeqs 批量获取用户在多个会话中的已读序号，没有游标的会话不出现在结果中
+func (r *repository) GetUserReadSeqs(ctx context.Context, userUUID string, convIDs []string) (map[string]int64, error) {
+	cursor, err := r.readColl.Find(ctx, bson.M{
+		"userUUID":       userUUID,
+		"conversationID": bson.M{"$in": convIDs},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var cursors []*ReadCursor
+	if err := cursor.All(ctx, &cursors); err != nil {
+		return nil, err
+	}
+
+	seqs := make(map[string]int64, len(cursors))
+	for _, c := range cursors {
+		seqs[c.ConversationID] = c.LastReadSeq
+	}
+	return seqs, nil
+}
+
+// CountReaders 统计会话中已读到 seq 的成员数
+func (r *repository) CountReaders(ctx context.Context, convID string, seq int64) (int64, error) {
+	return r.readColl.CountDocuments(ctx, bson.M{
+		"conversationID": convID,
+		"lastReadSeq":    bson.M{"$gte": seq},
+	})
 }
 
 func (r *repository) GetConversationsByUserID(ctx context.Context, userID string, limit int64) ([]*Conversation, error) {
@@ -116,6 +727,25 @@ func (r *repository) GetConversationsByUserID(ctx context.Context, userID string
 	return conversations, nil
 }
 
+// AllocateSeq 为会话原子地分配下一个消息序号
+// 计数器存放在会话文档的 maxSeq 字段上，$inc + Upsert 保证多实例并发下依然严格递增，
+// 且不会像 Redis 计数器那样因为淘汰策略被清空
+func (r *repository) AllocateSeq(ctx context.Context, conversationID string) (int64, error) {
+	update := bson.M{
+		"$inc": bson.M{"maxSeq": int64(1)},
+	}
+	opts := options.FindOneAndUpdate().
+		SetUpsert(true).
+		SetReturnDocument(options.After).
+		SetProjection(bson.M{"maxSeq": 1})
+
+	var conv Conversation
+	if err := r.convColl.FindOneAndUpdate(ctx, bson.M{"_id": conversationID}, update, opts).Decode(&conv); err != nil {
+		return 0, err
+	}
+	return conv.MaxSeq, nil
+}
+
 // UpdateLastMessage 更新会话的最后一条消息
 func (r *repository) UpdateLastMessage(ctx context.Context, conversationID string, message *Message) error {
 	// 这里的 conversationID 已经是 string 类型 (因为是确定性 ID)
@@ -125,7 +755,8 @@ func (r *repository) UpdateLastMessage(ctx context.Context, conversationID strin
 			"lastMessage":          message.Body,
 			"lastMessageTimestamp": message.SendAt,
 			"updatedAt":            time.Now(),
-			"type":                 message.ContentType,
+			"lastMessageID":        message.ID.Hex(),
+			"lastMessageType":      message.ContentType,
 		},
 	}
 
@@ -138,6 +769,104 @@ func (r *repository) UpdateLastMessage(ctx context.Context, conversationID strin
 	return err
 }
 
+// ReplaceLastMessage 消息仍是会话的最后一条消息时替换 LastMessage 的内容（撤回占位、编辑后的文本）
+func (r *repository) ReplaceLastMessage(ctx context.Context, conversationID, msgID string, body any) error {
+	filter := bson.M{"_id": conversationID, "lastMessageID": msgID}
+	update := bson.M{
+		"$set": bson.M{
+			"lastMessage":     body,
+			"lastMessageType": int16(1),
+			"updatedAt":       time.Now(),
+		},
+	}
+	_, err := r.convColl.UpdateOne(ctx, filter, update)
+	return err
+}
+
+// PinMessage 将消息加入会话的置顶列表
+// 已经置顶时返回 false；置顶数已达上限时返回 ErrPinLimitReached
+func (r *repository) PinMessage(ctx context.Context, conversationID string, pin *Pin, maxPins int) (bool, error) {
+	// 去重与上限在同一条更新中判断，并发置顶也不会超出上限
+	filter := bson.M{
+		"_id":                             conversationID,
+		"pins.messageID":                  bson.M{"$ne": pin.MessageID},
+		fmt.Sprintf("pins.%d", maxPins-1): bson.M{"$exists": false},
+	}
+	update := bson.M{"$push": bson.M{"pins": pin}}
+
+	result, err := r.convColl.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	if result.ModifiedCount > 0 {
+		return true, nil
+	}
+
+	pins, err := r.GetPins(ctx, conversationID)
+	if err != nil {
+		return false, err
+	}
+	for _, p := range pins {
+		if p.MessageID == pin.MessageID {
+			return false, nil
+		}
+	}
+	return false, ErrPinLimitReached
+}
+
+// UnpinMessage 将消息移出会话的置顶列表，消息未置顶时返回 false
+func (r *repository) UnpinMessage(ctx context.Context, conversationID, msgID string) (bool, error) {
+	update := bson.M{"$pull": bson.M{"pins": bson.M{"messageID": msgID}}}
+	result, err := r.convColl.UpdateByID(ctx, conversationID, update)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// GetPins 获取会话的置顶列表
+func (r *repository) GetPins(ctx context.Context, conversationID string) ([]*Pin, error) {
+	opts := options.FindOne().SetProjection(bson.M{"pins": 1})
+
+	var conv Conversation
+	if err := r.convColl.FindOne(ctx, bson.M{"_id": conversationID}, opts).Decode(&conv); err != nil {
+		return nil, err
+	}
+	return conv.Pins, nil
+}
+
+// GetMessageTTL 获取会话的消息自动删除时长（秒），会话文档不存在时视为关闭
+func (r *repository) GetMessageTTL(ctx context.Context, conversationID string) (int64, error) {
+	opts := options.FindOne().SetProjection(bson.M{"messageTTL": 1})
+
+	var conv Conversation
+	err := r.convColl.FindOne(ctx, bson.M{"_id": conversationID}, opts).Decode(&conv)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return conv.MessageTTL, nil
+}
+
+// SetMessageTTL 修改会话的消息自动删除时长，ttl 为 0 表示关闭；设置没有变化时返回 false
+// 会话还没有消息时文档可能不存在，开启时与 AllocateSeq 一样 Upsert
+func (r *repository) SetMessageTTL(ctx context.Context, conversationID string, ttl int64) (bool, error) {
+	update := bson.M{"$set": bson.M{"messageTTL": ttl}}
+	opts := options.Update().SetUpsert(true)
+	if ttl == 0 {
+		update = bson.M{"$unset": bson.M{"messageTTL": ""}}
+		opts.SetUpsert(false)
+	}
+
+	result, err := r.convColl.UpdateByID(ctx, conversationID, update, opts)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0 || result.UpsertedCount > 0, nil
+}
+
 // CreateConversation 创建新会话
 func (r *repository) CreateConversation(ctx context.Context, conv *Conversation) error {
 	_, err := r.convColl.InsertOne(ctx, conv)
@@ -182,3 +911,244 @@ func (r *repository) GetConversationByID(ctx context.Context, conversationID str
 	}
 	return &conv, nil
 }
+
+// CountUnread 批量统计会话中序号大于已读序号的消息数，readSeqs 为 会话ID -> 已读序号
+// 只统计实际入库且未过期的主时间线消息，没有未读消息的会话不出现在结果中
+func (r *repository) CountUnread(ctx context.Context, readSeqs map[string]int64) (map[string]UnreadCount, error) {
+	counts := make(map[string]UnreadCount, len(readSeqs))
+	if len(readSeqs) == 0 {
+		return counts, nil
+	}
+
+	ranges := make(bson.A, 0, len(readSeqs))
+	for convID, seq := range readSeqs {
+		ranges = append(ranges, bson.M{"conversationID": convID, "seq": bson.M{"$gt": seq}})
+	}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: excludeExpired(bson.M{"$or": ranges})}},
+		{{Key: "$group", Value: bson.M{
+			"_id":    "$conversationID",
+			"count":  bson.M{"$sum": 1},
+			"maxSeq": bson.M{"$max": "$seq"},
+		}}},
+	}
+
+	cursor, err := r.msgColl.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var row struct {
+			ConversationID string `bson:"_id"`
+			Count          int64  `bson:"count"`
+			MaxSeq         int64  `bson:"maxSeq"`
+		}
+		if err := cursor.Decode(&row); err != nil {
+			continue
+		}
+		counts[row.ConversationID] = UnreadCount{Count: row.Count, MaxSeq: row.MaxSeq}
+	}
+	return counts, cursor.Err()
+}
+
+// GetConversationsByIDs 批量获取会话
+func (r *repository) GetConversationsByIDs(ctx context.Context, convIDs []string) ([]*Conversation, error) {
+	cursor, err := r.convColl.Find(ctx, bson.M{"_id": bson.M{"$in": convIDs}})
+	if err != nil {
+		return nil, err
+	}
+
+	var conversations []*Conversation
+	if err := cursor.All(ctx, &conversations); err != nil {
+		return nil, err
+	}
+	return conversations, nil
+}
+
+// AddReaction 添加表情回应，已经回应过时返回 false
+func (r *repository) AddReaction(ctx context.Context, reaction *Reaction) (bool, error) {
+	_, err := r.reacColl.InsertOne(ctx, reaction)
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// RemoveReaction 取消表情回应，没有回应过时返回 false
+func (r *repository) RemoveReaction(ctx context.Context, msgID, userUUID, emoji string) (bool, error) {
+	result, err := r.reacColl.DeleteOne(ctx, bson.M{
+		"messageID": msgID,
+		"emoji":     emoji,
+		"userUUID":  userUUID,
+	})
+	if err != nil {
+		return false, err
+	}
+	return result.DeletedCount > 0, nil
+}
+
+// CountReactions 统计消息上某个表情的回应人数
+func (r *repository) CountReactions(ctx context.Context, msgID, emoji string) (int64, error) {
+	return r.reacColl.CountDocuments(ctx, bson.M{"messageID": msgID, "emoji": emoji})
+}
+
+// GetReactionSummaries 批量聚合消息的表情回应，返回 消息ID -> 各表情的回应人数及 userUUID 是否回应过
+// 同一消息的表情按首次被回应的时间排序
+func (r *repository) GetReactionSummaries(ctx context.Context, msgIDs []string, userUUID string) (map[string][]*ReactionSummary, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"messageID": bson.M{"$in": msgIDs}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":     bson.M{"messageID": "$messageID", "emoji": "$emoji"},
+			"count":   bson.M{"$sum": 1},
+			"firstAt": bson.M{"$min": "$createdAt"},
+			"reactedByMe": bson.M{"$max": bson.M{
+				"$eq": bson.A{"$userUUID", userUUID},
+			}},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "firstAt", Value: 1}}}},
+	}
+
+	cursor, err := r.reacColl.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	summaries := make(map[string][]*ReactionSummary)
+	for cursor.Next(ctx) {
+		var row struct {
+			Key struct {
+				MessageID string `bson:"messageID"`
+				Emoji     string `bson:"emoji"`
+			} `bson:"_id"`
+			Count       int64 `bson:"count"`
+			ReactedByMe bool  `bson:"reactedByMe"`
+		}
+		if err := cursor.Decode(&row); err != nil {
+			continue
+		}
+		summaries[row.Key.MessageID] = append(summaries[row.Key.MessageID], &ReactionSummary{
+			Emoji:       row.Key.Emoji,
+			Count:       row.Count,
+			ReactedByMe: row.ReactedByMe,
+		})
+	}
+	return summaries, cursor.Err()
+}
+
+// CreateScheduled 保存定时消息
+func (r *repository) CreateScheduled(ctx context.Context, sm *ScheduledMessage) error {
+	_, err := r.schedColl.InsertOne(ctx, sm)
+	return err
+}
+
+// GetScheduled 根据 ID 获取定时消息
+func (r *repository) GetScheduled(ctx context.Context, id string) (*ScheduledMessage, error) {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var sm ScheduledMessage
+	if err := r.schedColl.FindOne(ctx, bson.M{"_id": objID}).Decode(&sm); err != nil {
+		return nil, err
+	}
+	return &sm, nil
+}
+
+// ListPendingScheduled 按计划时间先后获取用户待发送的定时消息
+func (r *repository) ListPendingScheduled(ctx context.Context, senderUUID string) ([]*ScheduledMessage, error) {
+	filter := bson.M{"senderUUID": senderUUID, "status": SchedulePending}
+	opts := options.Find().SetSort(bson.D{{Key: "sendAt", Value: 1}})
+
+	cursor, err := r.schedColl.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []*ScheduledMessage
+	if err := cursor.All(ctx, &messages); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// UpdatePendingScheduled 修改仍在等待发送的定时消息的内容与计划时间，已被领取、发送或取消时返回 false
+func (r *repository) UpdatePendingScheduled(ctx context.Context, sm *ScheduledMessage) (bool, error) {
+	filter := bson.M{"_id": sm.ID, "senderUUID": sm.SenderUUID, "status": SchedulePending}
+	update := bson.M{
+		"$set": bson.M{
+			"contentType": sm.ContentType,
+			"body":        sm.Body,
+			"sendAt":      sm.SendAt,
+			"updatedAt":   sm.UpdatedAt,
+		},
+	}
+	result, err := r.schedColl.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// CancelScheduled 取消仍在等待发送的定时消息，已被领取、发送或取消时返回 false
+func (r *repository) CancelScheduled(ctx context.Context, id primitive.ObjectID, senderUUID string) (bool, error) {
+	filter := bson.M{"_id": id, "senderUUID": senderUUID, "status": SchedulePending}
+	update := bson.M{
+		"$set": bson.M{
+			"status":    ScheduleCanceled,
+			"updatedAt": time.Now().Unix(),
+		},
+	}
+	result, err := r.schedColl.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// ClaimDueScheduled 原子地领取一条到期的定时消息，没有到期消息时返回 nil
+// 领取后超过 staleBefore 仍未完成的消息（领取它的实例可能已崩溃）可以被重新领取
+func (r *repository) ClaimDueScheduled(ctx context.Context, now, staleBefore int64) (*ScheduledMessage, error) {
+	filter := bson.M{
+		"$or": []bson.M{
+			{"status": SchedulePending, "sendAt": bson.M{"$lte": now}},
+			{"status": ScheduleSending, "claimedAt": bson.M{"$lt": staleBefore}},
+		},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"status":    ScheduleSending,
+			"claimedAt": now,
+			"updatedAt": now,
+		},
+	}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "sendAt", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var sm ScheduledMessage
+	err := r.schedColl.FindOneAndUpdate(ctx, filter, update, opts).Decode(&sm)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &sm, nil
+}
+
+// FinishScheduled 记录定时消息的投递结果
+func (r *repository) FinishScheduled(ctx context.Context, id primitive.ObjectID, status int, reason string) error {
+	set := bson.M{
+		"status":    status,
+		"updatedAt": time.Now().Unix(),
+	}
+	if reason != "" {
+		set["error"] = reason
+	}
+	_, err := r.schedColl.UpdateByID(ctx, id, bson.M{"$set": set})
+	return err
+}
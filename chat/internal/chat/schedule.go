@@ -0,0 +1,221 @@
+package chat
+
+import (
+	"MyGoChat/pkg/common/request"
+	"MyGoChat/pkg/log"
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	scheduleInterval = 5 * time.Second     // 调度器检查到期消息的间隔
+	scheduleClaimTTL = time.Minute         // 领取后超过该时间仍未完成，允许其他实例重新领取
+	maxScheduleAhead = 30 * 24 * time.Hour // 最多提前多久定时
+)
+
+var (
+	ErrScheduleNotFound   = errors.New("scheduled message not found")
+	ErrScheduleNotPending = errors.New("scheduled message is no longer pending")
+	ErrInvalidSendTime    = errors.New("send time must be in the future and within 30 days")
+)
+
+// checkSendTime 计划发送时间必须晚于当前时间，且不能超过 maxScheduleAhead
+func checkSendTime(sendAt int64, now time.Time) error {
+	t := time.Unix(sendAt, 0)
+	if !t.After(now) || t.Sub(now) > maxScheduleAhead {
+		return ErrInvalidSendTime
+	}
+	return nil
+}
+
+// ScheduleMessage 保存定时消息
+// 创建时按普通发送的规则校验请求，并要求发送者是目标会话的成员，尽量保证到期时能够投递
+func (s *Service) ScheduleMessage(ctx context.Context, senderUUID string, req *request.SendMessageRequest, sendAt int64) (*ScheduledMessage, error) {
+	now := time.Now()
+	if err := checkSendTime(sendAt, now); err != nil {
+		return nil, err
+	}
+
+	msg, err := s.buildMessage(ctx, senderUUID, req)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.relRepo.GetRelationByConversation(ctx, senderUUID, msg.ConversationID); err != nil {
+		return nil, ErrNotMember
+	}
+
+	// buildMessage 已经校验过 body 的格式
+	body, _ := req.Body.(map[string]interface{})
+	sm := &ScheduledMessage{
+		ID:             primitive.NewObjectID(),
+		SenderUUID:     senderUUID,
+		ConversationID: msg.ConversationID,
+		TargetName:     req.TargetName,
+		MessageType:    req.MessageType,
+		ContentType:    req.ContentType,
+		Body:           body,
+		ReplyToMsgID:   req.ReplyToMsgID,
+		MentionUUIDs:   msg.GetMetadata().GetMentionUUIDs(),
+		MentionAll:     req.MentionAll,
+		ThreadRootID:   req.ThreadRootID,
+		SendAt:         sendAt,
+		Status:         SchedulePending,
+		CreatedAt:      now.Unix(),
+		UpdatedAt:      now.Unix(),
+	}
+	if err := s.repo.CreateScheduled(ctx, sm); err != nil {
+		return nil, err
+	}
+
+	log.Logger.Sugar().Infof("Message scheduled: id=%s, sender=%s, sendAt=%d", sm.ID.Hex(), senderUUID, sendAt)
+	return sm, nil
+}
+
+// ListScheduled 获取用户待发送的定时消息
+func (s *Service) ListScheduled(ctx context.Context, senderUUID string) ([]*ScheduledMessage, error) {
+	return s.repo.ListPendingScheduled(ctx, senderUUID)
+}
+
+// UpdateScheduled 修改待发送定时消息的内容（body 非空时）和计划时间（sendAt 非零时）
+func (s *Service) UpdateScheduled(ctx context.Context, senderUUID, id string, contentType int32, body map[string]interface{}, sendAt int64) (*ScheduledMessage, error) {
+	sm, err := s.loadScheduled(ctx, senderUUID, id)
+	if err != nil {
+		return nil, err
+	}
+	if sm.Status != SchedulePending {
+		return nil, ErrScheduleNotPending
+	}
+
+	if body != nil {
+		if contentType != 0 {
+			sm.ContentType = contentType
+		}
+		sm.Body = body
+		if _, err := s.packProtoBody(sm.ContentType, sm.Body); err != nil {
+			return nil, err
+		}
+	}
+	if sendAt != 0 {
+		if err := checkSendTime(sendAt, time.Now()); err != nil {
+			return nil, err
+		}
+		sm.SendAt = sendAt
+	}
+	sm.UpdatedAt = time.Now().Unix()
+
+	updated, err := s.repo.UpdatePendingScheduled(ctx, sm)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, ErrScheduleNotPending
+	}
+	return sm, nil
+}
+
+// CancelScheduled 取消待发送的定时消息
+func (s *Service) CancelScheduled(ctx context.Context, senderUUID, id string) error {
+	sm, err := s.loadScheduled(ctx, senderUUID, id)
+	if err != nil {
+		return err
+	}
+
+	canceled, err := s.repo.CancelScheduled(ctx, sm.ID, senderUUID)
+	if err != nil {
+		return err
+	}
+	if !canceled {
+		return ErrScheduleNotPending
+	}
+	return nil
+}
+
+// loadScheduled 获取属于发送者的定时消息
+func (s *Service) loadScheduled(ctx context.Context, senderUUID, id string) (*ScheduledMessage, error) {
+	sm, err := s.repo.GetScheduled(ctx, id)
+	if err != nil || sm.SenderUUID != senderUUID {
+		return nil, ErrScheduleNotFound
+	}
+	return sm, nil
+}
+
+// RunScheduler 定期领取到期的定时消息并投递到 Ingest Topic，直到 ctx 取消
+// 每条定时消息通过原子领取保证只被一个实例投递；领取后实例崩溃的消息在 scheduleClaimTTL 后会被重新领取，
+// 由于消息 ID 与定时消息 ID 相同，重复投递会被 ProcessMessage 的唯一索引拦截
+func (s *Service) RunScheduler(ctx context.Context) {
+	ticker := time.NewTicker(scheduleInterval)
+	defer ticker.Stop()
+
+	for {
+		s.dispatchDueScheduled(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// dispatchDueScheduled 逐条领取并投递所有到期的定时消息
+func (s *Service) dispatchDueScheduled(ctx context.Context) {
+	for ctx.Err() == nil {
+		now := time.Now()
+		sm, err := s.repo.ClaimDueScheduled(ctx, now.Unix(), now.Add(-scheduleClaimTTL).Unix())
+		if err != nil {
+			log.Logger.Sugar().Errorf("Failed to claim scheduled messages: %v", err)
+			return
+		}
+		if sm == nil {
+			return
+		}
+		s.dispatchScheduled(ctx, sm)
+	}
+}
+
+// dispatchScheduled 按发送时的状态重新校验定时消息并投递
+func (s *Service) dispatchScheduled(ctx context.Context, sm *ScheduledMessage) {
+	msg, err := s.buildMessage(ctx, sm.SenderUUID, sm.toRequest())
+	if err == nil {
+		if _, relErr := s.relRepo.GetRelationByConversation(ctx, sm.SenderUUID, msg.ConversationID); relErr != nil {
+			err = ErrNotMember
+		}
+	}
+	if err != nil {
+		log.Logger.Sugar().Warnf("Scheduled message %s can no longer be sent: %v", sm.ID.Hex(), err)
+		if err := s.repo.FinishScheduled(ctx, sm.ID, ScheduleFailed, err.Error()); err != nil {
+			log.Logger.Sugar().Errorf("Failed to mark scheduled message %s as failed: %v", sm.ID.Hex(), err)
+		}
+		return
+	}
+
+	msg.Id = sm.ID.Hex()
+	if err := s.EnqueueMessage(ctx, msg); err != nil {
+		// 保持领取状态，领取超时后重试
+		log.Logger.Sugar().Errorf("Failed to enqueue scheduled message %s: %v", sm.ID.Hex(), err)
+		return
+	}
+
+	if err := s.repo.FinishScheduled(ctx, sm.ID, ScheduleSent, ""); err != nil {
+		log.Logger.Sugar().Errorf("Failed to mark scheduled message %s as sent: %v", sm.ID.Hex(), err)
+	}
+	log.Logger.Sugar().Infof("Scheduled message sent: id=%s, conversation=%s", msg.Id, msg.ConversationID)
+}
+
+// toRequest 还原为发送消息请求
+func (sm *ScheduledMessage) toRequest() *request.SendMessageRequest {
+	return &request.SendMessageRequest{
+		ConversationID: sm.ConversationID,
+		TargetName:     sm.TargetName,
+		ContentType:    sm.ContentType,
+		Body:           sm.Body,
+		MessageType:    sm.MessageType,
+		ReplyToMsgID:   sm.ReplyToMsgID,
+		MentionUUIDs:   sm.MentionUUIDs,
+		MentionAll:     sm.MentionAll,
+		ThreadRootID:   sm.ThreadRootID,
+	}
+}
@@ -0,0 +1,40 @@
+package chat
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TestCheckSendTime 测试计划发送时间必须在未来且不超过上限
+func TestCheckSendTime(t *testing.T) {
+	now := time.Now()
+
+	assert.NoError(t, checkSendTime(now.Add(time.Hour).Unix(), now))
+	assert.ErrorIs(t, checkSendTime(now.Add(-time.Minute).Unix(), now), ErrInvalidSendTime)
+	assert.ErrorIs(t, checkSendTime(now.Add(maxScheduleAhead+time.Hour).Unix(), now), ErrInvalidSendTime)
+}
+
+// TestCancelScheduled 测试只能取消自己的、仍在等待发送的定时消息
+func TestCancelScheduled(t *testing.T) {
+	ctx := context.Background()
+	id := primitive.NewObjectID()
+
+	mockRepo := new(MockRepository)
+	mockRepo.On("GetScheduled", mock.Anything, id.Hex()).
+		Return(&ScheduledMessage{ID: id, SenderUUID: "alice", Status: SchedulePending}, nil)
+	service := &Service{repo: mockRepo}
+
+	assert.ErrorIs(t, service.CancelScheduled(ctx, "bob", id.Hex()), ErrScheduleNotFound)
+
+	// 调度器已经领取或已取消时，条件更新不会命中
+	mockRepo.On("CancelScheduled", mock.Anything, id, "alice").Return(false, nil).Once()
+	assert.ErrorIs(t, service.CancelScheduled(ctx, "alice", id.Hex()), ErrScheduleNotPending)
+
+	mockRepo.On("CancelScheduled", mock.Anything, id, "alice").Return(true, nil).Once()
+	assert.NoError(t, service.CancelScheduled(ctx, "alice", id.Hex()))
+}
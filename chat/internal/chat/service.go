@@ -1,15 +1,16 @@
 package chat
 
 import (
-	pb "MyGoChat/pkg/api/v1"
 	"MyGoChat/chat/internal/group"
 	"MyGoChat/chat/internal/relation"
 	"MyGoChat/chat/internal/user"
 	"MyGoChat/chat/internal/util"
+	pb "MyGoChat/pkg/api/v1"
 	"MyGoChat/pkg/common/request"
 	"MyGoChat/pkg/config"
 	myKafka "MyGoChat/pkg/kafka"
 	"MyGoChat/pkg/log"
+	"MyGoChat/pkg/route"
 	"context"
 	"encoding/json"
 	"errors"
@@ -29,7 +30,7 @@ type Service struct {
 	groupRepo group.Repository
 	userRepo  user.Repository
 	redis     *redis.Client
-	producer  *myKafka.Producer
+	producer  myKafka.MessageProducer
 }
 
 // ConversationCreatorAdapter 适配器，实现 relation.ConversationCreator 接口
@@ -59,7 +60,7 @@ func NewService(
 	groupRepo group.Repository, // 【新增】
 	userRepo user.Repository,
 	rdb *redis.Client,
-	producer *myKafka.Producer,
+	producer myKafka.MessageProducer,
 ) *Service {
 	return &Service{
 		repo:      repo,
@@ -72,7 +73,7 @@ func NewService(
 }
 
 // GetProducer 返回 Kafka Producer 实例（供 Handler 使用）
-func (s *Service) GetProducer() *myKafka.Producer {
+func (s *Service) GetProducer() myKafka.MessageProducer {
 	return s.producer
 }
 
@@ -90,34 +91,27 @@ func (s *Service) EnqueueMessage(ctx context.Context, msg *pb.Message) error {
 // SendMessage 是发送消息的唯一入口
 // 它负责：1. 号码转UUID  2. 生成确定性会话ID  3. 构造消息  4. 发送Kafka
 func (s *Service) SendMessage(ctx context.Context, senderUUID string, req *request.SendMessageRequest) (*pb.Message, error) {
-	var (
-		targetUUID     string
-		conversationID string
-		err            error
-	)
+	msg, err := s.buildMessage(ctx, senderUUID, req)
+	if err != nil {
+		return nil, err
+	}
 
-	// 1. 核心逻辑：号码转 UUID + 计算会话 ID
-	if req.MessageType == 1 {
-		// --- 私聊 ---
-		// 前端传的是 Username，我们需要 UserUUID
-		targetUUID, err = s.userRepo.GetUUIDByUsername(ctx, req.TargetName)
-		if err != nil {
-			return nil, errors.New("用户不存在")
-		}
-		// 算法生成确定性 ID: Hash(Sort(A, B))
-		conversationID = util.GetPrivateConversationID(senderUUID, targetUUID)
+	// 5. 发送到 Kafka (Ingest Topic)
+	// 消费者收到后会负责：存 Mongo(Upsert) + 推送给用户
+	if err := s.EnqueueMessage(ctx, msg); err != nil {
+		return nil, err
+	}
 
-	} else if req.MessageType == 2 {
-		// --- 群聊 ---
-		// 前端传的是 GroupNumber，我们需要 GroupUUID
-		targetUUID, err = s.groupRepo.GetUUIDByNumber(ctx, req.TargetName)
-		if err != nil {
-			return nil, errors.New("群组不存在")
-		}
-		// 确定性 ID: GroupUUID 本身就是 会话ID
-		conversationID = targetUUID
-	} else {
-		return nil, errors.New("不支持的消息类型")
+	// 5. 返回结果
+	return msg, nil
+}
+
+// buildMessage 校验发送请求并构造待投递的消息，HTTP 发送与定时发送共用
+func (s *Service) buildMessage(ctx context.Context, senderUUID string, req *request.SendMessageRequest) (*pb.Message, error) {
+	// 1. 核心逻辑：号码转 UUID + 计算会话 ID
+	targetUUID, conversationID, err := s.resolveTarget(ctx, senderUUID, req.MessageType, req.TargetName)
+	if err != nil {
+		return nil, err
 	}
 
 	// 2. 获取发送者用户名
@@ -136,22 +130,67 @@ func (s *Service) SendMessage(ctx context.Context, senderUUID string, req *reque
 		SendAt:         time.Now().Unix(),
 	}
 
+	// 回复消息：被回复的消息必须属于同一会话
+	if req.ReplyToMsgID != "" {
+		if _, err := s.loadReplyTarget(ctx, conversationID, req.ReplyToMsgID); err != nil {
+			return nil, errors.New("回复的消息不存在")
+		}
+	}
+	// 话题回复：根消息必须是同一群聊中的消息
+	if req.ThreadRootID != "" {
+		if _, err := s.loadThreadRoot(ctx, req.MessageType, conversationID, req.ThreadRootID); err != nil {
+			return nil, errors.New("话题不存在")
+		}
+	}
+	if req.ReplyToMsgID != "" || len(req.MentionUUIDs) > 0 || req.MentionAll || req.ThreadRootID != "" {
+		msg.Metadata = &pb.MessageMetadata{
+			ReplyToMsgID: req.ReplyToMsgID,
+			MentionUUIDs: req.MentionUUIDs,
+			MentionAll:   req.MentionAll,
+			ThreadRootID: req.ThreadRootID,
+		}
+	}
+	// @ 的成员必须在群内，@所有人 仅群管理员和群主可用
+	if err := s.validateMentions(ctx, msg); err != nil {
+		return nil, err
+	}
+
 	// 4. 处理消息体 (Any)
 	var errPack error
 	msg.Body, errPack = s.packProtoBody(req.ContentType, req.Body)
 	if errPack != nil {
 		return nil, errPack
 	}
-	// 5. 发送到 Kafka (Ingest Topic)
-	// 消费者收到后会负责：存 Mongo(Upsert) + 推送给用户
-	if err := s.EnqueueMessage(ctx, msg); err != nil {
-		return nil, err
-	}
-
-	// 5. 返回结果
 	return msg, nil
 }
 
+// resolveTarget 将前端传入的用户名/群号转换为接收者 UUID，并计算确定性的会话ID
+func (s *Service) resolveTarget(ctx context.Context, senderUUID string, messageType int32, targetName string) (targetUUID, conversationID string, err error) {
+	if messageType == 1 {
+		// --- 私聊 ---
+		// 前端传的是 Username，我们需要 UserUUID
+		targetUUID, err = s.userRepo.GetUUIDByUsername(ctx, targetName)
+		if err != nil {
+			return "", "", errors.New("用户不存在")
+		}
+		// 算法生成确定性 ID: Hash(Sort(A, B))
+		conversationID = util.GetPrivateConversationID(senderUUID, targetUUID)
+
+	} else if messageType == 2 {
+		// --- 群聊 ---
+		// 前端传的是 GroupNumber，我们需要 GroupUUID
+		targetUUID, err = s.groupRepo.GetUUIDByNumber(ctx, targetName)
+		if err != nil {
+			return "", "", errors.New("群组不存在")
+		}
+		// 确定性 ID: GroupUUID 本身就是 会话ID
+		conversationID = targetUUID
+	} else {
+		return "", "", errors.New("不支持的消息类型")
+	}
+	return targetUUID, conversationID, nil
+}
+
 // ProcessMessage 是 Logic 服务的核心消息处理方法
 // 该方法由 Kafka Consumer 调用，处理从 Gateway 发送过来的消息
 //
@@ -159,9 +198,11 @@ func (s *Service) SendMessage(ctx context.Context, senderUUID string, req *reque
 // 1. 反序列化：将 Kafka 消息体解析为 Protobuf Message 结构
 // 2. 验证消息：检查必要字段（SenderUUID, ConversationID 等）
 // 3. 解包消息体：将 google.protobuf.Any 解包为具体类型
-// 4. 持久化：将消息存储到 MongoDB
-// 5. 更新会话：更新对应会话的最后消息信息
-// 6. 消息投递：
+// 4. 分配序号：为消息分配会话内单调递增的 Seq
+// 5. 持久化：将消息存储到 MongoDB
+// 6. 确认：向发送端推送 ACK（任一步骤失败时推送 NACK）
+// 7. 更新会话：更新对应会话的最后消息信息
+// 8. 消息投递：
 //   - 在线用户：查询 Redis 路由表，投递到对应 Gateway 的 Delivery Topic
 //   - 离线用户：存储到 Redis 离线消息队列，等待用户上线时同步
 func (s *Service) ProcessMessage(ctx context.Context, kafkaMsg kafka.Message) error {
@@ -187,6 +228,52 @@ func (s *Service) ProcessMessage(ctx context.Context, kafkaMsg kafka.Message) er
 	// 确保必要字段存在，防止无效消息进入后续处理流程
 	if err := s.validateMessage(&msg); err != nil {
 		log.Logger.Sugar().Errorf("Invalid message: %v", err)
+		s.nackMessage(&msg, err.Error())
+		return err
+	}
+
+	// 幂等检查：重复投递的消息直接重新 ACK，避免再次分配序号、入库和扇出
+	if existing := s.findProcessed(ctx, &msg); existing != nil {
+		s.ackDuplicate(ctx, &msg, existing)
+		return nil
+	}
+
+	// 转发来源只能由 ForwardMessages 在服务端生成，客户端经 Gateway 发来的消息都带有 SenderDeviceID；
+	// 被回复消息的摘要总是按入库的被回复消息重新生成
+	if msg.Metadata != nil {
+		if msg.SenderDeviceID != "" {
+			msg.Metadata.ForwardedFrom = nil
+		}
+		msg.Metadata.Reply = nil
+	}
+
+	// 回复消息：被回复的消息必须属于同一会话，推送时附带其摘要
+	if replyToMsgID := msg.GetMetadata().GetReplyToMsgID(); replyToMsgID != "" {
+		replyTo, err := s.loadReplyTarget(ctx, msg.ConversationID, replyToMsgID)
+		if err != nil {
+			log.Logger.Sugar().Warnf("Invalid reply target %s: %v", replyToMsgID, err)
+			s.nackMessage(&msg, "invalid reply target")
+			return err
+		}
+		msg.Metadata.Reply = newReplySnippet(replyTo).toProto()
+	}
+
+	// 话题回复：根消息必须是同一群聊中的主时间线消息
+	var threadRoot *Message
+	if rootID := msg.GetMetadata().GetThreadRootID(); rootID != "" {
+		root, err := s.loadThreadRoot(ctx, msg.MessageType, msg.ConversationID, rootID)
+		if err != nil {
+			log.Logger.Sugar().Warnf("Invalid thread root %s: %v", rootID, err)
+			s.nackMessage(&msg, err.Error())
+			return err
+		}
+		threadRoot = root
+	}
+
+	// @ 的成员必须在群内，@所有人 仅群管理员和群主可用
+	if err := s.validateMentions(ctx, &msg); err != nil {
+		log.Logger.Sugar().Warnf("Invalid mentions from %s: %v", msg.SenderUUID, err)
+		s.nackMessage(&msg, err.Error())
 		return err
 	}
 
@@ -195,37 +282,104 @@ func (s *Service) ProcessMessage(ctx context.Context, kafkaMsg kafka.Message) er
 	body, err := s.unpackProtoBody(msg.ContentType, msg.Body)
 	if err != nil {
 		log.Logger.Sugar().Errorf("Failed to unpack body: %v", err)
+		s.nackMessage(&msg, "invalid message body")
 		return err
 	}
 
-	// Step 5: 构建 MongoDB 数据模型
+	// Step 5: 分配会话内序号
+	// 序号在同一会话内严格递增，客户端据此排序、检测缺口，并作为增量同步的游标
+	// 话题回复不在主时间线中，不分配序号
+	var seq int64
+	if threadRoot == nil {
+		seq, err = s.repo.AllocateSeq(ctx, msg.ConversationID)
+		if err != nil {
+			log.Logger.Sugar().Errorf("Failed to allocate seq for conversation %s: %v", msg.ConversationID, err)
+			s.nackMessage(&msg, "internal error, please retry")
+			return err
+		}
+	}
+
+	// 沿用上游生成的消息 ID（HTTP 发送时已生成），保证推送给客户端的 ID 与入库的一致
+	msgID, err := primitive.ObjectIDFromHex(msg.Id)
+	if err != nil {
+		msgID = primitive.NewObjectID()
+	}
+
+	// Step 6: 构建 MongoDB 数据模型
 	message := &Message{
+		ID:             msgID,
 		ConversationID: msg.ConversationID, // 直接使用字符串
 		SenderUUID:     msg.SenderUUID,
 		SenderName:     msg.SenderName,
 		ContentType:    int16(msg.ContentType),
 		Body:           body,
 		SendAt:         time.Now().Unix(),
+		Seq:            seq,
+		ClientMsgID:    msg.ClientMsgID,
+	}
+	if msg.Metadata != nil {
+		message.Metadata = &MessageMetadata{
+			ReplyToMsgID:  msg.Metadata.ReplyToMsgID,
+			MentionUUIDs:  msg.Metadata.MentionUUIDs,
+			MentionAll:    msg.Metadata.MentionAll,
+			ThreadRootID:  msg.Metadata.ThreadRootID,
+			ForwardedFrom: forwardInfoFromProto(msg.Metadata.ForwardedFrom),
+		}
+	}
+
+	// 会话开启了消息自动删除时记录过期时间，到期后由 TTL 索引删除
+	if ttl := s.messageTTL(ctx, msg.ConversationID); ttl > 0 {
+		expireAt := time.Unix(message.SendAt, 0).Add(ttl)
+		message.ExpireAt = &expireAt
+		msg.ExpireAt = expireAt.Unix()
 	}
 
-	// Step 6: 持久化消息到 MongoDB
+	// 以服务端入库的结果为准回填推送消息
+	msg.Id = msgID.Hex()
+	msg.SendAt = message.SendAt
+	msg.Seq = seq
+
+	// Step 7: 持久化消息到 MongoDB
 	// 消息必须先入库，确保数据不丢失，即使后续投递失败也可以通过离线消息恢复
 	if err := s.repo.CreateMsg(ctx, message); err != nil {
+		// Redis 快速路径漏掉的重复消息由唯一索引拦截
+		// 此时已分配的序号会留下空洞，未读数按实际入库的消息统计，不受影响
+		if errors.Is(err, ErrDuplicateMessage) {
+			if existing := s.resolveDuplicate(ctx, &msg); existing != nil {
+				s.ackDuplicate(ctx, &msg, existing)
+				return nil
+			}
+		}
 		log.Logger.Sugar().Errorf("Failed to save message: %v", err)
+		s.nackMessage(&msg, "internal error, please retry")
 		return err
 	}
 
-	// Step 6: 更新会话的最后消息（用于聊天列表展示）
+	// 消息已落库，记录幂等键并向发送端确认
+	s.markProcessed(ctx, &msg)
+	s.ackMessage(&msg)
+
+	// 话题回复不影响会话的最后消息、已读位置和未读数，只更新根消息的话题摘要
+	if threadRoot != nil {
+		s.deliverThreadReply(ctx, &msg, threadRoot)
+		log.Logger.Sugar().Infof("Thread reply processed successfully: id=%s, root=%s", msg.Id, threadRoot.ID.Hex())
+		return nil
+	}
+
+	// Step 8: 更新会话的最后消息（用于聊天列表展示）
 	if err := s.repo.UpdateLastMessage(ctx, msg.ConversationID, message); err != nil {
 		log.Logger.Sugar().Warnf("Failed to update conversation: %v", err)
 		// 这里不返回错误，因为消息已经存储成功，会话更新失败不影响消息投递
 	}
 
-	// Step 7: 投递消息给目标用户
+	// 发送者自己发出的消息视为已读
+	s.markSenderRead(ctx, &msg)
+
+	// Step 9: 投递消息给目标用户
 	// 根据用户在线状态决定是实时推送还是存储为离线消息
 	s.deliverMessage(ctx, &msg)
 
-	log.Logger.Sugar().Infof("Message processed successfully: id=%s", msg.Id)
+	log.Logger.Sugar().Infof("Message processed successfully: id=%s, seq=%d", msg.Id, msg.Seq)
 	return nil
 }
 
@@ -244,6 +398,11 @@ func (s *Service) validateMessage(msg *pb.Message) error {
 		return errors.New("recipient UUID is required for private chat")
 	}
 
+	// 系统通知只能由服务端生成，客户端经 Gateway 发来的消息都带有 SenderDeviceID
+	if msg.ContentType == ContentTypeSystem && msg.SenderDeviceID != "" {
+		return errors.New("system notices cannot be sent by clients")
+	}
+
 	return nil
 }
 
@@ -251,93 +410,165 @@ func (s *Service) validateMessage(msg *pb.Message) error {
 // 这是消息投递的核心方法，负责实现"消息到达"的最后一公里
 //
 // 投递策略：
-// 1. 私聊 (MessageType=1): 目标用户就是 RecipientUUID
-// 2. 群聊 (MessageType=2): 从关系表查询所有群成员，逐一投递（排除发送者）
+// 1. 私聊 (MessageType=1): 目标用户是 RecipientUUID
+// 2. 群聊 (MessageType=2): 从关系表查询所有群成员，逐一投递
+// 3. 发送者本人：回显到发送者的其他在线设备（跳过发出消息的设备），不计未读、不存离线
+// 4. 被 @ 的成员带有 Mentioned 标记；开启免打扰且未被 @ 的成员带有 Muted 标记，由客户端决定是否提醒
 //
 // 路由机制：
-// - 查询 Redis 键 "user_gateway:{userUUID}" 获取用户当前连接的 Gateway ID
-// - 如果存在：用户在线，发送到 Kafka Topic "im_message_delivery_{gatewayID}"
-// - 如果不存在：用户离线，存储到 Redis 列表 "offline_msg:{userUUID}"
+// - 查询 Redis 路由表 "user_routes:{userUUID}" 获取用户所有在线设备及其所在 Gateway
+// - 有在线设备：发送到对应 Gateway 的 Kafka Topic "im_message_delivery_{gatewayID}"
+// - 没有在线设备：存储到 Redis 离线队列
 func (s *Service) deliverMessage(ctx context.Context, msg *pb.Message) {
-	// 根据消息类型确定推送目标列表
-	var targetUsers []string
+	// 根据消息类型确定推送目标列表，关系记录中带有成员的免打扰设置
+	var targets []*relation.Relation
 
-	if msg.MessageType == 1 { // 私聊：直接推送给接收者
-		targetUsers = []string{msg.RecipientUUID}
+	if msg.MessageType == 1 { // 私聊：推送给接收者，并回显给发送者的其他设备
+		recipient := &relation.Relation{UserUUID: msg.RecipientUUID}
+		if rel, err := s.relRepo.GetRelationByConversation(ctx, msg.RecipientUUID, msg.ConversationID); err == nil {
+			recipient = rel
+		}
+		targets = []*relation.Relation{recipient, {UserUUID: msg.SenderUUID}}
 		log.Logger.Sugar().Infof("Delivering private message from %s to %s", msg.SenderUUID, msg.RecipientUUID)
-	} else if msg.MessageType == 2 { // 群聊：查询群成员列表
-		memberUUIDs, err := s.relRepo.GetGroupMemberUUIDs(ctx, msg.ConversationID)
+	} else if msg.MessageType == 2 { // 群聊：查询群成员列表（包含发送者）
+		members, err := s.relRepo.GetGroupMembers(ctx, msg.ConversationID)
 		if err != nil {
 			log.Logger.Sugar().Errorf("deliverMessage: failed to get group members for %s: %v", msg.ConversationID, err)
 			return
 		}
-		targetUsers = memberUUIDs
-		log.Logger.Sugar().Infof("Delivering group message to %d members", len(memberUUIDs))
+		targets = members
+		log.Logger.Sugar().Infof("Delivering group message to %d members", len(members))
 	}
 
 	// 遍历目标用户列表，逐一投递
-	for _, userUUID := range targetUsers {
-		// 跳过发送者自己，避免收到自己发的消息
+	for _, target := range targets {
+		userUUID := target.UserUUID
+		pushMsg := newPushMessage(msg, userUUID)
+
 		if userUUID == msg.SenderUUID {
+			// 自己发的消息只需同步到其他设备，离线设备会通过增量同步补齐
+			s.routeToUser(userUUID, pushMsg, false)
 			continue
 		}
 
-		// 构造推送消息（复制原消息，更新接收者字段）
-		pushMsg := &pb.Message{
-			Id:             msg.Id,
-			ConversationID: msg.ConversationID,
-			SenderUUID:     msg.SenderUUID,
-			SendAt:         msg.SendAt,
-			ContentType:    msg.ContentType,
-			Body:           msg.Body,
-			Metadata:       msg.Metadata,
-			SenderName:     msg.SenderName,
-			Avatar:         msg.Avatar,
-			MessageType:    msg.MessageType,
-			RecipientUUID:  userUUID, // 设置当前推送的目标用户
-		}
-
-		// 【核心路由逻辑】根据用户在线状态决定投递方式
-		// Redis 中存储了 user_gateway:{uuid} -> gatewayID 的映射关系
-		// 这个映射由 Gateway 的 Hub 在用户连接时写入，断开时删除
-		gatewayID := s.getUserGateway(userUUID)
-		if gatewayID != "" {
-			// 用户在线：通过 Kafka 投递到对应 Gateway
-			// Topic 格式: im_message_delivery_{gatewayID}
-			// Gateway 会订阅自己的 Delivery Topic，收到消息后通过 WebSocket 推送给客户端
-			cfg := config.GetConfig()
-			topic := cfg.Kafka.Topics.Delivery + gatewayID
-			s.publishToKafka(topic, pushMsg)
-			log.Logger.Sugar().Infof("Delivered message to online user %s via gateway %s", userUUID, gatewayID)
-		} else {
+		// 被 @ 的成员即使开启了免打扰也需要提醒
+		pushMsg.Mentioned = isMentioned(msg.Metadata, userUUID)
+		pushMsg.Muted = target.IsMute && !pushMsg.Mentioned
+
+		s.incrUnread(ctx, userUUID, msg.ConversationID, msg.Seq)
+		s.routeToUser(userUUID, pushMsg, true)
+	}
+}
+
+// newPushMessage 构造推送给单个接收者的消息（复制原消息，更新接收者字段）
+func newPushMessage(msg *pb.Message, recipientUUID string) *pb.Message {
+	return &pb.Message{
+		Id:             msg.Id,
+		ConversationID: msg.ConversationID,
+		SenderUUID:     msg.SenderUUID,
+		SendAt:         msg.SendAt,
+		Seq:            msg.Seq,
+		ContentType:    msg.ContentType,
+		Body:           msg.Body,
+		Metadata:       msg.Metadata,
+		SenderName:     msg.SenderName,
+		Avatar:         msg.Avatar,
+		MessageType:    msg.MessageType,
+		RecipientUUID:  recipientUUID, // 设置当前推送的目标用户
+		SenderDeviceID: msg.SenderDeviceID,
+		ExpireAt:       msg.ExpireAt,
+	}
+}
+
+// routeToUser 按用户在线设备投递单条消息
+// storeOffline 为 false 时用户离线直接丢弃，适用于已读回执这类只对在线用户有意义的事件
+func (s *Service) routeToUser(userUUID string, msg *pb.Message, storeOffline bool) {
+	// 【核心路由逻辑】根据用户在线设备决定投递方式
+	// Redis 路由表 user_routes:{uuid} 记录了用户每个在线设备所在的 Gateway
+	// 由 Gateway 的 Hub 在设备连接时写入，断开时删除
+	routes := s.getUserRoutes(userUUID)
+	if len(routes) == 0 {
+		if storeOffline {
 			// 用户离线：存储到 Redis 离线消息队列
 			// 当用户重新上线时，Gateway 会发送 sync_offline 请求
 			// Logic 服务收到请求后会调用 SyncOfflineMessages 将消息推送给用户
-			s.storeOfflineMessage(userUUID, pushMsg)
+			s.storeOfflineMessage(userUUID, msg)
 			log.Logger.Sugar().Infof("Stored offline message for user %s", userUUID)
 		}
+		return
+	}
+
+	// 回显给发送者时跳过发出消息的设备
+	skipDevice := ""
+	if userUUID == msg.SenderUUID {
+		skipDevice = msg.SenderDeviceID
+	}
+
+	// 用户在线：通过 Kafka 投递到设备所在的 Gateway，同一 Gateway 上的多个设备只投递一次
+	// Topic 格式: im_message_delivery_{gatewayID}
+	// Gateway 会订阅自己的 Delivery Topic，收到消息后通过 WebSocket 推送给该用户的设备
+	cfg := config.GetConfig()
+	for _, gatewayID := range routeGateways(routes, msg.TargetDeviceID, skipDevice) {
+		topic := cfg.Kafka.Topics.Delivery + gatewayID
+		s.publishToKafka(topic, msg)
+		log.Logger.Sugar().Infof("Delivered message to online user %s via gateway %s", userUUID, gatewayID)
 	}
 }
 
-// getUserGateway 获取用户当前连接的网关ID
-// 这是消息路由的核心：通过 Redis 查询用户当前在哪个 Gateway 上在线
-//
-// 路由表结构：
-// - Key: "user_gateway:{userUUID}"
-// - Value: gatewayID (如 "gateway-default", "gateway-1" 等)
-// - 由 Gateway 的 Hub 在用户连接时 SET，断开时 DEL
-//
-// 返回值：
-// - 非空字符串：用户在线，返回 gatewayID
-// - 空字符串：用户离线（Redis 中没有该 key）
-func (s *Service) getUserGateway(userUUID string) string {
-	ctx := context.Background()
-	gatewayID, err := s.redis.Get(ctx, "user_gateway:"+userUUID).Result()
+// routeGateways 计算需要投递的 Gateway 列表（去重）
+// targetDevice 非空时只投递该设备；skipDevice 非空时排除该设备
+func routeGateways(routes []route.Route, targetDevice, skipDevice string) []string {
+	var gateways []string
+	seen := make(map[string]bool)
+	for _, r := range routes {
+		if targetDevice != "" && r.DeviceID != targetDevice {
+			continue
+		}
+		if skipDevice != "" && r.DeviceID == skipDevice {
+			continue
+		}
+		if !seen[r.GatewayID] {
+			seen[r.GatewayID] = true
+			gateways = append(gateways, r.GatewayID)
+		}
+	}
+	return gateways
+}
+
+// conversationMembers 返回会话的所有成员：私聊为双方，群聊为全部群成员
+func (s *Service) conversationMembers(ctx context.Context, rel *relation.Relation) ([]string, error) {
+	if rel.Type == relation.TypePrivate {
+		return []string{rel.UserUUID, rel.TargetUUID}, nil
+	}
+	return s.relRepo.GetGroupMemberUUIDs(ctx, rel.ConversationID)
+}
+
+// newEventMessage 构造推送给指定用户的事件消息，事件内容打包在 Body 中
+func newEventMessage(conversationID, recipientUUID string, messageType, eventType int32, payload proto.Message) (*pb.Message, error) {
+	body, err := anypb.New(payload)
 	if err != nil {
-		// redis.Nil 表示 key 不存在，即用户离线
-		return ""
+		return nil, err
 	}
-	return gatewayID
+	return &pb.Message{
+		Id:             primitive.NewObjectID().Hex(),
+		ConversationID: conversationID,
+		RecipientUUID:  recipientUUID,
+		MessageType:    messageType,
+		EventType:      eventType,
+		SendAt:         time.Now().Unix(),
+		Body:           body,
+	}, nil
+}
+
+// getUserRoutes 获取用户所有在线设备的路由
+// 返回空列表表示用户离线（所有设备都已断开）
+func (s *Service) getUserRoutes(userUUID string) []route.Route {
+	routes, err := route.List(context.Background(), s.redis, userUUID)
+	if err != nil {
+		log.Logger.Sugar().Warnf("Failed to get routes for user %s: %v", userUUID, err)
+		return nil
+	}
+	return routes
 }
 
 // publishToKafka 发布消息到 Kafka
@@ -357,77 +588,80 @@ func (s *Service) publishToKafka(topic string, msg *pb.Message) {
 	}
 }
 
-// storeOfflineMessage 存储离线消息
-func (s *Service) storeOfflineMessage(userUUID string, msg *pb.Message) {
-	ctx := context.Background()
-	msgData, err := proto.Marshal(msg)
-	if err != nil {
-		log.Logger.Sugar().Errorf("Failed to marshal offline message: %v", err)
-		return
+// GetMessageHistory 按游标分页获取消息历史记录
+// 消息附带被回复消息的摘要以及相对于 userUUID 的表情回应汇总
+func (s *Service) GetMessageHistory(ctx context.Context, userUUID, conversationID string, query HistoryQuery) (*HistoryPage, error) {
+	if err := query.Validate(); err != nil {
+		return nil, err
 	}
 
-	// redis 列表存储离线消息，key格式为 "offline_msg:{userUUID}"
-	key := "offline_msg:" + userUUID
-	err = s.redis.LPush(ctx, key, string(msgData)).Err()
-	if err != nil {
-		log.Logger.Sugar().Errorf("Failed to store offline message: %v", err)
+	// 多取一条，用于判断是否还有下一页
+	limit := query.Limit
+	if limit <= 0 {
+		limit = 20
 	}
+	query.Limit = limit + 1
 
-	// 设置过期时间（7天）
-	s.redis.Expire(ctx, key, time.Hour*24*7)
-}
-
-// SyncOfflineMessages 用户上线时同步离线消息
-func (s *Service) SyncOfflineMessages(userUUID string) error {
-	ctx := context.Background()
-	key := "offline_msg:" + userUUID
-
-	// 获取所有离线消息
-	messages, err := s.redis.LRange(ctx, key, 0, -1).Result()
+	messages, err := s.repo.GetByConversation(ctx, conversationID, query)
 	if err != nil {
-		return err
+		return nil, err
 	}
-
-	if len(messages) == 0 {
-		return nil // 没有离线消息
+	if !query.ByID() {
+		if messages, err = s.withUnsequenced(ctx, conversationID, query, messages); err != nil {
+			return nil, err
+		}
 	}
 
-	// 获取用户当前连接的网关
-	gatewayID := s.getUserGateway(userUUID)
-	if gatewayID == "" {
-		return nil // 用户不在线，跳过
-	}
+	page := &HistoryPage{Messages: messages}
+	if len(messages) > limit {
+		page.Messages = messages[:limit]
+		page.HasMore = true
 
-	// 推送离线消息到网关
-	cfg := config.GetConfig()
-	topic := cfg.Kafka.Topics.Delivery + gatewayID
-	for _, msgData := range messages {
-		// 直接发送原始数据
-		err = s.producer.SendMessage(topic, []byte(msgData))
-		if err != nil {
-			log.Logger.Sugar().Errorf("Failed to send offline message: %v", err)
+		// 没有序号的历史消息只能以 ObjectID 继续翻页，ObjectID 模式同样覆盖之后有序号的消息
+		last := page.Messages[limit-1]
+		next := MessageCursor{Seq: last.Seq}
+		if query.ByID() || last.Seq == 0 {
+			next = MessageCursor{ID: last.ID}
 		}
+		page.NextCursor = next.String()
 	}
 
-	// 清除已推送的离线消息
-	s.redis.Del(ctx, key)
+	s.attachReplySnippets(ctx, page.Messages)
+	s.attachReactions(ctx, userUUID, page.Messages)
 
-	log.Logger.Sugar().Infof("Synced %d offline messages for user: %s", len(messages), userUUID)
-	return nil
+	return page, nil
 }
 
-// GetMessageHistory 获取消息历史记录
-func (s *Service) GetMessageHistory(conversationID string, limit, offset int) ([]*Message, error) {
-	ctx := context.Background()
-	return s.repo.GetByConversation(ctx, conversationID, limit, offset)
-}
+// withUnsequenced 在 Seq 模式的结果中补上没有序号的历史消息
+// 这些消息都早于有序号的消息：不带 after 时，倒序翻到有序号的消息之前、或正序从头翻页，才需要补齐
+// 带 after 时结果只包含晚于游标的消息，无论正序倒序都不补齐
+func (s *Service) withUnsequenced(ctx context.Context, conversationID string, query HistoryQuery, messages []*Message) ([]*Message, error) {
+	if !query.After.IsZero() {
+		return messages, nil
+	}
+	if query.Ascending {
+		legacy, err := s.repo.GetUnsequenced(ctx, conversationID, true, query.Limit)
+		if err != nil || len(legacy) == 0 {
+			return messages, err
+		}
+		messages = append(legacy, messages...)
+		if len(messages) > query.Limit {
+			messages = messages[:query.Limit]
+		}
+		return messages, nil
+	}
 
-// MarkMessagesAsRead 标记消息为已读
-func (s *Service) MarkMessagesAsRead(msgIDs []string) error {
-	return s.repo.MarkAsRead(context.Background(), msgIDs)
+	if len(messages) >= query.Limit {
+		return messages, nil
+	}
+	legacy, err := s.repo.GetUnsequenced(ctx, conversationID, false, query.Limit-len(messages))
+	if err != nil {
+		return nil, err
+	}
+	return append(messages, legacy...), nil
 }
 
-// ProcessSyncRequest 处理离线消息同步请求
+// ProcessSyncRequest 处理 Gateway 转发的客户端请求（离线同步、离线确认、增量同步、已读、撤回、编辑、表情回应）
 func (s *Service) ProcessSyncRequest(ctx context.Context, kafkaMsg kafka.Message) error {
 	var syncRequest map[string]interface{}
 	if err := json.Unmarshal(kafkaMsg.Value, &syncRequest); err != nil {
@@ -435,23 +669,90 @@ func (s *Service) ProcessSyncRequest(ctx context.Context, kafkaMsg kafka.Message
 		return err
 	}
 
-	action, ok := syncRequest["action"].(string)
-	if !ok || action != "sync_offline" {
-		return errors.New("invalid sync action")
-	}
-
 	userUUID, ok := syncRequest["useruuid"].(string)
 	if !ok {
 		log.Logger.Sugar().Errorf("Invalid user_uuid in sync request")
 		return errors.New("invalid user_uuid in sync request")
 	}
+	// 发起请求的设备，结果只推送给该设备
+	deviceID, _ := syncRequest["deviceid"].(string)
 
-	// 同步离线消息
-	err := s.SyncOfflineMessages(userUUID)
-	if err != nil {
-		log.Logger.Sugar().Errorf("Failed to sync offline messages for user %s: %v", userUUID, err)
-	} else {
-		log.Logger.Sugar().Infof("Successfully synced offline messages for user: %s", userUUID)
+	action, _ := syncRequest["action"].(string)
+	switch action {
+	case "sync_offline":
+		// 同步离线消息
+		err := s.SyncOfflineMessages(userUUID, deviceID)
+		if err != nil {
+			log.Logger.Sugar().Errorf("Failed to sync offline messages for user %s: %v", userUUID, err)
+		} else {
+			log.Logger.Sugar().Infof("Successfully synced offline messages for user: %s", userUUID)
+		}
+	case "offline_resume":
+		// 推送窗口超时仍未确认，重新推送离线消息
+		if err := s.ResumeOfflineMessages(userUUID, deviceID); err != nil {
+			log.Logger.Sugar().Errorf("Failed to resume offline messages for user %s: %v", userUUID, err)
+		}
+	case "offline_ack":
+		// 客户端确认已收到离线消息
+		var msgIDs []string
+		if ids, ok := syncRequest["ids"].([]interface{}); ok {
+			for _, id := range ids {
+				if str, ok := id.(string); ok {
+					msgIDs = append(msgIDs, str)
+				}
+			}
+		}
+		requestID, _ := syncRequest["requestID"].(string)
+		if _, err := s.AckOfflineMessages(ctx, userUUID, deviceID, msgIDs); err != nil {
+			log.Logger.Sugar().Errorf("Failed to ack offline messages for user %s: %v", userUUID, err)
+			s.nackCommand(userUUID, deviceID, requestID, "", err)
+		} else {
+			s.ackCommand(userUUID, deviceID, requestID, &pb.Ack{})
+		}
+	case "sync":
+		// 客户端通过 WebSocket 发起的按序号增量同步
+		req := SyncRequest{Conversations: make(map[string]int64)}
+		if convs, ok := syncRequest["conversations"].(map[string]interface{}); ok {
+			for convID, seq := range convs {
+				if v, ok := seq.(float64); ok {
+					req.Conversations[convID] = int64(v)
+				}
+			}
+		}
+		if limit, ok := syncRequest["limit"].(float64); ok {
+			req.Limit = int(limit)
+		}
+		requestID, _ := syncRequest["requestID"].(string)
+		if err := s.PushSync(ctx, userUUID, deviceID, requestID, req); err != nil {
+			log.Logger.Sugar().Errorf("Failed to sync conversations for user %s: %v", userUUID, err)
+		}
+	case "read":
+		// 客户端通过 WebSocket 推进已读位置
+		conversationID, _ := syncRequest["conversationID"].(string)
+		seq, _ := syncRequest["seq"].(float64)
+		msgID, _ := syncRequest["messageID"].(string)
+		requestID, _ := syncRequest["requestID"].(string)
+		s.readFromDevice(ctx, userUUID, deviceID, requestID, conversationID, int64(seq), msgID)
+	case "recall":
+		// 客户端通过 WebSocket 撤回消息
+		msgID, _ := syncRequest["messageID"].(string)
+		requestID, _ := syncRequest["requestID"].(string)
+		s.recallFromDevice(ctx, userUUID, deviceID, requestID, msgID)
+	case "edit":
+		// 客户端通过 WebSocket 编辑消息
+		msgID, _ := syncRequest["messageID"].(string)
+		requestID, _ := syncRequest["requestID"].(string)
+		content, _ := syncRequest["content"].(string)
+		s.editFromDevice(ctx, userUUID, deviceID, requestID, msgID, content)
+	case "reaction":
+		// 客户端通过 WebSocket 添加或取消表情回应
+		msgID, _ := syncRequest["messageID"].(string)
+		requestID, _ := syncRequest["requestID"].(string)
+		emoji, _ := syncRequest["emoji"].(string)
+		remove, _ := syncRequest["remove"].(bool)
+		s.reactFromDevice(ctx, userUUID, deviceID, requestID, msgID, emoji, remove)
+	default:
+		return errors.New("invalid sync action")
 	}
 	return nil
 }
@@ -459,7 +760,7 @@ func (s *Service) ProcessSyncRequest(ctx context.Context, kafkaMsg kafka.Message
 // 辅助函数：解包 google.protobuf.Any
 func (s *Service) unpackProtoBody(contentType int32, anyBody *anypb.Any) (any, error) {
 	switch contentType {
-	case 1: // Text
+	case 1, ContentTypeSystem: // Text, System notice
 		var textBody pb.TextBody
 		if err := anyBody.UnmarshalTo(&textBody); err != nil {
 			return nil, err
@@ -646,6 +947,19 @@ func (s *Service) GetConversationsByUserID(ctx context.Context, userID string, l
 		conversations = append(conversations, conv)
 	}
 
+	// 3. 填充未读数
+	convIDs := make([]string, 0, len(conversations))
+	for _, conv := range conversations {
+		convIDs = append(convIDs, conv.ID)
+	}
+	if counts, err := s.loadUnreadCounts(ctx, userID, convIDs); err == nil {
+		for _, conv := range conversations {
+			conv.UnreadCount = counts[conv.ID]
+		}
+	} else {
+		log.Logger.Sugar().Warnf("Failed to load unread counts for user %s: %v", userID, err)
+	}
+
 	return conversations, nil
 }
 
@@ -4,6 +4,7 @@ import (
 	pb "MyGoChat/pkg/api/v1"
 	"MyGoChat/chat/internal/util"
 	"MyGoChat/pkg/common/request"
+	"MyGoChat/pkg/route"
 	"context"
 	"testing"
 	"time"
@@ -26,19 +27,241 @@ func (m *MockRepository) CreateMsg(ctx context.Context, msg *Message) error {
 	return args.Error(0)
 }
 
-func (m *MockRepository) GetByConversation(ctx context.Context, convID string, limit, offset int) ([]*Message, error) {
-	args := m.Called(ctx, convID, limit, offset)
+func (m *MockRepository) GetByConversation(ctx context.Context, convID string, query HistoryQuery) ([]*Message, error) {
+	args := m.Called(ctx, convID, query)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).([]*Message), args.Error(1)
 }
 
-func (m *MockRepository) MarkAsRead(ctx context.Context, msgIDs []string) error {
+func (m *MockRepository) GetUnsequenced(ctx context.Context, convID string, ascending bool, limit int) ([]*Message, error) {
+	args := m.Called(ctx, convID, ascending, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*Message), args.Error(1)
+}
+
+func (m *MockRepository) AllocateSeq(ctx context.Context, conversationID string) (int64, error) {
+	args := m.Called(ctx, conversationID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockRepository) GetMessageByID(ctx context.Context, msgID string) (*Message, error) {
+	args := m.Called(ctx, msgID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Message), args.Error(1)
+}
+
+func (m *MockRepository) GetMentions(ctx context.Context, userUUID string, convIDs []string, before primitive.ObjectID, limit int) ([]*Message, error) {
+	args := m.Called(ctx, userUUID, convIDs, before, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*Message), args.Error(1)
+}
+
+func (m *MockRepository) GetMessagesByIDs(ctx context.Context, msgIDs []string) ([]*Message, error) {
 	args := m.Called(ctx, msgIDs)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*Message), args.Error(1)
+}
+
+func (m *MockRepository) GetMessageByClientMsgID(ctx context.Context, senderUUID, clientMsgID string) (*Message, error) {
+	args := m.Called(ctx, senderUUID, clientMsgID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Message), args.Error(1)
+}
+
+func (m *MockRepository) GetSendersInRange(ctx context.Context, convID string, fromSeq, toSeq int64) (map[string]int64, error) {
+	args := m.Called(ctx, convID, fromSeq, toSeq)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[string]int64), args.Error(1)
+}
+
+func (m *MockRepository) RecallMessage(ctx context.Context, msgID primitive.ObjectID, operatorUUID string, recalledAt int64) (bool, error) {
+	args := m.Called(ctx, msgID, operatorUUID, recalledAt)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepository) EditMessage(ctx context.Context, msgID primitive.ObjectID, senderUUID string, body any, editedAt int64) (*Message, error) {
+	args := m.Called(ctx, msgID, senderUUID, body, editedAt)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Message), args.Error(1)
+}
+
+func (m *MockRepository) GetRevisions(ctx context.Context, msgID string) ([]*MessageRevision, error) {
+	args := m.Called(ctx, msgID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*MessageRevision), args.Error(1)
+}
+
+func (m *MockRepository) PinMessage(ctx context.Context, conversationID string, pin *Pin, maxPins int) (bool, error) {
+	args := m.Called(ctx, conversationID, pin, maxPins)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepository) UnpinMessage(ctx context.Context, conversationID, msgID string) (bool, error) {
+	args := m.Called(ctx, conversationID, msgID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepository) GetPins(ctx context.Context, conversationID string) ([]*Pin, error) {
+	args := m.Called(ctx, conversationID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*Pin), args.Error(1)
+}
+
+func (m *MockRepository) GetMessageTTL(ctx context.Context, conversationID string) (int64, error) {
+	args := m.Called(ctx, conversationID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockRepository) SetMessageTTL(ctx context.Context, conversationID string, ttl int64) (bool, error) {
+	args := m.Called(ctx, conversationID, ttl)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepository) CreateScheduled(ctx context.Context, sm *ScheduledMessage) error {
+	args := m.Called(ctx, sm)
 	return args.Error(0)
 }
 
+func (m *MockRepository) GetScheduled(ctx context.Context, id string) (*ScheduledMessage, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*ScheduledMessage), args.Error(1)
+}
+
+func (m *MockRepository) ListPendingScheduled(ctx context.Context, senderUUID string) ([]*ScheduledMessage, error) {
+	args := m.Called(ctx, senderUUID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*ScheduledMessage), args.Error(1)
+}
+
+func (m *MockRepository) UpdatePendingScheduled(ctx context.Context, sm *ScheduledMessage) (bool, error) {
+	args := m.Called(ctx, sm)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepository) CancelScheduled(ctx context.Context, id primitive.ObjectID, senderUUID string) (bool, error) {
+	args := m.Called(ctx, id, senderUUID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepository) ClaimDueScheduled(ctx context.Context, now, staleBefore int64) (*ScheduledMessage, error) {
+	args := m.Called(ctx, now, staleBefore)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*ScheduledMessage), args.Error(1)
+}
+
+func (m *MockRepository) FinishScheduled(ctx context.Context, id primitive.ObjectID, status int, reason string) error {
+	args := m.Called(ctx, id, status, reason)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetThreadReplies(ctx context.Context, rootID string, before primitive.ObjectID, limit int) ([]*Message, error) {
+	args := m.Called(ctx, rootID, before, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*Message), args.Error(1)
+}
+
+func (m *MockRepository) UpdateThreadSummary(ctx context.Context, rootID primitive.ObjectID, repliedAt int64, participants []string) (*Message, error) {
+	args := m.Called(ctx, rootID, repliedAt, participants)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Message), args.Error(1)
+}
+
+func (m *MockRepository) AddReaction(ctx context.Context, reaction *Reaction) (bool, error) {
+	args := m.Called(ctx, reaction)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepository) RemoveReaction(ctx context.Context, msgID, userUUID, emoji string) (bool, error) {
+	args := m.Called(ctx, msgID, userUUID, emoji)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepository) CountReactions(ctx context.Context, msgID, emoji string) (int64, error) {
+	args := m.Called(ctx, msgID, emoji)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockRepository) GetReactionSummaries(ctx context.Context, msgIDs []string, userUUID string) (map[string][]*ReactionSummary, error) {
+	args := m.Called(ctx, msgIDs, userUUID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[string][]*ReactionSummary), args.Error(1)
+}
+
+func (m *MockRepository) AdvanceReadCursor(ctx context.Context, userUUID, convID string, seq int64, msgID string) (int64, bool, error) {
+	args := m.Called(ctx, userUUID, convID, seq, msgID)
+	return args.Get(0).(int64), args.Bool(1), args.Error(2)
+}
+
+func (m *MockRepository) GetReadCursors(ctx context.Context, convID string) ([]*ReadCursor, error) {
+	args := m.Called(ctx, convID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*ReadCursor), args.Error(1)
+}
+
+func (m *MockRepository) GetUserReadSeqs(ctx context.Context, userUUID string, convIDs []string) (map[string]int64, error) {
+	args := m.Called(ctx, userUUID, convIDs)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[string]int64), args.Error(1)
+}
+
+func (m *MockRepository) CountUnread(ctx context.Context, readSeqs map[string]int64) (map[string]UnreadCount, error) {
+	args := m.Called(ctx, readSeqs)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[string]UnreadCount), args.Error(1)
+}
+
+func (m *MockRepository) GetConversationsByIDs(ctx context.Context, convIDs []string) ([]*Conversation, error) {
+	args := m.Called(ctx, convIDs)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*Conversation), args.Error(1)
+}
+
+func (m *MockRepository) CountReaders(ctx context.Context, convID string, seq int64) (int64, error) {
+	args := m.Called(ctx, convID, seq)
+	return args.Get(0).(int64), args.Error(1)
+}
+
 func (m *MockRepository) CreateConversation(ctx context.Context, conv *Conversation) error {
 	args := m.Called(ctx, conv)
 	return args.Error(0)
@@ -73,6 +296,11 @@ func (m *MockRepository) UpdateLastMessage(ctx context.Context, conversationID s
 	return args.Error(0)
 }
 
+func (m *MockRepository) ReplaceLastMessage(ctx context.Context, conversationID, msgID string, body any) error {
+	args := m.Called(ctx, conversationID, msgID, body)
+	return args.Error(0)
+}
+
 // MockRelationRepository 模拟 relation.Repository 接口
 type MockRelationRepository struct {
 	mock.Mock
@@ -378,6 +606,27 @@ func TestConversationID_Deterministic(t *testing.T) {
 	assert.NotEmpty(t, convID1, "ConversationID should not be empty")
 }
 
+// TestRouteGateways 测试多设备路由：按 Gateway 去重，支持指定设备与跳过发送设备
+func TestRouteGateways(t *testing.T) {
+	routes := []route.Route{
+		{GatewayID: "gw-1", DeviceID: "phone"},
+		{GatewayID: "gw-1", DeviceID: "desktop"},
+		{GatewayID: "gw-2", DeviceID: "web"},
+	}
+
+	assert.Equal(t, []string{"gw-1", "gw-2"}, routeGateways(routes, "", ""))
+	assert.Equal(t, []string{"gw-2"}, routeGateways(routes, "web", ""))
+	// 跳过 phone 后 gw-1 上仍有 desktop，需要投递
+	assert.Equal(t, []string{"gw-1", "gw-2"}, routeGateways(routes, "", "phone"))
+	assert.Empty(t, routeGateways(routes, "web", "web"))
+
+	r, ok := route.Parse("gw-1|phone")
+	assert.True(t, ok)
+	assert.Equal(t, routes[0], r)
+	_, ok = route.Parse("no-separator")
+	assert.False(t, ok)
+}
+
 // TestSendMessage_InvalidMessageType 测试无效消息类型
 func TestSendMessage_InvalidMessageType(t *testing.T) {
 	ctx := context.Background()
@@ -0,0 +1,41 @@
+package chat
+
+import (
+	"MyGoChat/pkg/log"
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// ProcessSignal 处理 Gateway 转发的瞬时信号（正在输入、在线状态变化），信号不入库，只推送给在线用户
+func (s *Service) ProcessSignal(ctx context.Context, kafkaMsg kafka.Message) error {
+	var signal struct {
+		Action         string `json:"action"`
+		UserUUID       string `json:"useruuid"`
+		ConversationID string `json:"conversationID"`
+		Typing         bool   `json:"typing"`
+		Status         string `json:"status"`
+		LastSeen       int64  `json:"lastSeen"`
+		Timestamp      int64  `json:"timestamp"`
+	}
+	if err := json.Unmarshal(kafkaMsg.Value, &signal); err != nil {
+		log.Logger.Sugar().Errorf("Failed to unmarshal signal: %v", err)
+		return err
+	}
+
+	switch signal.Action {
+	case "typing":
+		if err := s.RelayTyping(ctx, signal.UserUUID, signal.ConversationID, signal.Typing, signal.Timestamp); err != nil {
+			log.Logger.Sugar().Debugf("Failed to relay typing of %s in %s: %v", signal.UserUUID, signal.ConversationID, err)
+		}
+	case "presence":
+		if err := s.PushPresence(ctx, signal.UserUUID, signal.Status, signal.LastSeen); err != nil {
+			log.Logger.Sugar().Errorf("Failed to push presence of %s: %v", signal.UserUUID, err)
+		}
+	default:
+		return errors.New("invalid signal action")
+	}
+	return nil
+}
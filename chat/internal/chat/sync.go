@@ -0,0 +1,230 @@
+package chat
+
+import (
+	pb "MyGoChat/pkg/api/v1"
+	"MyGoChat/pkg/config"
+	"MyGoChat/pkg/log"
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// 增量同步以 MongoDB 为准：客户端上报每个会话已有的最大序号，服务端返回更新的消息。
+// Redis 离线队列只是上线时的快速推送，丢失或淘汰不影响同步结果
+const (
+	defaultSyncLimit = 50
+	maxSyncLimit     = 200
+)
+
+// SyncConversations 按序号增量同步用户所有会话中的新消息
+func (s *Service) SyncConversations(ctx context.Context, userUUID string, req SyncRequest) ([]*ConversationSync, error) {
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultSyncLimit
+	}
+	if limit > maxSyncLimit {
+		limit = maxSyncLimit
+	}
+
+	convIDs, err := s.relRepo.GetUserConversationIDs(ctx, userUUID)
+	if err != nil {
+		return nil, err
+	}
+	results := []*ConversationSync{}
+	if len(convIDs) == 0 {
+		return results, nil
+	}
+
+	convs, err := s.repo.GetConversationsByIDs(ctx, convIDs)
+	if err != nil {
+		return nil, err
+	}
+	// 客户端没有上报游标的会话（如新设备、新会话），从已读位置开始同步
+	readSeqs, err := s.repo.GetUserReadSeqs(ctx, userUUID, convIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var syncedIDs []string
+	for _, conv := range convs {
+		lastSeq, ok := req.Conversations[conv.ID]
+		if !ok {
+			lastSeq = readSeqs[conv.ID]
+		}
+		if conv.MaxSeq <= lastSeq {
+			continue
+		}
+
+		messages, err := s.repo.GetByConversation(ctx, conv.ID, HistoryQuery{
+			After:     MessageCursor{Seq: lastSeq},
+			Ascending: true,
+			Limit:     limit + 1,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		result := &ConversationSync{
+			ConversationID: conv.ID,
+			Type:           conv.Type,
+			Messages:       messages,
+			LastSeq:        lastSeq,
+			MaxSeq:         conv.MaxSeq,
+		}
+		if len(messages) > limit {
+			result.Messages = messages[:limit]
+			result.HasMore = true
+		}
+		if n := len(result.Messages); n > 0 {
+			result.LastSeq = result.Messages[n-1].Seq
+		}
+		s.attachReplySnippets(ctx, result.Messages)
+		s.attachReactions(ctx, userUUID, result.Messages)
+		for _, m := range result.Messages {
+			syncedIDs = append(syncedIDs, m.ID.Hex())
+		}
+		results = append(results, result)
+	}
+
+	// 已经通过同步拿到的消息无需再从离线队列推送
+	if len(syncedIDs) > 0 {
+		if _, err := s.deleteOfflineMessages(ctx, userUUID, syncedIDs); err != nil {
+			log.Logger.Sugar().Warnf("Failed to clear synced offline messages for user %s: %v", userUUID, err)
+		}
+	}
+
+	return results, nil
+}
+
+// PushSync 处理 WebSocket 同步请求：将同步结果逐条推送给发起请求的设备，最后推送 SyncComplete
+func (s *Service) PushSync(ctx context.Context, userUUID, deviceID, requestID string, req SyncRequest) error {
+	gateways := routeGateways(s.getUserRoutes(userUUID), deviceID, "")
+	if len(gateways) == 0 {
+		return nil // 设备不在线，跳过
+	}
+
+	results, err := s.SyncConversations(ctx, userUUID, req)
+	if err != nil {
+		return err
+	}
+
+	delivery := config.GetConfig().Kafka.Topics.Delivery
+	publish := func(msg *pb.Message) {
+		msg.TargetDeviceID = deviceID
+		for _, gatewayID := range gateways {
+			s.publishToKafka(delivery+gatewayID, msg)
+		}
+	}
+
+	complete := &pb.SyncComplete{RequestID: requestID}
+	for _, result := range results {
+		for _, m := range result.Messages {
+			pushMsg, err := messageToProto(m, int32(result.Type), userUUID)
+			if err != nil {
+				log.Logger.Sugar().Warnf("PushSync: failed to convert message %s: %v", m.ID.Hex(), err)
+				continue
+			}
+			publish(pushMsg)
+		}
+		complete.Conversations = append(complete.Conversations, &pb.SyncState{
+			ConversationID: result.ConversationID,
+			LastSeq:        result.LastSeq,
+			MaxSeq:         result.MaxSeq,
+			HasMore:        result.HasMore,
+		})
+	}
+
+	event, err := newEventMessage("", userUUID, 0, pb.EventTypeSyncComplete, complete)
+	if err != nil {
+		return err
+	}
+	publish(event)
+	return nil
+}
+
+// messageToProto 将 MongoDB 中的消息还原为推送给客户端的 pb.Message
+func messageToProto(m *Message, messageType int32, recipientUUID string) (*pb.Message, error) {
+	msg := &pb.Message{
+		Id:                m.ID.Hex(),
+		ConversationID:    m.ConversationID,
+		SenderUUID:        m.SenderUUID,
+		SenderName:        m.SenderName,
+		SendAt:            m.SendAt,
+		Seq:               m.Seq,
+		Edited:            m.Edited,
+		EditedAt:          m.EditedAt,
+		ThreadReplyCount:  m.ThreadReplyCount,
+		ThreadLastReplyAt: m.ThreadLastReplyAt,
+		ContentType:       int32(m.ContentType),
+		MessageType:       messageType,
+		RecipientUUID:     recipientUUID,
+	}
+	if m.ExpireAt != nil {
+		msg.ExpireAt = m.ExpireAt.Unix()
+	}
+	if m.Metadata != nil {
+		msg.Metadata = &pb.MessageMetadata{
+			ReplyToMsgID:  m.Metadata.ReplyToMsgID,
+			MentionUUIDs:  m.Metadata.MentionUUIDs,
+			MentionAll:    m.Metadata.MentionAll,
+			ThreadRootID:  m.Metadata.ThreadRootID,
+			ForwardedFrom: m.Metadata.ForwardedFrom.toProto(),
+		}
+		if m.Metadata.Reply != nil {
+			msg.Metadata.Reply = m.Metadata.Reply.toProto()
+		}
+		// 与实时推送一致，同步结果同样标记接收者是否被 @
+		msg.Mentioned = isMentioned(msg.Metadata, recipientUUID)
+	}
+	if m.Recalled {
+		// 已撤回的消息只下发占位，没有消息体
+		msg.Recalled = true
+		return msg, nil
+	}
+
+	body, err := packStoredBody(m.ContentType, m.Body)
+	if err != nil {
+		return nil, err
+	}
+	msg.Body = body
+	return msg, nil
+}
+
+// packStoredBody 将入库的消息体（unpackProtoBody 的结果）重新打包为 google.protobuf.Any
+func packStoredBody(contentType int16, body any) (*anypb.Any, error) {
+	switch contentType {
+	case 1, ContentTypeSystem: // Text, System notice
+		content, ok := body.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid stored text body")
+		}
+		return anypb.New(&pb.TextBody{Content: content})
+	case 2, 3, 4: // Image, File, Voice
+		file, err := storedFileAttachment(body)
+		if err != nil {
+			return nil, err
+		}
+		return anypb.New(&pb.FileAttachment{
+			Url:      file.URL,
+			FileName: file.FileName,
+			Size:     file.Size,
+			MimeType: file.MimeType,
+		})
+	default:
+		return nil, fmt.Errorf("unsupported content type: %d", contentType)
+	}
+}
+
+// storedFileAttachment 将入库的文件类消息体还原为 FileAttachment
+// 从 MongoDB 读出的是 bson.D，借助 bson 编解码还原
+func storedFileAttachment(body any) (FileAttachment, error) {
+	var file FileAttachment
+	raw, err := bson.Marshal(body)
+	if err != nil {
+		return file, err
+	}
+	err = bson.Unmarshal(raw, &file)
+	return file, err
+}
@@ -0,0 +1,87 @@
+package chat
+
+import (
+	pb "MyGoChat/pkg/api/v1"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TestMessageToProto 测试从 MongoDB 读出的消息能还原为推送用的 pb.Message
+func TestMessageToProto(t *testing.T) {
+	textMsg := &Message{
+		ID:             primitive.NewObjectID(),
+		ConversationID: "conv-1",
+		SenderUUID:     "sender-uuid",
+		Seq:            7,
+		ContentType:    1,
+		Body:           "hello",
+	}
+
+	pushMsg, err := messageToProto(textMsg, 1, "recipient-uuid")
+	assert.NoError(t, err)
+	assert.Equal(t, textMsg.ID.Hex(), pushMsg.Id)
+	assert.Equal(t, int64(7), pushMsg.Seq)
+	assert.Equal(t, "recipient-uuid", pushMsg.RecipientUUID)
+
+	var text pb.TextBody
+	assert.NoError(t, pushMsg.Body.UnmarshalTo(&text))
+	assert.Equal(t, "hello", text.Content)
+
+	// 文件类消息体从 MongoDB 解码出来是 bson.D
+	fileMsg := &Message{
+		ID:          primitive.NewObjectID(),
+		ContentType: 2,
+		Body: bson.D{
+			{Key: "url", Value: "http://example.com/a.png"},
+			{Key: "fileName", Value: "a.png"},
+			{Key: "size", Value: int64(1024)},
+			{Key: "mimeType", Value: "image/png"},
+		},
+	}
+
+	pushMsg, err = messageToProto(fileMsg, 2, "recipient-uuid")
+	assert.NoError(t, err)
+
+	var file pb.FileAttachment
+	assert.NoError(t, pushMsg.Body.UnmarshalTo(&file))
+	assert.Equal(t, "a.png", file.FileName)
+	assert.Equal(t, int64(1024), file.Size)
+
+	_, err = messageToProto(&Message{ContentType: 1, Body: 123}, 1, "recipient-uuid")
+	assert.Error(t, err)
+
+	// 已撤回的消息没有消息体，只下发撤回标记
+	pushMsg, err = messageToProto(&Message{ID: primitive.NewObjectID(), ContentType: 1, Recalled: true}, 1, "recipient-uuid")
+	assert.NoError(t, err)
+	assert.True(t, pushMsg.Recalled)
+	assert.Nil(t, pushMsg.Body)
+}
+
+// TestMessageToProto_Mentions 测试同步结果保留 @ 信息，并标记接收者是否被 @
+func TestMessageToProto_Mentions(t *testing.T) {
+	m := &Message{
+		ID:          primitive.NewObjectID(),
+		ContentType: 1,
+		Body:        "@user-a hi",
+		Metadata:    &MessageMetadata{MentionUUIDs: []string{"user-a"}},
+	}
+
+	pushMsg, err := messageToProto(m, 2, "user-a")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"user-a"}, pushMsg.Metadata.MentionUUIDs)
+	assert.False(t, pushMsg.Metadata.MentionAll)
+	assert.True(t, pushMsg.Mentioned)
+
+	pushMsg, err = messageToProto(m, 2, "user-b")
+	assert.NoError(t, err)
+	assert.False(t, pushMsg.Mentioned)
+
+	m.Metadata = &MessageMetadata{MentionAll: true}
+	pushMsg, err = messageToProto(m, 2, "user-b")
+	assert.NoError(t, err)
+	assert.True(t, pushMsg.Metadata.MentionAll)
+	assert.True(t, pushMsg.Mentioned)
+}
@@ -0,0 +1,125 @@
+package chat
+
+import (
+	pb "MyGoChat/pkg/api/v1"
+	"MyGoChat/pkg/log"
+	"context"
+	"errors"
+)
+
+var (
+	ErrThreadNotAllowed  = errors.New("threads are only allowed in group chats")
+	ErrInvalidThreadRoot = errors.New("thread root not found in this conversation")
+)
+
+// loadThreadRoot 获取话题根消息：只有群聊可以开启话题，根消息必须属于同一会话且本身不是话题回复
+func (s *Service) loadThreadRoot(ctx context.Context, messageType int32, conversationID, rootID string) (*Message, error) {
+	if messageType != 2 {
+		return nil, ErrThreadNotAllowed
+	}
+	root, err := s.repo.GetMessageByID(ctx, rootID)
+	if err != nil || root.ConversationID != conversationID || root.IsThreadReply() {
+		return nil, ErrInvalidThreadRoot
+	}
+	return root, nil
+}
+
+// deliverThreadReply 投递话题回复：更新根消息的话题摘要，
+// 话题参与者（根消息发送者、回复过或被 @ 的成员）收到回复本身，其余群成员只收到话题更新事件
+func (s *Service) deliverThreadReply(ctx context.Context, msg *pb.Message, root *Message) {
+	// 回复者与被 @ 的成员从此成为话题参与者
+	participants := append([]string{root.SenderUUID, msg.SenderUUID}, msg.GetMetadata().GetMentionUUIDs()...)
+
+	updated, err := s.repo.UpdateThreadSummary(ctx, root.ID, msg.SendAt, participants)
+	if err != nil {
+		log.Logger.Sugar().Warnf("Failed to update thread summary of %s: %v", root.ID.Hex(), err)
+		updated = root
+		updated.ThreadReplyCount++
+		updated.ThreadLastReplyAt = msg.SendAt
+		updated.ThreadParticipants = append(updated.ThreadParticipants, participants...)
+	}
+
+	isParticipant := make(map[string]bool, len(updated.ThreadParticipants))
+	for _, uuid := range updated.ThreadParticipants {
+		isParticipant[uuid] = true
+	}
+
+	members, err := s.relRepo.GetGroupMembers(ctx, msg.ConversationID)
+	if err != nil {
+		log.Logger.Sugar().Errorf("deliverThreadReply: failed to get group members for %s: %v", msg.ConversationID, err)
+		return
+	}
+
+	update := &pb.ThreadUpdate{
+		ConversationID:      msg.ConversationID,
+		RootMessageID:       root.ID.Hex(),
+		ReplyCount:          updated.ThreadReplyCount,
+		LastReplyAt:         updated.ThreadLastReplyAt,
+		LastReplySenderUUID: msg.SenderUUID,
+	}
+	for _, target := range members {
+		userUUID := target.UserUUID
+		mentioned := isMentioned(msg.Metadata, userUUID)
+
+		if !isParticipant[userUUID] && !mentioned {
+			// 话题摘要只对在线成员有意义，离线成员通过历史消息中的根消息获取
+			event, err := newEventMessage(msg.ConversationID, userUUID, msg.MessageType, pb.EventTypeThread, update)
+			if err != nil {
+				log.Logger.Sugar().Errorf("deliverThreadReply: failed to build thread event: %v", err)
+				return
+			}
+			s.routeToUser(userUUID, event, false)
+			continue
+		}
+
+		pushMsg := newPushMessage(msg, userUUID)
+		if userUUID == msg.SenderUUID {
+			s.routeToUser(userUUID, pushMsg, false)
+			continue
+		}
+		// 话题回复不计入会话未读数
+		pushMsg.Mentioned = mentioned
+		pushMsg.Muted = target.IsMute && !mentioned
+		s.routeToUser(userUUID, pushMsg, true)
+	}
+}
+
+// GetThread 获取话题根消息，并按时间倒序分页获取话题回复
+func (s *Service) GetThread(ctx context.Context, userUUID, rootID string, before MessageCursor, limit int) (*Message, *HistoryPage, error) {
+	// 话题回复没有会话序号，只支持 ObjectID 游标
+	if !before.IsZero() && !before.IsID() {
+		return nil, nil, ErrInvalidCursor
+	}
+	if limit <= 0 {
+		limit = 20
+	}
+
+	root, err := s.repo.GetMessageByID(ctx, rootID)
+	if err != nil {
+		return nil, nil, ErrMessageNotFound
+	}
+	if root.IsThreadReply() {
+		return nil, nil, ErrInvalidThreadRoot
+	}
+	if _, err := s.relRepo.GetRelationByConversation(ctx, userUUID, root.ConversationID); err != nil {
+		return nil, nil, ErrNotMember
+	}
+
+	// 多取一条，用于判断是否还有下一页
+	messages, err := s.repo.GetThreadReplies(ctx, rootID, before.ID, limit+1)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	page := &HistoryPage{Messages: messages}
+	if len(messages) > limit {
+		page.Messages = messages[:limit]
+		page.HasMore = true
+		page.NextCursor = MessageCursor{ID: page.Messages[limit-1].ID}.String()
+	}
+
+	all := append([]*Message{root}, page.Messages...)
+	s.attachReplySnippets(ctx, all)
+	s.attachReactions(ctx, userUUID, all)
+	return root, page, nil
+}
@@ -0,0 +1,42 @@
+package chat
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TestLoadThreadRoot 测试话题根消息的校验：仅群聊、同一会话、不能嵌套
+func TestLoadThreadRoot(t *testing.T) {
+	ctx := context.Background()
+	rootID := primitive.NewObjectID()
+	replyID := primitive.NewObjectID()
+
+	mockRepo := new(MockRepository)
+	mockRepo.On("GetMessageByID", mock.Anything, rootID.Hex()).
+		Return(&Message{ID: rootID, ConversationID: "group-1"}, nil)
+	mockRepo.On("GetMessageByID", mock.Anything, replyID.Hex()).
+		Return(&Message{
+			ID:             replyID,
+			ConversationID: "group-1",
+			Metadata:       &MessageMetadata{ThreadRootID: rootID.Hex()},
+		}, nil)
+	service := &Service{repo: mockRepo}
+
+	root, err := service.loadThreadRoot(ctx, 2, "group-1", rootID.Hex())
+	assert.NoError(t, err)
+	assert.Equal(t, rootID, root.ID)
+
+	_, err = service.loadThreadRoot(ctx, 1, "group-1", rootID.Hex())
+	assert.ErrorIs(t, err, ErrThreadNotAllowed)
+
+	_, err = service.loadThreadRoot(ctx, 2, "group-2", rootID.Hex())
+	assert.ErrorIs(t, err, ErrInvalidThreadRoot)
+
+	// 话题回复不能再作为话题根
+	_, err = service.loadThreadRoot(ctx, 2, "group-1", replyID.Hex())
+	assert.ErrorIs(t, err, ErrInvalidThreadRoot)
+}
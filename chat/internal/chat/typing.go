@@ -0,0 +1,54 @@
+package chat
+
+import (
+	pb "MyGoChat/pkg/api/v1"
+	"context"
+	"time"
+)
+
+// 正在输入状态的有效期：客户端在到期前没有收到新的状态时自动清除，
+// 超过有效期才被处理的信号（如 Kafka 积压）直接丢弃
+const typingTTL = 5 * time.Second
+
+// RelayTyping 将用户的输入状态推送给会话中其他成员的在线设备
+// sentAt 为 Gateway 收到信号的时间（Unix 秒），已超过有效期的信号直接丢弃
+func (s *Service) RelayTyping(ctx context.Context, userUUID, conversationID string, typing bool, sentAt int64) error {
+	now := time.Now()
+	if typingExpired(sentAt, now) {
+		return nil
+	}
+
+	rel, err := s.relRepo.GetRelationByConversation(ctx, userUUID, conversationID)
+	if err != nil {
+		return ErrNotMember
+	}
+	members, err := s.conversationMembers(ctx, rel)
+	if err != nil {
+		return err
+	}
+
+	userName, _ := s.userRepo.GetUsernameByUUID(ctx, userUUID)
+	event := &pb.TypingEvent{
+		ConversationID: conversationID,
+		UserUUID:       userUUID,
+		UserName:       userName,
+		Typing:         typing,
+		ExpireAt:       now.Add(typingTTL).Unix(),
+	}
+	for _, memberUUID := range members {
+		if memberUUID == userUUID {
+			continue
+		}
+		pushMsg, err := newEventMessage(conversationID, memberUUID, int32(rel.Type), pb.EventTypeTyping, event)
+		if err != nil {
+			return err
+		}
+		s.routeToUser(memberUUID, pushMsg, false)
+	}
+	return nil
+}
+
+// typingExpired 信号是否已超过有效期；没有时间戳的信号按未过期处理
+func typingExpired(sentAt int64, now time.Time) bool {
+	return sentAt > 0 && now.Sub(time.Unix(sentAt, 0)) > typingTTL
+}
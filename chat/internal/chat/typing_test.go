@@ -0,0 +1,19 @@
+package chat
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestTypingExpired 测试超过有效期的正在输入信号被丢弃
+func TestTypingExpired(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	assert.False(t, typingExpired(now.Unix(), now))
+	assert.False(t, typingExpired(now.Add(-typingTTL).Unix(), now))
+	assert.True(t, typingExpired(now.Add(-typingTTL-time.Second).Unix(), now))
+	// 没有时间戳的信号按未过期处理
+	assert.False(t, typingExpired(0, now))
+}
@@ -0,0 +1,180 @@
+package chat
+
+import (
+	"MyGoChat/pkg/log"
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// 未读数缓存：Hash "unread:{userUUID}"，field 为会话ID，value 为未读数
+// 新消息到达时增量 +1，推进已读游标时重置为已读位置之后实际入库的消息数。
+// 缓存中缺失的会话按需从 MongoDB 计算并回填，因此 key 过期或丢失都不会导致错误计数。
+// 未读数不按 MaxSeq - LastReadSeq 计算：重复消息与入库失败的消息也会占用序号，留下的空洞不应计入未读
+//
+// 统计与写入缓存之间入库的消息可能已被统计，随后又触发增量。Hash "unread_seq:{userUUID}" 记录每个会话
+// 写入未读数时已统计到的序号（已读位置与统计到的最大序号中较大者），增量跳过不超过该序号的消息，避免重复计数
+const (
+	unreadKeyPrefix    = "unread:"
+	unreadSeqKeyPrefix = "unread_seq:"
+	unreadKeyTTL       = time.Hour * 24 * 7
+)
+
+// incrUnreadScript 仅在 field 已存在、且消息序号大于已统计到的序号时自增
+// field 不存在时说明尚未从 MongoDB 计算过，留给下次读取时回填
+// KEYS: unread, unread_seq  ARGV: conversationID, seq
+var incrUnreadScript = redis.NewScript(`
+if redis.call("HEXISTS", KEYS[1], ARGV[1]) == 0 then
+	return -1
+end
+local counted = tonumber(redis.call("HGET", KEYS[2], ARGV[1]) or "0")
+if tonumber(ARGV[2]) <= counted then
+	return -1
+end
+return redis.call("HINCRBY", KEYS[1], ARGV[1], 1)
+`)
+
+// backfillUnreadScript 回填未读数，只写入仍然缺失的 field，返回各会话最终缓存的未读数
+// 回填期间已读游标推进或其他请求先完成回填时保留已有的值
+// KEYS: unread, unread_seq  ARGV: ttl(秒), 之后每三个一组 conversationID, count, countedSeq
+var backfillUnreadScript = redis.NewScript(`
+local counts = {}
+for i = 2, #ARGV, 3 do
+	if redis.call("HSETNX", KEYS[1], ARGV[i], ARGV[i + 1]) == 1 then
+		redis.call("HSET", KEYS[2], ARGV[i], ARGV[i + 2])
+	end
+	table.insert(counts, redis.call("HGET", KEYS[1], ARGV[i]))
+end
+redis.call("EXPIRE", KEYS[1], ARGV[1])
+redis.call("EXPIRE", KEYS[2], ARGV[1])
+return counts
+`)
+
+// incrUnread 用户收到一条新消息，会话未读数 +1
+func (s *Service) incrUnread(ctx context.Context, userUUID, conversationID string, seq int64) {
+	keys := []string{unreadKeyPrefix + userUUID, unreadSeqKeyPrefix + userUUID}
+	if err := incrUnreadScript.Run(ctx, s.redis, keys, conversationID, seq).Err(); err != nil {
+		log.Logger.Sugar().Warnf("Failed to incr unread for user %s: %v", userUUID, err)
+	}
+}
+
+// setUnread 直接设置用户在会话中的未读数及其统计到的序号（已读游标推进后调用）
+func (s *Service) setUnread(ctx context.Context, userUUID, conversationID string, count, countedSeq int64) {
+	if count < 0 {
+		count = 0
+	}
+	key, seqKey := unreadKeyPrefix+userUUID, unreadSeqKeyPrefix+userUUID
+	_, err := s.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, key, conversationID, count)
+		pipe.HSet(ctx, seqKey, conversationID, countedSeq)
+		pipe.Expire(ctx, key, unreadKeyTTL)
+		pipe.Expire(ctx, seqKey, unreadKeyTTL)
+		return nil
+	})
+	if err != nil {
+		log.Logger.Sugar().Warnf("Failed to set unread for user %s: %v", userUUID, err)
+	}
+}
+
+// unreadAfter 计算已读到 seq 之后会话中剩余的未读数以及统计到的序号，读到最新时不必查询
+func (s *Service) unreadAfter(ctx context.Context, conv *Conversation, seq int64) (int64, int64) {
+	if seq >= conv.MaxSeq {
+		return 0, seq
+	}
+	counts, err := s.repo.CountUnread(ctx, map[string]int64{conv.ID: seq})
+	if err != nil {
+		log.Logger.Sugar().Warnf("Failed to count unread in %s: %v", conv.ID, err)
+		return conv.MaxSeq - seq, conv.MaxSeq
+	}
+	unread := counts[conv.ID]
+	return unread.Count, max(seq, unread.MaxSeq)
+}
+
+// loadUnreadCounts 获取用户在多个会话中的未读数
+// 优先读 Redis 缓存，缺失的会话按已读游标统计入库的消息后回填
+func (s *Service) loadUnreadCounts(ctx context.Context, userUUID string, convIDs []string) (map[string]int64, error) {
+	counts := make(map[string]int64, len(convIDs))
+	if len(convIDs) == 0 {
+		return counts, nil
+	}
+
+	key := unreadKeyPrefix + userUUID
+	cached, err := s.redis.HMGet(ctx, key, convIDs...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []string
+	for i, v := range cached {
+		str, ok := v.(string)
+		if !ok {
+			missing = append(missing, convIDs[i])
+			continue
+		}
+		n, err := strconv.ParseInt(str, 10, 64)
+		if err != nil {
+			missing = append(missing, convIDs[i])
+			continue
+		}
+		counts[convIDs[i]] = n
+	}
+
+	if len(missing) == 0 {
+		return counts, nil
+	}
+
+	readSeqs, err := s.repo.GetUserReadSeqs(ctx, userUUID, missing)
+	if err != nil {
+		return nil, err
+	}
+	since := make(map[string]int64, len(missing))
+	for _, convID := range missing {
+		since[convID] = readSeqs[convID] // 没有已读游标的会话从头统计
+	}
+	unread, err := s.repo.CountUnread(ctx, since)
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]interface{}, 0, 1+3*len(missing))
+	args = append(args, int(unreadKeyTTL.Seconds()))
+	for _, convID := range missing {
+		counts[convID] = unread[convID].Count
+		args = append(args, convID, unread[convID].Count, max(since[convID], unread[convID].MaxSeq))
+	}
+
+	keys := []string{key, unreadSeqKeyPrefix + userUUID}
+	stored, err := backfillUnreadScript.Run(ctx, s.redis, keys, args...).StringSlice()
+	if err != nil {
+		log.Logger.Sugar().Warnf("Failed to backfill unread for user %s: %v", userUUID, err)
+		return counts, nil
+	}
+	for i, convID := range missing {
+		if n, err := strconv.ParseInt(stored[i], 10, 64); err == nil {
+			counts[convID] = n
+		}
+	}
+
+	return counts, nil
+}
+
+// GetUnreadSummary 获取用户所有会话的未读数以及总未读数
+func (s *Service) GetUnreadSummary(ctx context.Context, userUUID string) (int64, map[string]int64, error) {
+	convIDs, err := s.relRepo.GetUserConversationIDs(ctx, userUUID)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	counts, err := s.loadUnreadCounts(ctx, userUUID, convIDs)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var total int64
+	for _, n := range counts {
+		total += n
+	}
+	return total, counts, nil
+}
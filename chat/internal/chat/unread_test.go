@@ -0,0 +1,113 @@
+package chat
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// TestUnreadCounts 测试未读数缓存：缺失时按已读游标统计入库消息回填，新消息只在已回填时自增，读到最新后归零
+func TestUnreadCounts(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(MockRepository)
+	mockRepo.On("GetUserReadSeqs", mock.Anything, "reader", []string{"conv-1"}).
+		Return(map[string]int64{"conv-1": 6}, nil).Once()
+	mockRepo.On("CountUnread", mock.Anything, map[string]int64{"conv-1": 6}).
+		Return(map[string]UnreadCount{"conv-1": {Count: 4, MaxSeq: 10}}, nil).Once()
+	service, mr := newReadTestService(t, mockRepo)
+
+	// 缓存缺失时不自增，避免在错误的基数上累加
+	service.incrUnread(ctx, "reader", "conv-1", 10)
+	assert.False(t, mr.Exists(unreadKeyPrefix+"reader"))
+
+	counts, err := service.loadUnreadCounts(ctx, "reader", []string{"conv-1"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), counts["conv-1"])
+
+	service.incrUnread(ctx, "reader", "conv-1", 11)
+	counts, err = service.loadUnreadCounts(ctx, "reader", []string{"conv-1"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), counts["conv-1"], "cached count is served without hitting MongoDB")
+
+	mockRepo.On("GetConversationByID", mock.Anything, "conv-1").
+		Return(&Conversation{ID: "conv-1", Type: 2, MaxSeq: 11}, nil)
+	mockRepo.On("AdvanceReadCursor", mock.Anything, "reader", "conv-1", int64(11), "").
+		Return(int64(6), true, nil)
+	mockRepo.On("GetSendersInRange", mock.Anything, "conv-1", int64(6), int64(11)).
+		Return(map[string]int64{}, nil)
+
+	_, err = service.MarkConversationRead(ctx, "reader", "conv-1", 11, "")
+	assert.NoError(t, err)
+	counts, err = service.loadUnreadCounts(ctx, "reader", []string{"conv-1"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), counts["conv-1"])
+
+	mockRepo.AssertExpectations(t)
+}
+
+// TestUnreadCounts_SkipsSeqGaps 测试重复或入库失败的消息留下的序号空洞不计入未读
+func TestUnreadCounts_SkipsSeqGaps(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(MockRepository)
+	mockRepo.On("GetConversationByID", mock.Anything, "conv-1").
+		Return(&Conversation{ID: "conv-1", Type: 2, MaxSeq: 10}, nil)
+	mockRepo.On("AdvanceReadCursor", mock.Anything, "reader", "conv-1", int64(7), "").
+		Return(int64(2), true, nil)
+	mockRepo.On("GetSendersInRange", mock.Anything, "conv-1", int64(2), int64(7)).
+		Return(map[string]int64{}, nil)
+	// 序号 8、9 没有入库，只剩序号 10 一条未读
+	mockRepo.On("CountUnread", mock.Anything, map[string]int64{"conv-1": 7}).
+		Return(map[string]UnreadCount{"conv-1": {Count: 1, MaxSeq: 10}}, nil)
+	mockRepo.On("GetUserReadSeqs", mock.Anything, "reader", []string{"conv-2"}).
+		Return(map[string]int64{}, nil)
+	mockRepo.On("CountUnread", mock.Anything, map[string]int64{"conv-2": 0}).
+		Return(map[string]UnreadCount{}, nil)
+	service, mr := newReadTestService(t, mockRepo)
+
+	_, err := service.MarkConversationRead(ctx, "reader", "conv-1", 7, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "1", mr.HGet(unreadKeyPrefix+"reader", "conv-1"))
+
+	// 没有已读游标、也没有入库消息的会话未读数为 0
+	counts, err := service.loadUnreadCounts(ctx, "reader", []string{"conv-2"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), counts["conv-2"])
+
+	mockRepo.AssertExpectations(t)
+}
+
+// TestUnreadCounts_NoDoubleCount 测试统计期间入库的消息随后触发的增量不会重复计数，回填也不覆盖已有的值
+func TestUnreadCounts_NoDoubleCount(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(MockRepository)
+	mockRepo.On("GetUserReadSeqs", mock.Anything, "reader", []string{"conv-1", "conv-2"}).
+		Return(map[string]int64{"conv-1": 6, "conv-2": 3}, nil)
+	service, mr := newReadTestService(t, mockRepo)
+	// 序号 9 的消息在统计前已入库，已计入回填的未读数；
+	// 统计期间 conv-2 的已读游标被推进，写入了新的未读数
+	mockRepo.On("CountUnread", mock.Anything, map[string]int64{"conv-1": 6, "conv-2": 3}).
+		Run(func(mock.Arguments) { service.setUnread(ctx, "reader", "conv-2", 1, 8) }).
+		Return(map[string]UnreadCount{"conv-1": {Count: 3, MaxSeq: 9}}, nil)
+
+	counts, err := service.loadUnreadCounts(ctx, "reader", []string{"conv-1", "conv-2"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int64{"conv-1": 3, "conv-2": 1}, counts)
+	assert.Equal(t, "9", mr.HGet(unreadSeqKeyPrefix+"reader", "conv-1"))
+	assert.Equal(t, "8", mr.HGet(unreadSeqKeyPrefix+"reader", "conv-2"), "existing count and its seq are kept")
+
+	// 序号 9 的增量在回填之后才到达，已统计过，不再计数
+	service.incrUnread(ctx, "reader", "conv-1", 9)
+	assert.Equal(t, "3", mr.HGet(unreadKeyPrefix+"reader", "conv-1"))
+
+	service.incrUnread(ctx, "reader", "conv-1", 10)
+	assert.Equal(t, "4", mr.HGet(unreadKeyPrefix+"reader", "conv-1"))
+
+	// 推进已读游标时写入的序号同样生效
+	service.incrUnread(ctx, "reader", "conv-2", 8)
+	service.incrUnread(ctx, "reader", "conv-2", 9)
+	assert.Equal(t, "2", mr.HGet(unreadKeyPrefix+"reader", "conv-2"))
+
+	mockRepo.AssertExpectations(t)
+}
@@ -14,7 +14,7 @@ import (
 )
 
 type MemberAdder interface {
-	JoinGroupRelation(ctx context.Context, userUUID, groupUUID string) error
+	CreateGroupOwnerRelation(ctx context.Context, userUUID, groupUUID string) error
 }
 
 type Service struct {
@@ -50,8 +50,8 @@ func (s *Service) CreateGroup(group *Group, adminUserUuid string) error {
 		return err
 	}
 
-	// 将创建者添加为群成员
-	if err := s.relRepo.JoinGroupRelation(context.Background(), adminUser.Uuid, group.Uuid); err != nil {
+	// 将创建者添加为群主
+	if err := s.relRepo.CreateGroupOwnerRelation(context.Background(), adminUser.Uuid, group.Uuid); err != nil {
 		logger.Error("CreateGroup: failed to add admin user to group members")
 		return err
 	}
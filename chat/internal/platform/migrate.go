@@ -2,6 +2,7 @@ package platform
 
 import (
 	"MyGoChat/pkg/log"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -16,3 +17,47 @@ func AutoMigrate(db *gorm.DB, models ...interface{}) error {
 	log.Logger.Sugar().Info("数据库自动迁移成功")
 	return nil
 }
+
+// Migration 一次性的数据迁移（表结构由 AutoMigrate 负责），执行记录保存在 schema_migrations 表中
+type Migration struct {
+	ID string // 迁移的唯一标识，发布后不可修改
+	Up func(tx *gorm.DB) error
+}
+
+type schemaMigration struct {
+	ID        string `gorm:"type:varchar(64);primarykey"`
+	AppliedAt time.Time
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// RunMigrations 按顺序执行尚未执行过的数据迁移，每个迁移与其执行记录在同一事务中提交
+// 多个实例同时启动时，后提交的实例因主键冲突回滚，迁移只生效一次
+func RunMigrations(db *gorm.DB, migrations ...Migration) error {
+	if err := db.AutoMigrate(&schemaMigration{}); err != nil {
+		return err
+	}
+	for _, m := range migrations {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			var applied int64
+			if err := tx.Model(&schemaMigration{}).Where("id = ?", m.ID).Count(&applied).Error; err != nil {
+				return err
+			}
+			if applied > 0 {
+				return nil
+			}
+			if err := m.Up(tx); err != nil {
+				return err
+			}
+			log.Logger.Sugar().Infof("数据迁移 %s 执行成功", m.ID)
+			return tx.Create(&schemaMigration{ID: m.ID, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			log.Logger.Sugar().Errorf("数据迁移 %s 失败: %v", m.ID, err)
+			return err
+		}
+	}
+	return nil
+}
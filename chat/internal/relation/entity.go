@@ -11,6 +11,13 @@ const (
 	TypeGroup   = 2 // 群聊（群成员）
 )
 
+// 群聊关系的成员角色，对应 Relation.Status
+const (
+	GroupRoleMember = 0 // 普通成员
+	GroupRoleAdmin  = 1 // 管理员
+	GroupRoleOwner  = 2 // 群主
+)
+
 // Relation 是核心表，代表“我与某个人/群”的关系
 // 索引建议：(OwnerUUID, Type), (OwnerUUID, TargetUUID)
 type Relation struct {
@@ -42,3 +49,8 @@ type Relation struct {
 	CreatedAt time.Time
 	UpdatedAt time.Time
 }
+
+// IsGroupAdmin 是否为群管理员或群主
+func (r *Relation) IsGroupAdmin() bool {
+	return r.Type == TypeGroup && r.Status >= GroupRoleAdmin
+}
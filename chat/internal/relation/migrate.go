@@ -0,0 +1,25 @@
+package relation
+
+import (
+	"MyGoChat/chat/internal/platform"
+
+	"gorm.io/gorm"
+)
+
+// GroupRolesMigration 群聊关系的 Status 改为成员角色之前，群成员的 Status 都是默认值 1（现在表示管理员）
+// 迁移将已有的群成员重置为普通成员，并将群组的创建者（groups.admin_user_id）设为群主
+var GroupRolesMigration = platform.Migration{
+	ID: "relation_group_roles",
+	Up: func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&Relation{}).
+			Where("type = ?", TypeGroup).
+			Update("status", GroupRoleMember).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Model(&Relation{}).
+			Where(`type = ? AND EXISTS (
+				SELECT 1 FROM "groups" g JOIN users u ON u.id = g.admin_user_id
+				WHERE g.uuid = relations.target_uuid AND u.uuid = relations.user_uuid)`, TypeGroup).
+			Update("status", GroupRoleOwner).Error
+	},
+}
@@ -10,13 +10,22 @@ import (
 
 type Repository interface {
 	JoinGroupRelation(ctx context.Context, userUUID, groupUUID string) error
+	CreateGroupOwnerRelation(ctx context.Context, userUUID, groupUUID string) error
 	CreateFriendRelation(ctx context.Context, userUUID, friendUUID string) error
 	ListUserRelation(ctx context.Context, userUUID string) ([]*Relation, error)
 	GetGroupMemberUUIDs(ctx context.Context, groupUUID string) ([]string, error)
+	GetGroupMembers(ctx context.Context, groupUUID string) ([]*Relation, error)
 	GetUserConversationIDs(ctx context.Context, userUUID string) ([]string, error)
 	GetUserRelationsWithConversation(ctx context.Context, userUUID string, limit int) ([]*Relation, error)
+	GetRelationByConversation(ctx context.Context, userUUID, conversationID string) (*Relation, error)
+	GetFriendUUIDs(ctx context.Context, userUUID string) ([]string, error)
+	GetContactUUIDs(ctx context.Context, userUUID string, candidates []string) ([]string, error)
 }
 
+// activeRelation 有效关系的查询条件：私聊关系的 Status 是好友状态，只有 1=正常 有效；
+// 群聊关系（type = 2）的 Status 是成员角色，任何角色都是群成员
+const activeRelation = "(type = 2 OR status = 1)"
+
 type repository struct {
 	db *gorm.DB
 }
@@ -26,15 +35,25 @@ func NewRelationRepo(data *platform.Data) Repository {
 }
 
 func (r *repository) JoinGroupRelation(ctx context.Context, userUUID, groupUUID string) error {
+	return r.createGroupRelation(ctx, userUUID, groupUUID, GroupRoleMember)
+}
 
+// CreateGroupOwnerRelation 创建群组时将创建者加入为群主
+func (r *repository) CreateGroupOwnerRelation(ctx context.Context, userUUID, groupUUID string) error {
+	return r.createGroupRelation(ctx, userUUID, groupUUID, GroupRoleOwner)
+}
+
+func (r *repository) createGroupRelation(ctx context.Context, userUUID, groupUUID string, role int) error {
 	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		if err := tx.Create(&Relation{
-			UserUUID:       userUUID,
-			TargetUUID:     groupUUID,
-			Type:           TypeGroup,
-			Status:         1, // Active
-			ConversationID: groupUUID,
-		}).Error; err != nil {
+		// Status 带有 default:1，显式指定写入的字段，否则普通成员的零值角色会被默认值覆盖
+		if err := tx.Select("UserUUID", "TargetUUID", "Type", "Status", "ConversationID", "DeletedAt", "CreatedAt", "UpdatedAt").
+			Create(&Relation{
+				UserUUID:       userUUID,
+				TargetUUID:     groupUUID,
+				Type:           TypeGroup,
+				Status:         role,
+				ConversationID: groupUUID,
+			}).Error; err != nil {
 			return err
 		}
 		return nil
@@ -45,7 +64,7 @@ func (r *repository) ListUserRelation(ctx context.Context, userUUID string) ([]*
 
 	var relations []*Relation
 
-	if err := r.db.WithContext(ctx).Where("user_uuid = ? AND status = ?", userUUID, 1).Find(&relations).Error; err != nil {
+	if err := r.db.WithContext(ctx).Where("user_uuid = ? AND "+activeRelation, userUUID).Find(&relations).Error; err != nil {
 		return nil, err
 	}
 	return relations, nil
@@ -83,7 +102,7 @@ func (r *repository) GetGroupMemberUUIDs(ctx context.Context, groupUUID string)
 	var memberUUIDs []string
 	err := r.db.WithContext(ctx).
 		Model(&Relation{}).
-		Where("target_uuid = ? AND type = ? AND status = ?", groupUUID, TypeGroup, 1).
+		Where("target_uuid = ? AND type = ?", groupUUID, TypeGroup).
 		Pluck("user_uuid", &memberUUIDs).Error
 
 	if err != nil {
@@ -92,12 +111,25 @@ func (r *repository) GetGroupMemberUUIDs(ctx context.Context, groupUUID string)
 	return memberUUIDs, nil
 }
 
+// GetGroupMembers 获取群成员的关系记录（包含免打扰等个人设置）
+func (r *repository) GetGroupMembers(ctx context.Context, groupUUID string) ([]*Relation, error) {
+	var members []*Relation
+	err := r.db.WithContext(ctx).
+		Where("target_uuid = ? AND type = ?", groupUUID, TypeGroup).
+		Find(&members).Error
+
+	if err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
 // GetUserConversationIDs 获取用户的所有会话ID列表
 func (r *repository) GetUserConversationIDs(ctx context.Context, userUUID string) ([]string, error) {
 	var conversationIDs []string
 	err := r.db.WithContext(ctx).
 		Model(&Relation{}).
-		Where("user_uuid = ? AND status = ?", userUUID, 1).
+		Where("user_uuid = ? AND "+activeRelation, userUUID).
 		Pluck("conversation_id", &conversationIDs).Error
 
 	if err != nil {
@@ -110,7 +142,7 @@ func (r *repository) GetUserConversationIDs(ctx context.Context, userUUID string
 func (r *repository) GetUserRelationsWithConversation(ctx context.Context, userUUID string, limit int) ([]*Relation, error) {
 	var relations []*Relation
 	err := r.db.WithContext(ctx).
-		Where("user_uuid = ? AND status = ?", userUUID, 1).
+		Where("user_uuid = ? AND "+activeRelation, userUUID).
 		Order("updated_at DESC").
 		Limit(limit).
 		Find(&relations).Error
@@ -120,3 +152,54 @@ func (r *repository) GetUserRelationsWithConversation(ctx context.Context, userU
 	}
 	return relations, nil
 }
+
+// GetRelationByConversation 获取用户在指定会话中的关系记录，可用于校验用户是否为会话成员
+func (r *repository) GetRelationByConversation(ctx context.Context, userUUID, conversationID string) (*Relation, error) {
+	var relation Relation
+	err := r.db.WithContext(ctx).
+		Where("user_uuid = ? AND conversation_id = ?", userUUID, conversationID).
+		First(&relation).Error
+
+	if err != nil {
+		return nil, err
+	}
+	return &relation, nil
+}
+
+// GetFriendUUIDs 获取用户的好友（状态正常的私聊关系）
+func (r *repository) GetFriendUUIDs(ctx context.Context, userUUID string) ([]string, error) {
+	var friendUUIDs []string
+	err := r.db.WithContext(ctx).
+		Model(&Relation{}).
+		Where("user_uuid = ? AND type = ? AND status = 1", userUUID, TypePrivate).
+		Pluck("target_uuid", &friendUUIDs).Error
+
+	if err != nil {
+		return nil, err
+	}
+	return friendUUIDs, nil
+}
+
+// GetContactUUIDs 从 candidates 中筛选与用户同在一个会话的用户（好友或同一个群的成员）
+// 好友双方的私聊关系记录使用同一个会话ID，因此与群聊一样按会话ID匹配
+func (r *repository) GetContactUUIDs(ctx context.Context, userUUID string, candidates []string) ([]string, error) {
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+	conversations := r.db.Model(&Relation{}).
+		Select("conversation_id").
+		Where("user_uuid = ? AND "+activeRelation, userUUID)
+
+	var contactUUIDs []string
+	err := r.db.WithContext(ctx).
+		Model(&Relation{}).
+		Where("user_uuid IN ? AND "+activeRelation, candidates).
+		Where("conversation_id IN (?)", conversations).
+		Distinct().
+		Pluck("user_uuid", &contactUUIDs).Error
+
+	if err != nil {
+		return nil, err
+	}
+	return contactUUIDs, nil
+}
@@ -3,9 +3,9 @@ package server
 import (
 	"MyGoChat/chat/internal/chat"
 	"MyGoChat/chat/internal/group"
-	"MyGoChat/pkg/middleware"
 	"MyGoChat/chat/internal/relation"
 	"MyGoChat/chat/internal/user"
+	"MyGoChat/pkg/middleware"
 
 	"net/http"
 
@@ -44,6 +44,8 @@ func NewRouter(
 			{
 				info.PUT("/update", userHandler.Update) // 更新用户信息
 			}
+
+			user.GET("/presence", middleware.JWTAuthMiddleware(), userHandler.GetPresence) // 批量查询在线状态
 		}
 		// hostname/api/group
 		group := api.Group("/group")
@@ -57,11 +59,30 @@ func NewRouter(
 		{
 			message.Use(middleware.JWTAuthMiddleware())
 			message.POST("/send", chatHandler.SendMessage)                               // 发送消息（HTTP）
+			message.POST("/forward", chatHandler.Forward)                                // 转发消息
+			message.POST("/scheduled", chatHandler.Schedule)                             // 创建定时消息
+			message.GET("/scheduled", chatHandler.ListScheduled)                         // 获取待发送的定时消息
+			message.PUT("/scheduled/:id", chatHandler.UpdateScheduled)                   // 修改定时消息
+			message.DELETE("/scheduled/:id", chatHandler.CancelScheduled)                // 取消定时消息
 			message.GET("/history/:conversationId", chatHandler.GetMessageHistory)       // 获取历史消息
 			message.POST("/sync-offline", chatHandler.SyncOfflineMessages)               // 同步离线消息
+			message.POST("/offline/ack", chatHandler.AckOffline)                         // 确认已收到离线消息
 			message.GET("/conversations", chatHandler.GetConversations)                  // 获取会话列表
 			message.POST("/conversation/private", chatHandler.CreatePrivateConversation) // 创建私聊会话
+			message.PUT("/conversation/:conversationId/ttl", chatHandler.SetMessageTTL)  // 修改消息自动删除设置
 			message.POST("/read", chatHandler.MarkAsRead)                                // 标记消息已读
+			message.GET("/read/:conversationId", chatHandler.GetReadReceipts)            // 获取会话已读状态
+			message.GET("/unread", chatHandler.GetUnread)                                // 获取未读数
+			message.POST("/recall", chatHandler.Recall)                                  // 撤回消息
+			message.POST("/edit", chatHandler.Edit)                                      // 编辑消息
+			message.GET("/revisions/:messageId", chatHandler.GetRevisions)               // 获取消息编辑历史
+			message.GET("/mentions", chatHandler.GetMentions)                            // 获取@我的消息
+			message.GET("/thread/:messageId", chatHandler.GetThread)                     // 获取话题回复
+			message.POST("/reaction", chatHandler.AddReaction)                           // 添加表情回应
+			message.DELETE("/reaction", chatHandler.RemoveReaction)                      // 取消表情回应
+			message.POST("/pin", chatHandler.Pin)                                        // 置顶消息
+			message.DELETE("/pin", chatHandler.Unpin)                                    // 取消置顶消息
+			message.GET("/pins/:conversationId", chatHandler.GetPins)                    // 获取会话的置顶消息
 		}
 
 		relations := api.Group("/relations")
@@ -3,7 +3,10 @@ package user
 import (
 	"MyGoChat/pkg/common/request"
 	"MyGoChat/pkg/common/response"
+	"MyGoChat/pkg/log"
+	"errors"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -103,3 +106,33 @@ func (h *Handler) Update(c *gin.Context) {
 	c.JSON(http.StatusOK, response.SuccessMsg(gin.H{"user": user}))
 
 }
+
+// GetPresence 批量查询用户的在线状态
+// 查询参数：uuids=uuid1,uuid2,...（最多 100 个）
+func (h *Handler) GetPresence(c *gin.Context) {
+	var uuids []string
+	for _, id := range strings.Split(c.Query("uuids"), ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			uuids = append(uuids, id)
+		}
+	}
+
+	viewerUUID := c.GetString("useruuid")
+	if viewerUUID == "" {
+		c.JSON(http.StatusUnauthorized, response.FailMsg("Unauthorized"))
+		return
+	}
+
+	list, err := h.service.GetPresence(c.Request.Context(), viewerUUID, uuids)
+	if err != nil {
+		if errors.Is(err, ErrTooManyUsers) {
+			c.JSON(http.StatusBadRequest, response.FailMsg("一次最多查询 100 个用户"))
+			return
+		}
+		log.Logger.Sugar().Errorf("GetPresence: failed to get presence: %v", err)
+		c.JSON(http.StatusInternalServerError, response.FailMsg("查询在线状态失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, response.SuccessMsg(gin.H{"presence": list}))
+}
@@ -3,6 +3,7 @@ package user
 import (
 	"MyGoChat/chat/internal/util"
 	"MyGoChat/pkg/log"
+	"MyGoChat/pkg/presence"
 	"MyGoChat/pkg/token"
 	"context"
 	"errors"
@@ -12,13 +13,19 @@ import (
 	"github.com/google/uuid"
 )
 
+// ContactFinder 查询与用户同在一个会话（好友或同一个群）的用户，由 relation 模块实现，避免循环依赖
+type ContactFinder interface {
+	GetContactUUIDs(ctx context.Context, userUUID string, candidates []string) ([]string, error)
+}
+
 type Service struct {
-	repo Repository
-	rdb  *redis.Client
+	repo     Repository
+	rdb      *redis.Client
+	contacts ContactFinder
 }
 
-func NewService(repo Repository, rdb *redis.Client) *Service {
-	return &Service{repo: repo, rdb: rdb}
+func NewService(repo Repository, rdb *redis.Client, contacts ContactFinder) *Service {
+	return &Service{repo: repo, rdb: rdb, contacts: contacts}
 }
 
 func (s *Service) Register(user *User) (string, error) {
@@ -101,15 +108,46 @@ func (s *Service) GetUserByID(id uint) (*User, error) {
 	return s.repo.GetUserByID(id)
 }
 
-func (s *Service) GetUserStatus(userUUID string) (severID string, isOnline bool, err error) {
-	severID, err = s.rdb.HGet(s.rdb.Context(), "user_status", userUUID).Result()
+// 单次最多查询的用户数
+const maxPresenceBatch = 100
+
+var ErrTooManyUsers = errors.New("too many users in one presence query")
+
+// GetPresence 批量查询用户的在线状态与最后在线时间（由 Gateway 维护，见 pkg/presence）
+// 只有好友和同一个群的成员可以看到在线状态，其他用户一律显示为离线且没有最后在线时间；
+// 隐身的用户同样显示为离线，查询自己时返回真实状态
+func (s *Service) GetPresence(ctx context.Context, viewerUUID string, userUUIDs []string) ([]presence.Presence, error) {
+	if len(userUUIDs) > maxPresenceBatch {
+		return nil, ErrTooManyUsers
+	}
+	if len(userUUIDs) == 0 {
+		return []presence.Presence{}, nil
+	}
+
+	contactUUIDs, err := s.contacts.GetContactUUIDs(ctx, viewerUUID, userUUIDs)
+	if err != nil {
+		return nil, err
+	}
+	visible := make(map[string]bool, len(contactUUIDs)+1)
+	visible[viewerUUID] = true
+	for _, uuid := range contactUUIDs {
+		visible[uuid] = true
+	}
+
+	list, err := presence.GetMany(ctx, s.rdb, userUUIDs, time.Now())
 	if err != nil {
-		if errors.Is(err, redis.Nil) {
-			return "", false, nil
+		return nil, err
+	}
+	for i, p := range list {
+		switch {
+		case p.UserUUID == viewerUUID:
+		case visible[p.UserUUID]:
+			list[i] = p.Visible()
+		default:
+			list[i] = presence.Presence{UserUUID: p.UserUUID, Status: presence.StatusOffline}
 		}
-		return "", false, err
 	}
-	return severID, true, nil
+	return list, nil
 }
 
 func (s *Service) GetUserUUID(ctx context.Context, username string) (string, error) {
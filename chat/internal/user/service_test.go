@@ -0,0 +1,47 @@
+package user
+
+import (
+	"MyGoChat/pkg/presence"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubContacts 固定的联系人列表
+type stubContacts map[string]bool
+
+func (c stubContacts) GetContactUUIDs(ctx context.Context, userUUID string, candidates []string) ([]string, error) {
+	var contacts []string
+	for _, uuid := range candidates {
+		if c[uuid] {
+			contacts = append(contacts, uuid)
+		}
+	}
+	return contacts, nil
+}
+
+// TestGetPresence_OnlyContacts 测试只有好友和同群成员能看到在线状态，其他用户显示为离线且没有最后在线时间
+func TestGetPresence_OnlyContacts(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	now := time.Now()
+	for _, uuid := range []string{"viewer", "friend", "stranger"} {
+		require.NoError(t, presence.SetDevice(ctx, rdb, uuid, "web", presence.StatusOnline, now))
+	}
+
+	service := NewService(nil, rdb, stubContacts{"friend": true})
+	list, err := service.GetPresence(ctx, "viewer", []string{"viewer", "friend", "stranger"})
+	require.NoError(t, err)
+	require.Len(t, list, 3)
+
+	assert.Equal(t, presence.StatusOnline, list[0].Status)
+	assert.Equal(t, presence.StatusOnline, list[1].Status)
+	assert.NotZero(t, list[1].LastSeen)
+	assert.Equal(t, presence.Presence{UserUUID: "stranger", Status: presence.StatusOffline}, list[2])
+}
@@ -21,7 +21,7 @@ func NewGatewayRouter(hub *socket.Hub) *gin.Engine {
 
 	// 添加健康检查端点
 	r.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{"status": "ok", "service": "gateway"})
+		c.JSON(200, gin.H{"status": "ok", "service": "gateway", "delivery": hub.Stats()})
 	})
 
 	return r
@@ -4,8 +4,10 @@ import (
 	pb "MyGoChat/pkg/api/v1"
 	"MyGoChat/pkg/config"
 	"MyGoChat/pkg/log"
+	"MyGoChat/pkg/presence"
 	"encoding/json"
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -15,18 +17,50 @@ import (
 
 const (
 	// 心跳相关常量
-	writeWait      = 10 * time.Second    // 写操作超时时间
-	pongWait       = 60 * time.Second    // 等待 pong 消息的超时时间
-	pingPeriod     = (pongWait * 9) / 10 // ping 消消息发送周期，必须小于 pongWait
-	maxMessageSize = 512                 // 最大消息大小
+	writeWait  = 10 * time.Second    // 写操作超时时间
+	pongWait   = 60 * time.Second    // 等待 pong 消息的超时时间
+	pingPeriod = (pongWait * 9) / 10 // ping 消消息发送周期，必须小于 pongWait
+
+	// 上行帧的最大字节数，超过时连接被关闭
+	// 一帧是一条命令或聊天消息，需容纳 JSON 编码的长文本消息连同引用、提及等元数据
+	maxMessageSize = 64 * 1024
+
+	// 慢消费者处理
+	sendBufferSize    = 256              // 每个连接发送队列的容量
+	slowConsumerGrace = 10 * time.Second // 发送队列持续满载超过该时间后断开连接
 )
 
 // Client 是一个中间人，代表一个连接到服务器的用户。
 type Client struct {
 	hub      *Hub
 	conn     *websocket.Conn
-	send     chan []byte
+	send     chan outbound
 	userUUID string
+	deviceID string      // 设备标识，同一用户的多个连接以此区分
+	format   frameFormat // 握手时协商的下行帧格式
+
+	typing map[string]typingState // 各会话最近一次转发的“正在输入”信号，用于限流
+	status presence.Status        // 设备的在线状态（online / away），心跳时刷新
+
+	slowSince atomic.Int64 // 发送队列开始满载的时间（UnixNano），0 表示发送正常
+}
+
+// outbound 发送队列中的一条下行帧
+// 同时保留原消息，连接因处理过慢被断开时可将队列中尚未写出的消息转存到离线队列
+type outbound struct {
+	frame []byte
+	msg   *pb.Message // Gateway 直接回复的 ACK / NACK 为 nil
+}
+
+// markSlow 记录发送队列满载，返回已经持续满载的时长
+func (c *Client) markSlow(now time.Time) time.Duration {
+	c.slowSince.CompareAndSwap(0, now.UnixNano())
+	return now.Sub(time.Unix(0, c.slowSince.Load()))
+}
+
+// recovered 发送成功时清除满载标记，之前处于满载状态时返回 true
+func (c *Client) recovered() bool {
+	return c.slowSince.Swap(0) != 0
 }
 
 // readPump 从 WebSocket 连接中读取消息并将其发送到Hub的kafka producer.
@@ -42,11 +76,10 @@ func (c *Client) readPump() {
 	c.conn.SetPongHandler(func(string) error {
 		c.conn.SetReadDeadline(time.Now().Add(pongWait))
 		log.Logger.Sugar().Debugf("Received pong from client: %s", c.userUUID)
+		c.heartbeat()
 		return nil
 	})
 
-	ingestTopic := config.GetConfig().Kafka.Topics.Ingest
-
 	for {
 		// 读取消息
 		_, messageBytes, err := c.conn.ReadMessage()
@@ -59,35 +92,59 @@ func (c *Client) readPump() {
 			break
 		}
 
-		// 尝试解析消息 - 支持 JSON 和 protobuf 两种格式
-		msg, err := c.parseMessage(messageBytes)
-		if err != nil {
-			log.Logger.Sugar().Errorf("Error parsing message: %v", err)
-			log.Logger.Sugar().Debugf("Raw message bytes: %s", string(messageBytes))
+		// 命令（JSON 控制帧或 protobuf 信封）在 handleCommand 中按类型路由
+		if c.handleCommand(messageBytes) {
 			continue
 		}
 
-		// 【安全关键】强制覆盖 SenderUUID
-		msg.SenderUUID = c.userUUID
-
-		// 序列化为protobuf发送给Kafka
-		serializedMsg, err := proto.Marshal(msg)
+		// 旧格式：整个帧是一条聊天消息，支持 JSON 和 protobuf 两种格式
+		msg, err := c.parseMessage(messageBytes)
 		if err != nil {
-			log.Logger.Sugar().Errorf("Error marshalling message: %v", err)
+			log.Logger.Sugar().Errorf("Error parsing message: %v", err)
+			log.Logger.Sugar().Debugf("Raw message bytes: %s", string(messageBytes))
+			// 能识别出 ClientMsgID 时直接回复 NACK，无需经过 Logic 服务
+			if msg != nil {
+				c.sendNack(msg, "invalid message format")
+			}
 			continue
 		}
 
-		// 发送到 Kafka Ingest Topic，由 Logic 服务消费处理
-		producer := c.hub.Producer
-		err = producer.SendMessage(ingestTopic, serializedMsg)
-		if err != nil {
-			log.Logger.Sugar().Errorf("Error sending message to Kafka: %v", err)
+		if err := c.ingest(msg); err != nil {
 			return
 		}
+	}
+}
+
+// ingest 将客户端发送的聊天消息写入 Kafka Ingest Topic，由 Logic 服务消费处理
+func (c *Client) ingest(msg *pb.Message) error {
+	// 【安全关键】强制覆盖 SenderUUID
+	msg.SenderUUID = c.userUUID
+	// 记录发出消息的设备，用于 ACK 回给该设备、回显时跳过该设备
+	msg.SenderDeviceID = c.deviceID
+	// 消息 ID 由服务端分配，客户端以 ClientMsgID 对应自己的消息
+	msg.Id = ""
+	// 转发来源与被回复消息的摘要由服务端生成，不接受客户端填写
+	if msg.Metadata != nil {
+		msg.Metadata.ForwardedFrom = nil
+		msg.Metadata.Reply = nil
+	}
 
-		log.Logger.Sugar().Debugf("Message sent to Kafka ingest topic, sender: %s, recipient: %s",
-			msg.SenderUUID, msg.RecipientUUID)
+	// 序列化为protobuf发送给Kafka
+	serializedMsg, err := proto.Marshal(msg)
+	if err != nil {
+		log.Logger.Sugar().Errorf("Error marshalling message: %v", err)
+		return err
 	}
+
+	err = c.hub.Producer.SendMessage(config.GetConfig().Kafka.Topics.Ingest, serializedMsg)
+	if err != nil {
+		log.Logger.Sugar().Errorf("Error sending message to Kafka: %v", err)
+		return err
+	}
+
+	log.Logger.Sugar().Debugf("Message sent to Kafka ingest topic, sender: %s, recipient: %s",
+		msg.SenderUUID, msg.RecipientUUID)
+	return nil
 }
 
 // writePump 将消息从集线器发送到 WebSocket 连接。
@@ -100,7 +157,7 @@ func (c *Client) writePump() {
 
 	for {
 		select {
-		case message, ok := <-c.send:
+		case out, ok := <-c.send:
 			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
 			if !ok {
 				// Hub 关闭了 channel
@@ -108,23 +165,9 @@ func (c *Client) writePump() {
 				return
 			}
 
-			w, err := c.conn.NextWriter(websocket.BinaryMessage)
-			if err != nil {
-				log.Logger.Sugar().Errorf("Error getting next writer: %v", err)
-				return
-			}
-
-			w.Write(message)
-
-			// 添加排队的聊天消息到当前的 WebSocket 消息
-			n := len(c.send)
-			for i := 0; i < n; i++ {
-				w.Write([]byte{'\n'})
-				w.Write(<-c.send)
-			}
-
-			if err := w.Close(); err != nil {
-				log.Logger.Sugar().Errorf("Error closing writer: %v", err)
+			// 每条消息单独一个 WebSocket 帧，帧类型由协商的格式决定
+			if err := c.conn.WriteMessage(c.format.messageType(), out.frame); err != nil {
+				log.Logger.Sugar().Errorf("Error writing message: %v", err)
 				return
 			}
 
@@ -138,6 +181,49 @@ func (c *Client) writePump() {
 	}
 }
 
+// sendNack 直接向当前连接回复发送失败
+func (c *Client) sendNack(msg *pb.Message, reason string) {
+	if msg.ClientMsgID == "" {
+		return
+	}
+	c.reply(pb.EventTypeNack, msg.ConversationID, msg.MessageType, &pb.Ack{
+		ClientMsgID:    msg.ClientMsgID,
+		ConversationID: msg.ConversationID,
+		Reason:         reason,
+	})
+}
+
+// reply 将 ACK / NACK 事件直接放入当前连接的发送队列，不经过 Logic 服务
+func (c *Client) reply(eventType int32, conversationID string, messageType int32, ack *pb.Ack) {
+	body, err := anypb.New(ack)
+	if err != nil {
+		return
+	}
+	frame, err := c.format.encode(&pb.Message{
+		ConversationID: conversationID,
+		RecipientUUID:  c.userUUID,
+		MessageType:    messageType,
+		EventType:      eventType,
+		SendAt:         time.Now().Unix(),
+		Body:           body,
+	})
+	if err != nil {
+		return
+	}
+
+	// 与 DispatchMessage 一样持有读锁发送，连接已被移除时发送通道可能已经关闭
+	c.hub.mu.RLock()
+	defer c.hub.mu.RUnlock()
+	if c.hub.clients[c.userUUID][c.deviceID] != c {
+		return
+	}
+	select {
+	case c.send <- outbound{frame: frame}:
+	default:
+		log.Logger.Sugar().Warnf("Client channel full, dropping reply %s for %s", ack.ClientMsgID, c.userUUID)
+	}
+}
+
 // parseMessage 解析消息，支持 JSON 和 protobuf 两种格式
 func (c *Client) parseMessage(messageBytes []byte) (*pb.Message, error) {
 	// 先尝试解析为 protobuf 格式
@@ -156,6 +242,7 @@ func (c *Client) parseJSONMessage(messageBytes []byte) (*pb.Message, error) {
 	// 定义临时结构体来解析 JSON
 	var jsonMsg struct {
 		ID             string      `json:"id"`
+		ClientMsgID    string      `json:"clientMsgID"`
 		ConversationID string      `json:"conversationID"`
 		SenderUUID     string      `json:"senderUUID"`
 		SendAt         int64       `json:"sendAt"`
@@ -165,6 +252,12 @@ func (c *Client) parseJSONMessage(messageBytes []byte) (*pb.Message, error) {
 		RecipientUUID  string      `json:"recipientUUID"`
 		SenderName     string      `json:"senderName"`
 		Avatar         string      `json:"avatar"`
+		Metadata       *struct {
+			ReplyToMsgID string   `json:"replyToMsgID"`
+			MentionUUIDs []string `json:"mentionUUIDs"`
+			MentionAll   bool     `json:"mentionAll"`
+			ThreadRootID string   `json:"threadRootID"`
+		} `json:"metadata"`
 	}
 
 	if err := json.Unmarshal(messageBytes, &jsonMsg); err != nil {
@@ -177,6 +270,7 @@ func (c *Client) parseJSONMessage(messageBytes []byte) (*pb.Message, error) {
 	// 转换为 protobuf 消息
 	msg := &pb.Message{
 		Id:             jsonMsg.ID,
+		ClientMsgID:    jsonMsg.ClientMsgID,
 		ConversationID: jsonMsg.ConversationID,
 		SenderUUID:     jsonMsg.SenderUUID,
 		SendAt:         jsonMsg.SendAt,
@@ -186,13 +280,22 @@ func (c *Client) parseJSONMessage(messageBytes []byte) (*pb.Message, error) {
 		SenderName:     jsonMsg.SenderName,
 		Avatar:         jsonMsg.Avatar,
 	}
+	if jsonMsg.Metadata != nil {
+		msg.Metadata = &pb.MessageMetadata{
+			ReplyToMsgID: jsonMsg.Metadata.ReplyToMsgID,
+			MentionUUIDs: jsonMsg.Metadata.MentionUUIDs,
+			MentionAll:   jsonMsg.Metadata.MentionAll,
+			ThreadRootID: jsonMsg.Metadata.ThreadRootID,
+		}
+	}
 
 	// 处理 body 字段 - 转换为 google.protobuf.Any
 	if jsonMsg.Body != nil {
 		anyBody, err := c.convertBodyToAny(jsonMsg.ContentType, jsonMsg.Body)
 		if err != nil {
 			log.Logger.Sugar().Errorf("Failed to convert body to Any: %v", err)
-			return nil, err
+			// 返回已解析的部分，便于调用方根据 ClientMsgID 回复 NACK
+			return msg, err
 		}
 		msg.Body = anyBody
 	}
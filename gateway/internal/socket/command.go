@@ -0,0 +1,335 @@
+package socket
+
+import (
+	pb "MyGoChat/pkg/api/v1"
+	"MyGoChat/pkg/log"
+	"MyGoChat/pkg/presence"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// 客户端命令，与聊天消息共用同一条 WebSocket 连接
+// JSON 客户端发送控制帧，protobuf 客户端发送字段相同的 pb.Envelope（二进制帧）：
+//   - {"type": "message", "requestID": "...", "message": {聊天消息}}
+//   - {"type": "offline_ack", "requestID": "...", "ids": ["...", "..."]}
+//   - {"type": "sync", "requestID": "...", "conversations": {"会话ID": 已有的最大序号}, "limit": 50}
+//   - {"type": "read", "requestID": "...", "conversationID": "...", "seq": 10, "messageID": "..."}
+//   - {"type": "recall", "requestID": "...", "messageID": "..."}
+//   - {"type": "edit", "requestID": "...", "messageID": "...", "content": "..."}
+//   - {"type": "react", "requestID": "...", "messageID": "...", "emoji": "👍", "remove": false}
+//   - {"type": "typing", "requestID": "...", "conversationID": "...", "stop": false}
+//   - {"type": "presence", "requestID": "...", "status": "online" | "away" | "invisible"}
+//   - {"type": "ping", "requestID": "..."}
+//
+// 带 requestID 的命令会收到以 requestID 为 clientMsgID 的 ACK 或 NACK：
+// typing / presence / ping 由 Gateway 本地处理并直接回复，其余命令转发给 Logic 服务，由 Logic 服务回复。
+// 没有 type 的帧按旧格式当作单条聊天消息处理
+const (
+	commandMessage    = "message"     // 发送聊天消息，经 Ingest Topic 入库，ACK / NACK 以消息的 clientMsgID 对应
+	commandOfflineAck = "offline_ack" // 确认已收到离线消息
+	commandSync       = "sync"        // 按序号增量同步，结果逐条推送，最后推送 SyncComplete 事件
+	commandRead       = "read"        // 推进会话已读位置，ACK 携带推进后的已读序号
+	commandRecall     = "recall"      // 撤回消息，成功时所有成员收到撤回事件，失败时本设备收到以 requestID 为 clientMsgID 的 NACK
+	commandEdit       = "edit"        // 编辑文本消息，成功时所有成员收到编辑事件，失败时同样返回 NACK
+	commandReact      = "react"       // 添加或取消表情回应，成功时在线成员收到回应事件，失败时同样返回 NACK
+	commandTyping     = "typing"      // 正在输入（stop=true 为停止输入），经信号 Topic 转发给会话其他在线成员，不入库
+	commandPresence   = "presence"    // 切换在线状态，状态变化时在线好友收到在线状态事件
+	commandPing       = "ping"        // 应用层心跳，Gateway 直接回复 ACK
+)
+
+// 同一连接在同一会话中两次“正在输入”信号的最小间隔，间隔内的重复信号直接丢弃；
+// 停止输入只在之前转发过开始输入时转发，因此交替发送开始与停止同样受该间隔限制
+const typingInterval = 2 * time.Second
+
+// 会话成员收到开始输入后，超过该时间（与 Logic 服务下发的 expireAt 一致）没有新状态会自动清除
+const typingExpire = 5 * time.Second
+
+// 每个连接最多同时记录的会话数；会话成员关系由 Logic 服务校验，Gateway 只限制记录的数量，
+// 超过时先清理已不影响转发的记录，仍然超过则丢弃新会话的开始输入
+const maxTypingConversations = 32
+
+// typingState 连接在一个会话中最近一次转发的“正在输入”信号
+type typingState struct {
+	sentAt time.Time // 最近一次转发开始输入的时间
+	active bool      // 已转发开始输入，尚未转发停止输入
+}
+
+var (
+	errUnknownCommand = errors.New("unknown command type")
+	errMissingPayload = errors.New("missing command payload")
+)
+
+type command struct {
+	Type           string           `json:"type"`
+	RequestID      string           `json:"requestID"`
+	Message        json.RawMessage  `json:"message"`
+	IDs            []string         `json:"ids"`
+	Conversations  map[string]int64 `json:"conversations"`
+	Limit          int              `json:"limit"`
+	Seq            int64            `json:"seq"`
+	MessageID      string           `json:"messageID"`
+	Content        string           `json:"content"`
+	Emoji          string           `json:"emoji"`
+	Remove         bool             `json:"remove"`
+	ConversationID string           `json:"conversationID"`
+	Stop           bool             `json:"stop"`
+	Status         string           `json:"status"`
+
+	msg *pb.Message // protobuf 信封携带的聊天消息
+}
+
+// handleCommand 尝试将帧解析为命令并处理；不是命令时返回 false，按旧格式当作单条聊天消息解析
+func (c *Client) handleCommand(messageBytes []byte) bool {
+	cmd, ok := decodeCommand(messageBytes)
+	if !ok {
+		return false
+	}
+	if err := c.execCommand(cmd); err != nil {
+		log.Logger.Sugar().Warnf("Command %s from %s/%s failed: %v", cmd.Type, c.userUUID, c.deviceID, err)
+		c.nackCommand(cmd.RequestID, err.Error())
+	}
+	return true
+}
+
+// decodeCommand 解析 JSON 控制帧或 protobuf 信封
+// JSON 帧只要带有 type 即为命令；二进制帧只有解析为信封且命令类型已知时才是命令，否则是旧格式的 pb.Message
+func decodeCommand(messageBytes []byte) (*command, bool) {
+	if len(messageBytes) == 0 {
+		return nil, false
+	}
+
+	if messageBytes[0] == '{' {
+		var cmd command
+		if err := json.Unmarshal(messageBytes, &cmd); err != nil || cmd.Type == "" {
+			return nil, false
+		}
+		return &cmd, true
+	}
+
+	var env pb.Envelope
+	if err := proto.Unmarshal(messageBytes, &env); err != nil || !knownCommand(env.Type) {
+		return nil, false
+	}
+	return commandFromEnvelope(&env), true
+}
+
+func knownCommand(t string) bool {
+	switch t {
+	case commandMessage, commandOfflineAck, commandSync, commandRead, commandRecall,
+		commandEdit, commandReact, commandTyping, commandPresence, commandPing:
+		return true
+	}
+	return false
+}
+
+// commandFromEnvelope 将 protobuf 信封转换为与 JSON 控制帧相同的命令；载荷与类型不符时视为缺少载荷
+func commandFromEnvelope(env *pb.Envelope) *command {
+	cmd := &command{Type: env.Type, RequestID: env.RequestID}
+	switch p := env.Payload.(type) {
+	case *pb.Envelope_Message:
+		cmd.msg = p.Message
+	case *pb.Envelope_OfflineAck:
+		cmd.IDs = p.OfflineAck.Ids
+	case *pb.Envelope_Sync:
+		cmd.Conversations = make(map[string]int64, len(p.Sync.Conversations))
+		for _, cursor := range p.Sync.Conversations {
+			cmd.Conversations[cursor.ConversationID] = cursor.Seq
+		}
+		cmd.Limit = int(p.Sync.Limit)
+	case *pb.Envelope_Read:
+		cmd.ConversationID = p.Read.ConversationID
+		cmd.Seq = p.Read.Seq
+		cmd.MessageID = p.Read.MessageID
+	case *pb.Envelope_Recall:
+		cmd.MessageID = p.Recall.MessageID
+	case *pb.Envelope_Edit:
+		cmd.MessageID = p.Edit.MessageID
+		cmd.Content = p.Edit.Content
+	case *pb.Envelope_React:
+		cmd.MessageID = p.React.MessageID
+		cmd.Emoji = p.React.Emoji
+		cmd.Remove = p.React.Remove
+	case *pb.Envelope_Typing:
+		cmd.ConversationID = p.Typing.ConversationID
+		cmd.Stop = p.Typing.Stop
+	case *pb.Envelope_Presence:
+		cmd.Status = p.Presence.Status
+	}
+	return cmd
+}
+
+// execCommand 按类型处理命令：本地处理的命令在这里回复 ACK，其余命令转发给 Logic 服务
+// 返回的错误由调用方以 NACK 回复
+func (c *Client) execCommand(cmd *command) error {
+	switch cmd.Type {
+	case commandMessage:
+		msg := cmd.msg
+		if msg == nil {
+			if len(cmd.Message) == 0 {
+				return errMissingPayload
+			}
+			var err error
+			if msg, err = c.parseJSONMessage(cmd.Message); err != nil {
+				return errors.New("invalid message format")
+			}
+		}
+		if msg.ClientMsgID == "" {
+			msg.ClientMsgID = cmd.RequestID
+		}
+		return c.ingest(msg)
+	case commandOfflineAck:
+		if len(cmd.IDs) == 0 {
+			return errMissingPayload
+		}
+		c.hub.sendSyncRequest(map[string]interface{}{
+			"action":    "offline_ack",
+			"useruuid":  c.userUUID,
+			"deviceid":  c.deviceID,
+			"requestID": cmd.RequestID,
+			"ids":       cmd.IDs,
+		})
+	case commandSync:
+		c.hub.sendSyncRequest(map[string]interface{}{
+			"action":        "sync",
+			"useruuid":      c.userUUID,
+			"deviceid":      c.deviceID,
+			"requestID":     cmd.RequestID,
+			"conversations": cmd.Conversations,
+			"limit":         cmd.Limit,
+		})
+	case commandRead:
+		if cmd.ConversationID == "" {
+			return errMissingPayload
+		}
+		c.hub.sendSyncRequest(map[string]interface{}{
+			"action":         "read",
+			"useruuid":       c.userUUID,
+			"deviceid":       c.deviceID,
+			"requestID":      cmd.RequestID,
+			"conversationID": cmd.ConversationID,
+			"seq":            cmd.Seq,
+			"messageID":      cmd.MessageID,
+		})
+	case commandRecall:
+		if cmd.MessageID == "" {
+			return errMissingPayload
+		}
+		c.hub.sendSyncRequest(map[string]interface{}{
+			"action":    "recall",
+			"useruuid":  c.userUUID,
+			"deviceid":  c.deviceID,
+			"requestID": cmd.RequestID,
+			"messageID": cmd.MessageID,
+		})
+	case commandEdit:
+		if cmd.MessageID == "" {
+			return errMissingPayload
+		}
+		c.hub.sendSyncRequest(map[string]interface{}{
+			"action":    "edit",
+			"useruuid":  c.userUUID,
+			"deviceid":  c.deviceID,
+			"requestID": cmd.RequestID,
+			"messageID": cmd.MessageID,
+			"content":   cmd.Content,
+		})
+	case commandReact:
+		if cmd.MessageID == "" {
+			return errMissingPayload
+		}
+		c.hub.sendSyncRequest(map[string]interface{}{
+			"action":    "reaction",
+			"useruuid":  c.userUUID,
+			"deviceid":  c.deviceID,
+			"requestID": cmd.RequestID,
+			"messageID": cmd.MessageID,
+			"emoji":     cmd.Emoji,
+			"remove":    cmd.Remove,
+		})
+	case commandTyping:
+		if cmd.ConversationID == "" {
+			return errMissingPayload
+		}
+		if c.allowTyping(cmd.ConversationID, !cmd.Stop, time.Now()) {
+			c.hub.sendSignal(cmd.ConversationID, map[string]interface{}{
+				"action":         "typing",
+				"useruuid":       c.userUUID,
+				"conversationID": cmd.ConversationID,
+				"typing":         !cmd.Stop,
+			})
+		}
+		c.ackCommand(cmd.RequestID)
+	case commandPresence:
+		if err := c.setPresence(presence.Status(cmd.Status)); err != nil {
+			return err
+		}
+		c.ackCommand(cmd.RequestID)
+	case commandPing:
+		c.ackCommand(cmd.RequestID)
+	default:
+		return errUnknownCommand
+	}
+	return nil
+}
+
+// ackCommand 回复 Gateway 本地处理成功的命令，requestID 作为 ClientMsgID 返回
+func (c *Client) ackCommand(requestID string) {
+	if requestID == "" {
+		return
+	}
+	c.reply(pb.EventTypeAck, "", 0, &pb.Ack{ClientMsgID: requestID})
+}
+
+// nackCommand 回复处理失败的命令，requestID 作为 ClientMsgID 返回
+func (c *Client) nackCommand(requestID, reason string) {
+	if requestID == "" {
+		return
+	}
+	c.reply(pb.EventTypeNack, "", 0, &pb.Ack{ClientMsgID: requestID, Reason: reason})
+}
+
+// allowTyping 限制“正在输入”信号的频率：开始输入在同一会话的 typingInterval 内只转发一次，
+// 停止输入只在已转发开始输入、且尚未转发停止时转发；停止后计时保留到间隔结束，不能借交替发送绕过间隔
+// 只在 readPump 中调用，无需加锁
+func (c *Client) allowTyping(conversationID string, typing bool, now time.Time) bool {
+	state, ok := c.typing[conversationID]
+	if !typing {
+		if !ok || !state.active {
+			return false
+		}
+		if now.Sub(state.sentAt) >= typingInterval {
+			delete(c.typing, conversationID)
+		} else {
+			state.active = false
+			c.typing[conversationID] = state
+		}
+		return true
+	}
+	if ok && now.Sub(state.sentAt) < typingInterval {
+		return false
+	}
+	if !ok && len(c.typing) >= maxTypingConversations {
+		c.pruneTyping(now)
+		if len(c.typing) >= maxTypingConversations {
+			return false
+		}
+	}
+	if c.typing == nil {
+		c.typing = make(map[string]typingState)
+	}
+	c.typing[conversationID] = typingState{sentAt: now, active: true}
+	return true
+}
+
+// pruneTyping 删除不再影响转发的记录：已停止且过了限流间隔，或开始输入已超过成员端的自动清除时间
+func (c *Client) pruneTyping(now time.Time) {
+	for conversationID, state := range c.typing {
+		age := now.Sub(state.sentAt)
+		if age >= typingExpire || (!state.active && age >= typingInterval) {
+			delete(c.typing, conversationID)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package socket
+
+import (
+	pb "MyGoChat/pkg/api/v1"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+// TestAllowTyping 测试“正在输入”限流：开始输入在间隔内只转发一次，停止输入只在转发过开始输入后转发一次
+func TestAllowTyping(t *testing.T) {
+	c := &Client{}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// 没有开始过的停止输入不转发
+	assert.False(t, c.allowTyping("conv-1", false, start))
+
+	assert.True(t, c.allowTyping("conv-1", true, start))
+	assert.False(t, c.allowTyping("conv-1", true, start.Add(time.Second)))
+	assert.True(t, c.allowTyping("conv-2", true, start.Add(time.Second)), "conversations are limited separately")
+
+	// 交替发送开始与停止：间隔内只转发一次停止，不能借停止重置计时
+	assert.True(t, c.allowTyping("conv-1", false, start.Add(time.Second)))
+	assert.False(t, c.allowTyping("conv-1", true, start.Add(time.Second)))
+	assert.False(t, c.allowTyping("conv-1", false, start.Add(time.Second)))
+
+	assert.True(t, c.allowTyping("conv-1", true, start.Add(typingInterval)))
+	assert.True(t, c.allowTyping("conv-1", false, start.Add(typingInterval)))
+}
+
+// TestAllowTyping_Bounded 测试每个连接记录的会话数有上限，过期记录被清理后才接受新会话
+func TestAllowTyping_Bounded(t *testing.T) {
+	c := &Client{}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for i := 0; i < maxTypingConversations; i++ {
+		assert.True(t, c.allowTyping(fmt.Sprintf("conv-%d", i), true, start))
+	}
+	assert.False(t, c.allowTyping("conv-new", true, start.Add(typingInterval)), "new conversations are rejected at the limit")
+
+	// 已停止且过了限流间隔的记录可以清理
+	assert.True(t, c.allowTyping("conv-0", false, start.Add(time.Second)))
+	assert.True(t, c.allowTyping("conv-new", true, start.Add(typingInterval)))
+	assert.Len(t, c.typing, maxTypingConversations)
+
+	// 开始输入超过成员端的自动清除时间后同样可以清理
+	assert.True(t, c.allowTyping("conv-other", true, start.Add(typingExpire)))
+	assert.Len(t, c.typing, 2)
+
+	// 过了限流间隔的停止输入直接删除记录
+	assert.True(t, c.allowTyping("conv-other", false, start.Add(typingExpire+typingInterval)))
+	assert.NotContains(t, c.typing, "conv-other")
+}
+
+// marshal 序列化测试用的 protobuf 帧
+func marshal(t *testing.T, m proto.Message) []byte {
+	data, err := proto.Marshal(m)
+	require.NoError(t, err)
+	return data
+}
+
+// TestDecodeCommand 测试帧的识别：带 type 的 JSON 帧与命令类型已知的信封是命令，其余按旧格式当作聊天消息
+func TestDecodeCommand(t *testing.T) {
+	tests := []struct {
+		name  string
+		frame []byte
+		ok    bool
+		want  *command
+	}{
+		{
+			name:  "json command",
+			frame: []byte(`{"type": "read", "requestID": "r1", "conversationID": "conv-1", "seq": 10, "messageID": "m1"}`),
+			ok:    true,
+			want:  &command{Type: commandRead, RequestID: "r1", ConversationID: "conv-1", Seq: 10, MessageID: "m1"},
+		},
+		{
+			name:  "json sync",
+			frame: []byte(`{"type": "sync", "conversations": {"conv-1": 5, "conv-2": 0}, "limit": 50}`),
+			ok:    true,
+			want:  &command{Type: commandSync, Conversations: map[string]int64{"conv-1": 5, "conv-2": 0}, Limit: 50},
+		},
+		{
+			name:  "json unknown type is still a command",
+			frame: []byte(`{"type": "shout"}`),
+			ok:    true,
+			want:  &command{Type: "shout"},
+		},
+		{
+			name:  "legacy json message without type",
+			frame: []byte(`{"conversationID": "conv-1", "content": "hi"}`),
+		},
+		{
+			name:  "invalid json",
+			frame: []byte(`{"type": "read"`),
+		},
+		{
+			name:  "empty frame",
+			frame: nil,
+		},
+		{
+			name: "envelope",
+			frame: marshal(t, &pb.Envelope{Type: commandRecall, RequestID: "r2", Payload: &pb.Envelope_Recall{
+				Recall: &pb.RecallCommand{MessageID: "m1"},
+			}}),
+			ok:   true,
+			want: &command{Type: commandRecall, RequestID: "r2", MessageID: "m1"},
+		},
+		{
+			name:  "envelope without payload",
+			frame: marshal(t, &pb.Envelope{Type: commandPing, RequestID: "r3"}),
+			ok:    true,
+			want:  &command{Type: commandPing, RequestID: "r3"},
+		},
+		{
+			name:  "envelope with unknown type",
+			frame: marshal(t, &pb.Envelope{Type: "shout", RequestID: "r4"}),
+		},
+		{
+			name:  "legacy protobuf message",
+			frame: marshal(t, &pb.Message{ConversationID: "conv-1", ClientMsgID: "c1"}),
+		},
+		{
+			name:  "garbage binary",
+			frame: []byte{0xff, 0xff, 0xff},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd, ok := decodeCommand(tt.frame)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.want, cmd)
+		})
+	}
+}
+
+// TestCommandFromEnvelope 测试信封各类载荷与 JSON 控制帧字段的对应关系
+func TestCommandFromEnvelope(t *testing.T) {
+	msg := &pb.Message{ConversationID: "conv-1", ClientMsgID: "c1"}
+
+	tests := []struct {
+		name string
+		env  *pb.Envelope
+		want *command
+	}{
+		{
+			name: "message",
+			env:  &pb.Envelope{Type: commandMessage, RequestID: "r1", Payload: &pb.Envelope_Message{Message: msg}},
+			want: &command{Type: commandMessage, RequestID: "r1", msg: msg},
+		},
+		{
+			name: "offline ack",
+			env: &pb.Envelope{Type: commandOfflineAck, Payload: &pb.Envelope_OfflineAck{
+				OfflineAck: &pb.OfflineAckCommand{Ids: []string{"m1", "m2"}},
+			}},
+			want: &command{Type: commandOfflineAck, IDs: []string{"m1", "m2"}},
+		},
+		{
+			name: "sync",
+			env: &pb.Envelope{Type: commandSync, Payload: &pb.Envelope_Sync{Sync: &pb.SyncCommand{
+				Conversations: []*pb.SyncCursor{{ConversationID: "conv-1", Seq: 5}, {ConversationID: "conv-2"}},
+				Limit:         20,
+			}}},
+			want: &command{Type: commandSync, Conversations: map[string]int64{"conv-1": 5, "conv-2": 0}, Limit: 20},
+		},
+		{
+			name: "sync without cursors",
+			env:  &pb.Envelope{Type: commandSync, Payload: &pb.Envelope_Sync{Sync: &pb.SyncCommand{}}},
+			want: &command{Type: commandSync, Conversations: map[string]int64{}},
+		},
+		{
+			name: "read",
+			env: &pb.Envelope{Type: commandRead, Payload: &pb.Envelope_Read{
+				Read: &pb.ReadCommand{ConversationID: "conv-1", Seq: 10, MessageID: "m1"},
+			}},
+			want: &command{Type: commandRead, ConversationID: "conv-1", Seq: 10, MessageID: "m1"},
+		},
+		{
+			name: "recall",
+			env:  &pb.Envelope{Type: commandRecall, Payload: &pb.Envelope_Recall{Recall: &pb.RecallCommand{MessageID: "m1"}}},
+			want: &command{Type: commandRecall, MessageID: "m1"},
+		},
+		{
+			name: "edit",
+			env: &pb.Envelope{Type: commandEdit, Payload: &pb.Envelope_Edit{
+				Edit: &pb.EditCommand{MessageID: "m1", Content: "edited"},
+			}},
+			want: &command{Type: commandEdit, MessageID: "m1", Content: "edited"},
+		},
+		{
+			name: "react",
+			env: &pb.Envelope{Type: commandReact, Payload: &pb.Envelope_React{
+				React: &pb.ReactCommand{MessageID: "m1", Emoji: "👍", Remove: true},
+			}},
+			want: &command{Type: commandReact, MessageID: "m1", Emoji: "👍", Remove: true},
+		},
+		{
+			name: "typing",
+			env: &pb.Envelope{Type: commandTyping, Payload: &pb.Envelope_Typing{
+				Typing: &pb.TypingCommand{ConversationID: "conv-1", Stop: true},
+			}},
+			want: &command{Type: commandTyping, ConversationID: "conv-1", Stop: true},
+		},
+		{
+			name: "presence",
+			env: &pb.Envelope{Type: commandPresence, Payload: &pb.Envelope_Presence{
+				Presence: &pb.PresenceCommand{Status: "away"},
+			}},
+			want: &command{Type: commandPresence, Status: "away"},
+		},
+		{
+			// 载荷与类型不符时只保留类型，执行时按缺少载荷处理
+			name: "mismatched payload",
+			env:  &pb.Envelope{Type: commandRead, Payload: &pb.Envelope_Recall{Recall: &pb.RecallCommand{MessageID: "m1"}}},
+			want: &command{Type: commandRead, MessageID: "m1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, commandFromEnvelope(tt.env))
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package socket
+
+import (
+	pb "MyGoChat/pkg/api/v1"
+)
+
+// convertEventBody 将事件消息的 Body 解包为前端友好的结构
+// 未知事件类型返回 nil，前端按 eventType 自行忽略
+func convertEventBody(msg *pb.Message) map[string]interface{} {
+	switch msg.EventType {
+	case pb.EventTypeReadReceipt:
+		var receipt pb.ReadReceipt
+		if err := msg.Body.UnmarshalTo(&receipt); err != nil {
+			return nil
+		}
+		return map[string]interface{}{
+			"conversationID": receipt.ConversationID,
+			"readerUUID":     receipt.ReaderUUID,
+			"seq":            receipt.Seq,
+			"readCount":      receipt.ReadCount,
+			"readAt":         receipt.ReadAt,
+		}
+	case pb.EventTypeAck, pb.EventTypeNack:
+		var ack pb.Ack
+		if err := msg.Body.UnmarshalTo(&ack); err != nil {
+			return nil
+		}
+		return map[string]interface{}{
+			"clientMsgID":    ack.ClientMsgID,
+			"messageID":      ack.MessageID,
+			"conversationID": ack.ConversationID,
+			"seq":            ack.Seq,
+			"sendAt":         ack.SendAt,
+			"reason":         ack.Reason,
+		}
+	case pb.EventTypeSyncComplete:
+		var complete pb.SyncComplete
+		if err := msg.Body.UnmarshalTo(&complete); err != nil {
+			return nil
+		}
+		conversations := make([]map[string]interface{}, 0, len(complete.Conversations))
+		for _, state := range complete.Conversations {
+			conversations = append(conversations, map[string]interface{}{
+				"conversationID": state.ConversationID,
+				"lastSeq":        state.LastSeq,
+				"maxSeq":         state.MaxSeq,
+				"hasMore":        state.HasMore,
+			})
+		}
+		return map[string]interface{}{
+			"requestID":     complete.RequestID,
+			"conversations": conversations,
+		}
+	case pb.EventTypeRecall:
+		var recall pb.MessageRecall
+		if err := msg.Body.UnmarshalTo(&recall); err != nil {
+			return nil
+		}
+		return map[string]interface{}{
+			"conversationID": recall.ConversationID,
+			"messageID":      recall.MessageID,
+			"seq":            recall.Seq,
+			"senderUUID":     recall.SenderUUID,
+			"operatorUUID":   recall.OperatorUUID,
+			"recalledAt":     recall.RecalledAt,
+		}
+	case pb.EventTypeEdit:
+		var edit pb.MessageEdit
+		if err := msg.Body.UnmarshalTo(&edit); err != nil {
+			return nil
+		}
+		var text pb.TextBody
+		if edit.Body != nil {
+			_ = edit.Body.UnmarshalTo(&text)
+		}
+		return map[string]interface{}{
+			"conversationID": edit.ConversationID,
+			"messageID":      edit.MessageID,
+			"seq":            edit.Seq,
+			"senderUUID":     edit.SenderUUID,
+			"content":        text.Content,
+			"editedAt":       edit.EditedAt,
+		}
+	case pb.EventTypeReaction:
+		var reaction pb.ReactionEvent
+		if err := msg.Body.UnmarshalTo(&reaction); err != nil {
+			return nil
+		}
+		return map[string]interface{}{
+			"conversationID": reaction.ConversationID,
+			"messageID":      reaction.MessageID,
+			"userUUID":       reaction.UserUUID,
+			"emoji":          reaction.Emoji,
+			"added":          reaction.Added,
+			"count":          reaction.Count,
+		}
+	case pb.EventTypeThread:
+		var update pb.ThreadUpdate
+		if err := msg.Body.UnmarshalTo(&update); err != nil {
+			return nil
+		}
+		return map[string]interface{}{
+			"conversationID":      update.ConversationID,
+			"rootMessageID":       update.RootMessageID,
+			"replyCount":          update.ReplyCount,
+			"lastReplyAt":         update.LastReplyAt,
+			"lastReplySenderUUID": update.LastReplySenderUUID,
+		}
+	case pb.EventTypePin:
+		var pin pb.PinEvent
+		if err := msg.Body.UnmarshalTo(&pin); err != nil {
+			return nil
+		}
+		return map[string]interface{}{
+			"conversationID": pin.ConversationID,
+			"messageID":      pin.MessageID,
+			"operatorUUID":   pin.OperatorUUID,
+			"pinned":         pin.Pinned,
+			"pinnedAt":       pin.PinnedAt,
+		}
+	case pb.EventTypeTyping:
+		var typing pb.TypingEvent
+		if err := msg.Body.UnmarshalTo(&typing); err != nil {
+			return nil
+		}
+		return map[string]interface{}{
+			"conversationID": typing.ConversationID,
+			"userUUID":       typing.UserUUID,
+			"userName":       typing.UserName,
+			"typing":         typing.Typing,
+			"expireAt":       typing.ExpireAt,
+		}
+	case pb.EventTypePresence:
+		var p pb.PresenceEvent
+		if err := msg.Body.UnmarshalTo(&p); err != nil {
+			return nil
+		}
+		return map[string]interface{}{
+			"userUUID": p.UserUUID,
+			"status":   p.Status,
+			"lastSeen": p.LastSeen,
+		}
+	}
+	return nil
+}
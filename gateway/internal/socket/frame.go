@@ -0,0 +1,54 @@
+package socket
+
+import (
+	pb "MyGoChat/pkg/api/v1"
+
+	"github.com/gorilla/websocket"
+	"google.golang.org/protobuf/proto"
+)
+
+// 下行帧格式，在 WebSocket 握手时通过子协议（Sec-WebSocket-Protocol）按连接协商：
+//   - mygochat.json      每个文本帧是一条 JSON 消息（见 convertProtoToJSON）
+//   - mygochat.protobuf  每个二进制帧是一条序列化的 pb.Message
+//
+// 客户端同时请求两者时优先使用 protobuf；未请求子协议的旧客户端按 JSON 处理。
+// 一个 WebSocket 帧只承载一条消息，客户端无需再按分隔符拆分。上行两种格式都接受
+const (
+	subprotocolJSON     = "mygochat.json"
+	subprotocolProtobuf = "mygochat.protobuf"
+)
+
+type frameFormat int
+
+const (
+	frameJSON frameFormat = iota
+	frameProtobuf
+)
+
+// formatOf 根据握手协商出的子协议确定下行帧格式
+func formatOf(subprotocol string) frameFormat {
+	if subprotocol == subprotocolProtobuf {
+		return frameProtobuf
+	}
+	return frameJSON
+}
+
+// messageType 返回该格式使用的 WebSocket 帧类型
+func (f frameFormat) messageType() int {
+	if f == frameProtobuf {
+		return websocket.BinaryMessage
+	}
+	return websocket.TextMessage
+}
+
+// encode 将消息编码为该格式的下行帧
+// protobuf 帧去掉仅供服务端路由使用的设备字段，与 JSON 帧保持一致
+func (f frameFormat) encode(msg *pb.Message) ([]byte, error) {
+	if f == frameJSON {
+		return convertProtoToJSON(msg)
+	}
+	out := proto.Clone(msg).(*pb.Message)
+	out.SenderDeviceID = ""
+	out.TargetDeviceID = ""
+	return proto.Marshal(out)
+}
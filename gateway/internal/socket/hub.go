@@ -5,9 +5,12 @@ import (
 	"MyGoChat/pkg/config"
 	myKafka "MyGoChat/pkg/kafka"
 	"MyGoChat/pkg/log"
+	"MyGoChat/pkg/offline"
+	"MyGoChat/pkg/route"
 	"context"
 	"encoding/json"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-redis/redis/v8"
@@ -16,24 +19,54 @@ import (
 )
 
 type Hub struct {
-	clients    map[string]*Client
+	// 本 Gateway 上的连接：userUUID -> deviceID -> Client
+	// 同一用户可以在多个设备上同时在线，同一设备重复连接时旧连接会被踢下线
+	clients    map[string]map[string]*Client
 	register   chan *Client
 	unregister chan *Client
+	evicted    chan *Client // 因处理过慢被断开的连接，由 Run 清理路由表和在线状态
 	mu         sync.RWMutex
 	Producer   *myKafka.Producer
 	redis      *redis.Client
 	gatewayID  string // 网关唯一标识
+	instanceID string // 本进程的实例ID，重启后变化，使崩溃前留下的路由不会复活
 	ctx        context.Context
+	stats      deliveryStats
+}
+
+// deliveryStats 慢消费者相关的计数，自 Gateway 启动起累计
+type deliveryStats struct {
+	spilled      atomic.Int64 // 发送队列已满、转存到离线队列的消息数
+	dropped      atomic.Int64 // 发送队列已满、未转存而丢弃的消息数（不可转存，或同一用户的其他设备已收到）
+	disconnected atomic.Int64 // 发送队列持续满载而被断开的连接数
+}
+
+// DeliveryStats 慢消费者计数的快照
+type DeliveryStats struct {
+	Spilled      int64 `json:"spilled"`
+	Dropped      int64 `json:"dropped"`
+	Disconnected int64 `json:"disconnected"`
+}
+
+// Stats 返回慢消费者计数
+func (h *Hub) Stats() DeliveryStats {
+	return DeliveryStats{
+		Spilled:      h.stats.spilled.Load(),
+		Dropped:      h.stats.dropped.Load(),
+		Disconnected: h.stats.disconnected.Load(),
+	}
 }
 
 func NewHub(producer *myKafka.Producer, redisClient *redis.Client, gatewayID string) *Hub {
 	return &Hub{
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
-		clients:    make(map[string]*Client),
+		evicted:    make(chan *Client),
+		clients:    make(map[string]map[string]*Client),
 		Producer:   producer,
 		redis:      redisClient,
 		gatewayID:  gatewayID,
+		instanceID: route.NewInstanceID(),
 		ctx:        context.Background(),
 	}
 }
@@ -55,40 +88,127 @@ func (h *Hub) DispatchMessage(_ context.Context, kafkaMsg kafka.Message) error {
 		return nil
 	}
 
-	// Step 3: 将 Protobuf 消息转换为 JSON 格式，方便前端解析
-	jsonMsg, err := convertProtoToJSON(&msg)
-	if err != nil {
-		log.Logger.Sugar().Errorf("DispatchMessage: 转换JSON失败: %v", err)
-		return err
-	}
-
-	// Step 4: 在本 Gateway 的客户端连接池中查找接收者
-	// 使用读锁保护并发访问 clients map
+	// Step 3: 在本 Gateway 的客户端连接池中查找接收者的所有设备，逐一推送
+	// 按各连接协商的帧格式（JSON / protobuf）编码，同一格式只编码一次
+	// 通过 channel 推送到 writePump，writePump 会将消息通过 WebSocket 发送给客户端
+	// 持有读锁期间只做非阻塞发送，避免与关闭 channel 并发
+	frames := make(map[frameFormat][]byte, 2)
+	var full, recovered []*Client
+	delivered := 0
 	h.mu.RLock()
-	recipientClient, ok := h.clients[recipientUUID]
-	h.mu.RUnlock()
-
-	if ok {
-		// Step 5a: 用户在本 Gateway 在线，通过 channel 推送到 writePump
-		// writePump 会将消息通过 WebSocket 发送给客户端
+	for deviceID, client := range h.clients[recipientUUID] {
+		// 只发给指定设备（如 ACK、同步结果）
+		if msg.TargetDeviceID != "" && deviceID != msg.TargetDeviceID {
+			continue
+		}
+		// 回显自己发的消息时跳过发出消息的设备
+		if recipientUUID == msg.SenderUUID && deviceID == msg.SenderDeviceID {
+			continue
+		}
+		frame, ok := frames[client.format]
+		if !ok {
+			var err error
+			if frame, err = client.format.encode(&msg); err != nil {
+				h.mu.RUnlock()
+				log.Logger.Sugar().Errorf("DispatchMessage: 编码下行帧失败: %v", err)
+				return err
+			}
+			frames[client.format] = frame
+		}
 		select {
-		case recipientClient.send <- jsonMsg:
-			log.Logger.Sugar().Debugf("Dispatched message to user %s", recipientUUID)
+		case client.send <- outbound{frame: frame, msg: &msg}:
+			delivered++
+			if client.recovered() {
+				recovered = append(recovered, client)
+			}
 		default:
-			log.Logger.Sugar().Infof("Client channel full, closing connection: %s", recipientUUID)
-			h.mu.Lock()
-			close(recipientClient.send)
-			delete(h.clients, recipientClient.userUUID)
-			h.mu.Unlock()
+			full = append(full, client)
 		}
+	}
+	h.mu.RUnlock()
+
+	if len(full) > 0 {
+		h.handleSlowClients(&msg, full, delivered)
+	}
+	// 设备恢复后补发满载期间转存到离线队列的消息
+	for _, client := range recovered {
+		h.requestOfflineMessageSync(client.userUUID, client.deviceID)
+	}
+
+	if delivered > 0 {
+		log.Logger.Sugar().Debugf("Dispatched message to user %s on %d device(s)", recipientUUID, delivered)
 	} else {
-		// Step 5b: 用户不在本 Gateway 上
 		log.Logger.Sugar().Debugf("User not connected to this gateway: %s", recipientUUID)
 	}
 
 	return nil
 }
 
+// handleSlowClients 处理发送队列已满的设备：消息转存到用户的离线队列，
+// 设备恢复后补发，满载持续超过 slowConsumerGrace 的连接被断开，重连后同样从离线队列补齐
+// 同一用户的离线队列是共享的，会推送给该用户之后连接或恢复的每台设备：
+// 只有本 Gateway 上该用户没有设备收到消息时才转存，否则转存的消息会重复推送给已收到的设备；
+// 此时慢设备缺少的消息由客户端按序号增量同步（sync 命令）补齐
+// 断开连接时队列中尚未写出的消息先于当前消息转存，保持离线队列按到达顺序投递
+func (h *Hub) handleSlowClients(msg *pb.Message, full []*Client, delivered int) {
+	now := time.Now()
+	var unsent []*pb.Message
+	for _, client := range full {
+		slowFor := client.markSlow(now)
+		if slowFor < slowConsumerGrace {
+			log.Logger.Sugar().Debugf("Client channel full for %s: %s/%s", slowFor, client.userUUID, client.deviceID)
+			continue
+		}
+		// 在这里移除连接以取出队列中的消息；路由表和在线状态交给 Run 清理，与同设备重新连接的注册保持先后顺序
+		if pending, ok := h.detachClient(client); ok {
+			unsent = append(unsent, pending...)
+			h.evicted <- client
+			h.stats.disconnected.Add(1)
+			log.Logger.Sugar().Warnf("Client channel full for %s, closing connection: %s/%s (%d queued)", slowFor, client.userUUID, client.deviceID, len(pending))
+		}
+	}
+
+	// 该用户仍有设备连接在本 Gateway 上时，队列中的消息已经或即将投递给这些设备，不再转存
+	h.mu.RLock()
+	connected := len(h.clients[msg.RecipientUUID]) > 0
+	h.mu.RUnlock()
+
+	// 同一条消息可能同时在该用户多台设备的队列中，只转存一次；
+	// 其余设备的队列中仍引用该消息，转存副本以免并发修改
+	seen := make(map[*pb.Message]bool, len(unsent))
+	for _, m := range unsent {
+		if seen[m] {
+			continue
+		}
+		seen[m] = true
+		if connected {
+			h.stats.dropped.Add(1)
+			continue
+		}
+		h.spill(proto.Clone(m).(*pb.Message))
+	}
+
+	if delivered > 0 {
+		h.stats.dropped.Add(1)
+		return
+	}
+	h.spill(msg)
+}
+
+// spill 将无法投递的消息转存到接收者的离线队列，不可转存的消息计为丢弃
+func (h *Hub) spill(msg *pb.Message) {
+	if h.redis == nil || !offline.Spillable(msg) {
+		h.stats.dropped.Add(1)
+		return
+	}
+	if err := offline.Store(h.ctx, h.redis, msg.RecipientUUID, msg); err != nil {
+		log.Logger.Sugar().Errorf("Failed to spill message %s for user %s: %v", msg.Id, msg.RecipientUUID, err)
+		h.stats.dropped.Add(1)
+		return
+	}
+	h.stats.spilled.Add(1)
+}
+
 // convertProtoToJSON 将 Protobuf Message 转换为 JSON 格式
 // 用于发送给 WebSocket 客户端，方便前端解析
 func convertProtoToJSON(msg *pb.Message) ([]byte, error) {
@@ -99,16 +219,66 @@ func convertProtoToJSON(msg *pb.Message) ([]byte, error) {
 		"senderUUID":     msg.SenderUUID,
 		"recipientUUID":  msg.RecipientUUID,
 		"sendAt":         msg.SendAt,
+		"seq":            msg.Seq,
 		"contentType":    msg.ContentType,
 		"messageType":    msg.MessageType,
 		"senderName":     msg.SenderName,
 		"avatar":         msg.Avatar,
+		"eventType":      msg.EventType,
+		"clientMsgID":    msg.ClientMsgID,
+		"offline":        msg.Offline,
+		"recalled":       msg.Recalled,
+		"edited":         msg.Edited,
+		"editedAt":       msg.EditedAt,
+		"mentioned":      msg.Mentioned,
+		"muted":          msg.Muted,
+	}
+	if msg.ThreadReplyCount > 0 {
+		jsonData["threadReplyCount"] = msg.ThreadReplyCount
+		jsonData["threadLastReplyAt"] = msg.ThreadLastReplyAt
+	}
+	if msg.ExpireAt > 0 {
+		jsonData["expireAt"] = msg.ExpireAt
+	}
+
+	// 元数据：回复消息附带被回复消息的摘要，@ 消息附带被 @ 的成员，话题回复附带根消息ID，转发消息附带来源
+	if md := msg.GetMetadata(); md != nil {
+		metadata := map[string]interface{}{
+			"replyToMsgID": md.ReplyToMsgID,
+			"mentionUUIDs": md.MentionUUIDs,
+			"mentionAll":   md.MentionAll,
+			"threadRootID": md.ThreadRootID,
+		}
+		if reply := md.Reply; reply != nil {
+			metadata["reply"] = map[string]interface{}{
+				"messageID":   reply.MessageID,
+				"senderUUID":  reply.SenderUUID,
+				"senderName":  reply.SenderName,
+				"contentType": reply.ContentType,
+				"preview":     reply.Preview,
+				"recalled":    reply.Recalled,
+				"seq":         reply.Seq,
+			}
+		}
+		if from := md.ForwardedFrom; from != nil {
+			metadata["forwardedFrom"] = map[string]interface{}{
+				"messageID":      from.MessageID,
+				"conversationID": from.ConversationID,
+				"senderUUID":     from.SenderUUID,
+				"senderName":     from.SenderName,
+				"sendAt":         from.SendAt,
+			}
+		}
+		jsonData["metadata"] = metadata
 	}
 
 	// 解包 Body 字段
-	if msg.Body != nil {
+	if msg.Body != nil && msg.EventType != pb.EventTypeMessage {
+		// 事件消息的 Body 是事件内容，而非聊天内容
+		jsonData["body"] = convertEventBody(msg)
+	} else if msg.Body != nil {
 		switch msg.ContentType {
-		case 1: // Text
+		case 1, 5: // Text, System notice
 			var textBody pb.TextBody
 			if err := msg.Body.UnmarshalTo(&textBody); err == nil {
 				jsonData["body"] = map[string]interface{}{
@@ -131,49 +301,136 @@ func convertProtoToJSON(msg *pb.Message) ([]byte, error) {
 	return json.Marshal(jsonData)
 }
 
-// Run 负责客户端连接的注册和注销
+// Run 负责客户端连接的注册和注销，并定期刷新本 Gateway 的存活键
+// 存活键过期（进程崩溃或 Hub 卡住）后，Logic 服务将指向本 Gateway 的路由视为离线
+// 同时定期检查在线用户的离线队列，推送窗口超时仍未确认时请求续推
 func (h *Hub) Run() {
 	log.Logger.Info("WebSocket Hub started")
 
+	h.keepAlive()
+	ticker := time.NewTicker(route.GatewayRefresh)
+	defer ticker.Stop()
+	resumeTicker := time.NewTicker(offline.PendingTimeout)
+	defer resumeTicker.Stop()
+
 	for {
 		select {
+		case <-ticker.C:
+			h.keepAlive()
+
+		case <-resumeTicker.C:
+			go h.resumeStalledOffline()
+
 		case client := <-h.register:
-			// 用户连接：注册到本地连接池
+			// 设备连接：注册到本地连接池
 			h.mu.Lock()
-			h.clients[client.userUUID] = client
+			devices, ok := h.clients[client.userUUID]
+			if !ok {
+				devices = make(map[string]*Client)
+				h.clients[client.userUUID] = devices
+			}
+			if old, ok := devices[client.deviceID]; ok {
+				// 同一设备重复连接，关闭旧连接；旧连接注销时发现已被替换，不会删除路由
+				close(old.send)
+			}
+			devices[client.deviceID] = client
 			h.mu.Unlock()
 
-			// Key: user_gateway:{userUUID}, Value: gatewayID
+			// 路由表 user_routes:{userUUID} 中登记 gatewayID|instanceID|deviceID
 			if h.redis != nil {
-				err := h.redis.Set(h.ctx, "user_gateway:"+client.userUUID, h.gatewayID, 0).Err()
-				if err != nil {
+				if err := route.Add(h.ctx, h.redis, client.userUUID, h.routeOf(client)); err != nil {
 					log.Logger.Sugar().Errorf("Failed to register user online status: %v", err)
 				}
 			}
+			h.deviceOnline(client)
 
-			log.Logger.Sugar().Infof("Client connected: %s on gateway %s", client.userUUID, h.gatewayID)
+			log.Logger.Sugar().Infof("Client connected: %s/%s on gateway %s", client.userUUID, client.deviceID, h.gatewayID)
 
 		case client := <-h.unregister:
-			// 用户断开：从本地连接池移除
-			h.mu.Lock()
-			if _, ok := h.clients[client.userUUID]; ok {
-				delete(h.clients, client.userUUID)
-				close(client.send)
+			// 设备断开：从本地连接池和路由表移除
+			// 这样 Logic 服务就知道该设备已离线；所有设备都离线时消息会存入离线队列
+			if h.dropClient(client) {
+				log.Logger.Sugar().Infof("Client disconnected: %s/%s", client.userUUID, client.deviceID)
 			}
-			h.mu.Unlock()
 
-			// 从 Redis 中移除用户路由信息
-			// 这样 Logic 服务就知道用户已离线，会将消息存入离线队列
-			if h.redis != nil {
-				err := h.redis.Del(h.ctx, "user_gateway:"+client.userUUID).Err()
-				if err != nil {
-					log.Logger.Sugar().Errorf("Failed to unregister user online status: %v", err)
-				}
+		case client := <-h.evicted:
+			// 慢连接已在 handleSlowClients 中从连接池移除，这里只清理路由表和在线状态
+			h.cleanupClient(client)
+		}
+	}
+}
+
+// dropClient 从连接池移除连接、关闭发送通道并删除路由，只在 Run 中调用
+// 连接已被移除或已被同设备的新连接替换时返回 false
+func (h *Hub) dropClient(client *Client) bool {
+	if _, ok := h.detachClient(client); !ok {
+		return false
+	}
+	h.cleanupClient(client)
+	return true
+}
+
+// detachClient 从连接池移除连接并关闭发送通道，返回发送队列中尚未写出的消息
+// 持有写锁时不会再有新的帧入队，取出剩余的帧后 writePump 只会读到通道关闭
+// 连接已被移除或已被同设备的新连接替换时返回 false
+func (h *Hub) detachClient(client *Client) ([]*pb.Message, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	devices := h.clients[client.userUUID]
+	if devices[client.deviceID] != client {
+		return nil, false
+	}
+	delete(devices, client.deviceID)
+	if len(devices) == 0 {
+		delete(h.clients, client.userUUID)
+	}
+	var pending []*pb.Message
+	for drained := false; !drained; {
+		select {
+		case out := <-client.send:
+			if out.msg != nil {
+				pending = append(pending, out.msg)
 			}
+		default:
+			drained = true
+		}
+	}
+	close(client.send)
+	return pending, true
+}
 
-			log.Logger.Sugar().Infof("Client disconnected: %s", client.userUUID)
+// cleanupClient 删除已移除连接的路由并更新在线状态
+// 与注册新连接一样只在 Run 中执行：同一设备已经重新连接时，路由记录（gatewayID|instanceID|deviceID 相同）
+// 与在线状态都属于新连接，不再清理
+func (h *Hub) cleanupClient(client *Client) {
+	h.mu.RLock()
+	_, reconnected := h.clients[client.userUUID][client.deviceID]
+	h.mu.RUnlock()
+	if reconnected {
+		return
+	}
+
+	if h.redis != nil {
+		if err := route.Remove(h.ctx, h.redis, client.userUUID, h.routeOf(client)); err != nil {
+			log.Logger.Sugar().Errorf("Failed to unregister user online status: %v", err)
 		}
 	}
+	h.deviceOffline(client)
+}
+
+// keepAlive 刷新本 Gateway 的存活键
+func (h *Hub) keepAlive() {
+	if h.redis == nil {
+		return
+	}
+	if err := route.KeepAlive(h.ctx, h.redis, h.gatewayID, h.instanceID); err != nil {
+		log.Logger.Sugar().Errorf("Failed to refresh liveness of gateway %s: %v", h.gatewayID, err)
+	}
+}
+
+// routeOf 返回连接在路由表中的记录
+func (h *Hub) routeOf(client *Client) route.Route {
+	return route.Route{GatewayID: h.gatewayID, Instance: h.instanceID, DeviceID: client.deviceID}
 }
 
 // Stop 优雅关闭 Hub
@@ -184,46 +441,103 @@ func (h *Hub) Stop() {
 	defer h.mu.Unlock()
 
 	// 关闭所有客户端连接
-	for userUUID, client := range h.clients {
-		close(client.send)
-
-		// 从 Redis 中移除用户在线状态
-		if h.redis != nil {
-			err := h.redis.Del(h.ctx, "user_gateway:"+userUUID).Err()
-			if err != nil {
-				log.Logger.Sugar().Errorf("Failed to cleanup user online status: %v", err)
+	for userUUID, devices := range h.clients {
+		for _, client := range devices {
+			close(client.send)
+
+			// 从路由表中移除设备
+			if h.redis != nil {
+				err := route.Remove(h.ctx, h.redis, userUUID, h.routeOf(client))
+				if err != nil {
+					log.Logger.Sugar().Errorf("Failed to cleanup user online status: %v", err)
+				}
 			}
+			h.deviceOffline(client)
 		}
 	}
 
 	// 清空客户端映射
-	h.clients = make(map[string]*Client)
+	h.clients = make(map[string]map[string]*Client)
+
+	if h.redis != nil {
+		if err := route.Shutdown(h.ctx, h.redis, h.gatewayID, h.instanceID); err != nil {
+			log.Logger.Sugar().Errorf("Failed to unregister gateway %s: %v", h.gatewayID, err)
+		}
+	}
 
 	log.Logger.Info("WebSocket Hub stopped")
 }
 
-// requestOfflineMessageSync 请求同步用户的离线消息
-func (h *Hub) requestOfflineMessageSync(userUUID string) {
-	// 构造同步请求消息
-	syncRequest := map[string]interface{}{
-		"action":    "sync_offline",
-		"useruuid":  userUUID,
-		"timestamp": time.Now().Unix(),
+// requestOfflineMessageSync 请求将用户的离线消息同步到刚连接的设备
+func (h *Hub) requestOfflineMessageSync(userUUID, deviceID string) {
+	h.sendSyncRequest(map[string]interface{}{
+		"action":   "sync_offline",
+		"useruuid": userUUID,
+		"deviceid": deviceID,
+	})
+}
+
+// resumeStalledOffline 检查本 Gateway 上在线用户的离线队列
+// 推送窗口超时仍未确认（推送的消息丢失或客户端没有确认）时，请求 Logic 服务向该用户的一台设备续推
+func (h *Hub) resumeStalledOffline() {
+	if h.redis == nil {
+		return
+	}
+
+	h.mu.RLock()
+	users := make([]string, 0, len(h.clients))
+	devices := make(map[string]string, len(h.clients))
+	for userUUID, clients := range h.clients {
+		for deviceID := range clients {
+			users = append(users, userUUID)
+			devices[userUUID] = deviceID
+			break
+		}
+	}
+	h.mu.RUnlock()
+
+	stalled, err := offline.Stalled(h.ctx, h.redis, users)
+	if err != nil {
+		log.Logger.Sugar().Errorf("Failed to check stalled offline queues: %v", err)
+		return
 	}
+	for _, userUUID := range stalled {
+		h.sendSyncRequest(map[string]interface{}{
+			"action":   "offline_resume",
+			"useruuid": userUUID,
+			"deviceid": devices[userUUID],
+		})
+	}
+}
+
+// sendSyncRequest 向 Logic 服务发送同步类请求（离线同步、离线确认等）
+// 以用户为 Key 保证同一用户的请求按顺序处理
+func (h *Hub) sendSyncRequest(syncRequest map[string]interface{}) {
+	userUUID, _ := syncRequest["useruuid"].(string)
+	h.sendRequest(config.GetConfig().Kafka.Topics.Sync_request, userUUID, syncRequest)
+}
+
+// sendSignal 向 Logic 服务发送瞬时信号（正在输入、在线状态变化），信号不入库，与同步请求分开以免被同步积压拖慢
+// 正在输入以会话为 Key、在线状态以用户为 Key，保证同一会话或同一用户的信号按顺序处理
+func (h *Hub) sendSignal(key string, signal map[string]interface{}) {
+	h.sendRequest(config.GetConfig().Kafka.Topics.Signal, key, signal)
+}
+
+// sendRequest 附带时间戳后序列化为 JSON 发送到 Logic 服务的指定主题
+func (h *Hub) sendRequest(topic, key string, request map[string]interface{}) {
+	request["timestamp"] = time.Now().Unix()
 
 	// 序列化为 JSON
-	requestData, err := json.Marshal(syncRequest)
+	requestData, err := json.Marshal(request)
 	if err != nil {
-		log.Logger.Sugar().Errorf("Failed to marshal sync request: %v", err)
+		log.Logger.Sugar().Errorf("Failed to marshal %v request: %v", request["action"], err)
 		return
 	}
 
-	// 发送到 Logic 服务的同步主题
-	syncTopic := config.GetConfig().Kafka.Topics.Sync_request
-	err = h.Producer.SendMessage(syncTopic, requestData)
+	err = h.Producer.SendMessageWithKey(topic, []byte(key), requestData)
 	if err != nil {
-		log.Logger.Sugar().Errorf("Failed to send sync request: %v", err)
+		log.Logger.Sugar().Errorf("Failed to send %v request: %v", request["action"], err)
 	} else {
-		log.Logger.Sugar().Debugf("Sent offline message sync request for user: %s", userUUID)
+		log.Logger.Sugar().Debugf("Sent %v request for user: %v", request["action"], request["useruuid"])
 	}
 }
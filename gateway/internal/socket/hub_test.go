@@ -0,0 +1,161 @@
+package socket
+
+import (
+	pb "MyGoChat/pkg/api/v1"
+	"MyGoChat/pkg/log"
+	"MyGoChat/pkg/offline"
+	"MyGoChat/pkg/route"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// newTestHub 创建连接 miniredis 的 Hub，不启动 Run 循环，被断开的慢连接留在 evicted 中
+func newTestHub(t *testing.T) (*Hub, *redis.Client) {
+	if log.Logger == nil {
+		log.Logger = zap.NewNop()
+	}
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	return &Hub{
+		clients:    make(map[string]map[string]*Client),
+		evicted:    make(chan *Client, 2),
+		redis:      rdb,
+		gatewayID:  "gw-1",
+		instanceID: "instance-1",
+		ctx:        context.Background(),
+	}, rdb
+}
+
+// addTestClient 在 Hub 中登记一个不带 WebSocket 连接的设备
+func addTestClient(h *Hub, userUUID, deviceID string) *Client {
+	client := &Client{hub: h, send: make(chan outbound, sendBufferSize), userUUID: userUUID, deviceID: deviceID}
+	if h.clients[userUUID] == nil {
+		h.clients[userUUID] = make(map[string]*Client)
+	}
+	h.clients[userUUID][deviceID] = client
+	return client
+}
+
+// chatMessage 返回一条发给 recipient 的聊天消息
+func chatMessage(id string) *pb.Message {
+	return &pb.Message{
+		Id:             id,
+		ConversationID: "conv-1",
+		SenderUUID:     "sender",
+		RecipientUUID:  "recipient",
+		EventType:      pb.EventTypeMessage,
+	}
+}
+
+// TestHandleSlowClients_SpillsQueuedMessages 测试断开慢连接时，队列中尚未写出的消息先于当前消息转存到离线队列
+func TestHandleSlowClients_SpillsQueuedMessages(t *testing.T) {
+	h, rdb := newTestHub(t)
+	ctx := context.Background()
+
+	phone := addTestClient(h, "recipient", "phone")
+	tablet := addTestClient(h, "recipient", "tablet")
+	queued := []*pb.Message{chatMessage("m1"), chatMessage("m2")}
+	for _, msg := range queued {
+		phone.send <- outbound{frame: []byte("frame"), msg: msg}
+		tablet.send <- outbound{frame: []byte("frame"), msg: msg}
+	}
+	// Gateway 直接回复的 ACK 不转存
+	phone.send <- outbound{frame: []byte("ack")}
+
+	slowSince := time.Now().Add(-2 * slowConsumerGrace)
+	phone.slowSince.Store(slowSince.UnixNano())
+	tablet.slowSince.Store(slowSince.UnixNano())
+
+	h.handleSlowClients(chatMessage("m3"), []*Client{phone, tablet}, 0)
+
+	ids, err := rdb.ZRange(ctx, offline.QueueKey("recipient"), 0, -1).Result()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"m1", "m2", "m3"}, ids, "queued messages are spilled once and before the current one")
+
+	stats := h.Stats()
+	assert.Equal(t, int64(3), stats.Spilled)
+	assert.Equal(t, int64(0), stats.Dropped)
+	assert.Equal(t, int64(2), stats.Disconnected)
+	assert.Empty(t, h.clients)
+	assert.Len(t, h.evicted, 2, "route cleanup is handed to Run")
+
+	// 其余设备队列中的原消息不被修改
+	assert.False(t, queued[0].Offline)
+
+	_, ok := <-phone.send
+	assert.False(t, ok, "send channel is drained and closed")
+}
+
+// TestHandleSlowClients_WithinGrace 测试满载未超过宽限期时只转存当前消息，不断开连接
+func TestHandleSlowClients_WithinGrace(t *testing.T) {
+	h, rdb := newTestHub(t)
+	ctx := context.Background()
+
+	phone := addTestClient(h, "recipient", "phone")
+	phone.send <- outbound{frame: []byte("frame"), msg: chatMessage("m1")}
+
+	h.handleSlowClients(chatMessage("m2"), []*Client{phone}, 0)
+
+	ids, err := rdb.ZRange(ctx, offline.QueueKey("recipient"), 0, -1).Result()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"m2"}, ids)
+	assert.Len(t, phone.send, 1)
+	assert.Same(t, phone, h.clients["recipient"]["phone"])
+	assert.Equal(t, int64(0), h.Stats().Disconnected)
+}
+
+// TestCleanupClient_Reconnected 测试慢连接被断开后同一设备已重新连接时，清理旧连接不会删除新连接的路由
+func TestCleanupClient_Reconnected(t *testing.T) {
+	h, rdb := newTestHub(t)
+	ctx := context.Background()
+	h.keepAlive()
+
+	old := addTestClient(h, "recipient", "phone")
+	old.slowSince.Store(time.Now().Add(-2 * slowConsumerGrace).UnixNano())
+	h.handleSlowClients(chatMessage("m1"), []*Client{old}, 0)
+
+	// 同一设备在清理前重新连接，路由记录与旧连接相同
+	addTestClient(h, "recipient", "phone")
+	require.NoError(t, route.Add(ctx, rdb, "recipient", h.routeOf(old)))
+
+	h.cleanupClient(<-h.evicted)
+	routes, err := route.List(ctx, rdb, "recipient")
+	require.NoError(t, err)
+	assert.Len(t, routes, 1, "route of the new connection is kept")
+
+	// 没有重新连接时正常删除路由
+	h.dropClient(h.clients["recipient"]["phone"])
+	routes, err = route.List(ctx, rdb, "recipient")
+	require.NoError(t, err)
+	assert.Empty(t, routes)
+}
+
+// TestHandleSlowClients_DeliveredElsewhere 测试同一用户的其他设备已收到或仍连接时不转存，避免离线队列重复推送
+func TestHandleSlowClients_DeliveredElsewhere(t *testing.T) {
+	h, rdb := newTestHub(t)
+	ctx := context.Background()
+
+	phone := addTestClient(h, "recipient", "phone")
+	addTestClient(h, "recipient", "tablet")
+	phone.send <- outbound{frame: []byte("frame"), msg: chatMessage("m1")}
+	phone.slowSince.Store(time.Now().Add(-2 * slowConsumerGrace).UnixNano())
+
+	// tablet 已收到 m2，phone 被断开，队列中的 m1 同样已投递给仍连接的 tablet
+	h.handleSlowClients(chatMessage("m2"), []*Client{phone}, 1)
+
+	exists, err := rdb.Exists(ctx, offline.QueueKey("recipient")).Result()
+	require.NoError(t, err)
+	assert.Zero(t, exists, "nothing is spilled to the shared offline queue")
+
+	stats := h.Stats()
+	assert.Equal(t, int64(0), stats.Spilled)
+	assert.Equal(t, int64(2), stats.Dropped)
+	assert.Equal(t, int64(1), stats.Disconnected)
+}
@@ -0,0 +1,95 @@
+package socket
+
+import (
+	"MyGoChat/pkg/log"
+	"MyGoChat/pkg/presence"
+	"MyGoChat/pkg/route"
+	"fmt"
+	"time"
+)
+
+// updatePresence 执行一次在线状态更新，用户对其他人可见的状态发生变化时通过信号 Topic 通知 Logic 服务推送给好友
+func (h *Hub) updatePresence(userUUID string, update func(now time.Time) error) {
+	if h.redis == nil {
+		return
+	}
+
+	now := time.Now()
+	before, err := presence.Get(h.ctx, h.redis, userUUID, now)
+	if err != nil {
+		log.Logger.Sugar().Warnf("Failed to get presence of %s: %v", userUUID, err)
+	}
+	if err := update(now); err != nil {
+		log.Logger.Sugar().Errorf("Failed to update presence of %s: %v", userUUID, err)
+		return
+	}
+	after, err := presence.Get(h.ctx, h.redis, userUUID, now)
+	if err != nil {
+		log.Logger.Sugar().Warnf("Failed to get presence of %s: %v", userUUID, err)
+		return
+	}
+
+	visible := after.Visible()
+	if before.Visible().Status == visible.Status {
+		return
+	}
+	h.sendSignal(userUUID, map[string]interface{}{
+		"action":   "presence",
+		"useruuid": userUUID,
+		"status":   string(visible.Status),
+		"lastSeen": visible.LastSeen,
+	})
+}
+
+// deviceOnline 设备连接后登记为在线
+func (h *Hub) deviceOnline(client *Client) {
+	h.updatePresence(client.userUUID, func(now time.Time) error {
+		return presence.SetDevice(h.ctx, h.redis, client.userUUID, client.deviceID, presence.StatusOnline, now)
+	})
+}
+
+// deviceOffline 设备断开后删除其在线状态
+func (h *Hub) deviceOffline(client *Client) {
+	h.updatePresence(client.userUUID, func(now time.Time) error {
+		return presence.RemoveDevice(h.ctx, h.redis, client.userUUID, client.deviceID, now)
+	})
+}
+
+// heartbeat 收到客户端心跳时延长路由表的过期时间，并刷新设备状态与最后在线时间
+// 只在 readPump 中调用
+func (c *Client) heartbeat() {
+	if c.hub.redis == nil {
+		return
+	}
+	if err := route.Refresh(c.hub.ctx, c.hub.redis, c.userUUID); err != nil {
+		log.Logger.Sugar().Warnf("Failed to refresh route of %s/%s: %v", c.userUUID, c.deviceID, err)
+	}
+	if err := presence.SetDevice(c.hub.ctx, c.hub.redis, c.userUUID, c.deviceID, c.status, time.Now()); err != nil {
+		log.Logger.Sugar().Warnf("Failed to refresh presence of %s/%s: %v", c.userUUID, c.deviceID, err)
+	}
+}
+
+// setPresence 处理客户端切换状态：online / away 针对当前设备，invisible 针对用户的所有设备，
+// 切换为 online 同时取消隐身
+// 只在 readPump 中调用，未知状态返回错误
+func (c *Client) setPresence(status presence.Status) error {
+	switch status {
+	case presence.StatusOnline, presence.StatusAway:
+		c.status = status
+		c.hub.updatePresence(c.userUUID, func(now time.Time) error {
+			if status == presence.StatusOnline {
+				if err := presence.SetInvisible(c.hub.ctx, c.hub.redis, c.userUUID, false); err != nil {
+					return err
+				}
+			}
+			return presence.SetDevice(c.hub.ctx, c.hub.redis, c.userUUID, c.deviceID, status, now)
+		})
+	case presence.StatusInvisible:
+		c.hub.updatePresence(c.userUUID, func(time.Time) error {
+			return presence.SetInvisible(c.hub.ctx, c.hub.redis, c.userUUID, true)
+		})
+	default:
+		return fmt.Errorf("unknown presence status: %s", status)
+	}
+	return nil
+}
@@ -2,7 +2,11 @@ package socket
 
 import (
 	"MyGoChat/pkg/log"
+	"MyGoChat/pkg/presence"
+	"crypto/rand"
+	"encoding/hex"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 
@@ -16,6 +20,8 @@ var upgrader = websocket.Upgrader{
 	CheckOrigin: func(r *http.Request) bool {
 		return true
 	},
+	// 下行帧格式，按优先级排列，见 frame.go
+	Subprotocols: []string{subprotocolProtobuf, subprotocolJSON},
 }
 
 // ServeWs 处理 WebSocket 连接请求
@@ -33,6 +39,16 @@ func ServeWs(hub *Hub, c *gin.Context) {
 		return
 	}
 
+	// 设备标识：客户端应在每台设备上生成并持久化一个固定的 device_id，
+	// 未提供时为本次连接随机生成一个
+	deviceID := c.Query("device_id")
+	if deviceID == "" {
+		deviceID = newDeviceID()
+	} else if len(deviceID) > 64 || strings.Contains(deviceID, "|") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid device_id"})
+		return
+	}
+
 	// 将 HTTP 连接升级为 WebSocket
 	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
@@ -44,15 +60,18 @@ func ServeWs(hub *Hub, c *gin.Context) {
 	client := &Client{
 		hub:      hub,
 		conn:     conn,
-		send:     make(chan []byte, 256),
+		send:     make(chan outbound, sendBufferSize),
 		userUUID: uuid,
+		deviceID: deviceID,
+		format:   formatOf(conn.Subprotocol()),
+		status:   presence.StatusOnline,
 	}
 
 	// 注册到 Hub, Hub.Run() 会处理注册请求，更新 clients map 和 Redis 路由表
 	hub.register <- client
 
 	// 触发离线消息同步
-	hub.requestOfflineMessageSync(uuid)
+	hub.requestOfflineMessageSync(uuid, deviceID)
 
 	// 启动读写 goroutine
 	// writePump: 监听 client.send channel，将消息写入 WebSocket
@@ -60,3 +79,10 @@ func ServeWs(hub *Hub, c *gin.Context) {
 	go client.writePump()
 	go client.readPump()
 }
+
+// newDeviceID 为未携带 device_id 的连接生成临时设备标识
+func newDeviceID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return "anon-" + hex.EncodeToString(b)
+}
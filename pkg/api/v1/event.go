@@ -0,0 +1,18 @@
+package pb
+
+// 事件类型，对应 Message.EventType
+// 普通聊天消息为 0；其余事件不落库，具体内容打包在 Message.Body 中
+const (
+	EventTypeMessage      int32 = 0  // 聊天消息
+	EventTypeReadReceipt  int32 = 1  // 已读回执，Body 为 ReadReceipt
+	EventTypeAck          int32 = 2  // 发送确认，Body 为 Ack
+	EventTypeNack         int32 = 3  // 发送失败，Body 为 Ack（含失败原因）
+	EventTypeSyncComplete int32 = 4  // 增量同步结束，Body 为 SyncComplete
+	EventTypeRecall       int32 = 5  // 消息撤回，Body 为 MessageRecall
+	EventTypeEdit         int32 = 6  // 消息编辑，Body 为 MessageEdit
+	EventTypeReaction     int32 = 7  // 表情回应，Body 为 ReactionEvent
+	EventTypeThread       int32 = 8  // 话题摘要更新，Body 为 ThreadUpdate
+	EventTypePin          int32 = 9  // 消息置顶状态变化，Body 为 PinEvent
+	EventTypeTyping       int32 = 10 // 正在输入，Body 为 TypingEvent
+	EventTypePresence     int32 = 11 // 好友在线状态变化，Body 为 PresenceEvent
+)
@@ -24,15 +24,29 @@ const (
 )
 
 type Message struct {
-	state          protoimpl.MessageState `protogen:"open.v1"`
-	Id             string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`                                // 消息唯一ID (MongoDB ObjectID)
-	ConversationID string                 `protobuf:"bytes,2,opt,name=conversationID,proto3" json:"conversationID,omitempty"`        // 会话ID (MongoDB ObjectID)
-	SenderUUID     string                 `protobuf:"bytes,3,opt,name=senderUUID,proto3" json:"senderUUID,omitempty"`                // 发送消息用户UUID
-	SendAt         int64                  `protobuf:"varint,4,opt,name=sendAt,proto3" json:"sendAt,omitempty"`                       // 消息发送时间
-	ContentType    int32                  `protobuf:"varint,5,opt,name=contentType,proto3" json:"contentType,omitempty"`             // 1=text, 2=image, 3=file, 4=voice
-	Body           *anypb.Any             `protobuf:"bytes,6,opt,name=body,proto3" json:"body,omitempty"`                            // 消息内容
-	Metadata       *MessageMetadata       `protobuf:"bytes,7,opt,name=metadata,proto3" json:"metadata,omitempty"`                    // 元数据
-	DeletedAt      *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=deleted_at,json=deletedAt,proto3" json:"deleted_at,omitempty"` // 删除时间
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	Id                string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`                                 // 消息唯一ID (MongoDB ObjectID)
+	ConversationID    string                 `protobuf:"bytes,2,opt,name=conversationID,proto3" json:"conversationID,omitempty"`         // 会话ID (MongoDB ObjectID)
+	SenderUUID        string                 `protobuf:"bytes,3,opt,name=senderUUID,proto3" json:"senderUUID,omitempty"`                 // 发送消息用户UUID
+	SendAt            int64                  `protobuf:"varint,4,opt,name=sendAt,proto3" json:"sendAt,omitempty"`                        // 消息发送时间
+	ContentType       int32                  `protobuf:"varint,5,opt,name=contentType,proto3" json:"contentType,omitempty"`              // 1=text, 2=image, 3=file, 4=voice, 5=系统通知
+	Body              *anypb.Any             `protobuf:"bytes,6,opt,name=body,proto3" json:"body,omitempty"`                             // 消息内容
+	Metadata          *MessageMetadata       `protobuf:"bytes,7,opt,name=metadata,proto3" json:"metadata,omitempty"`                     // 元数据
+	DeletedAt         *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=deleted_at,json=deletedAt,proto3" json:"deleted_at,omitempty"`  // 删除时间
+	Seq               int64                  `protobuf:"varint,13,opt,name=seq,proto3" json:"seq,omitempty"`                             // 会话内单调递增序号，由 Logic 服务在持久化时分配
+	EventType         int32                  `protobuf:"varint,14,opt,name=eventType,proto3" json:"eventType,omitempty"`                 // 事件类型，0=聊天消息 1=已读回执 2=发送确认 3=发送失败 4=同步结束 5=撤回 6=编辑 7=表情回应 8=话题更新 9=置顶 10=正在输入 11=在线状态；事件的具体内容打包在 body 中
+	ClientMsgID       string                 `protobuf:"bytes,15,opt,name=clientMsgID,proto3" json:"clientMsgID,omitempty"`              // 客户端生成的消息ID，用于把 ACK/NACK 与本地待发送消息对应起来
+	Offline           bool                   `protobuf:"varint,16,opt,name=offline,proto3" json:"offline,omitempty"`                     // 该消息来自离线队列，客户端收到后需回复 offline_ack，服务端才会删除
+	TargetDeviceID    string                 `protobuf:"bytes,17,opt,name=targetDeviceID,proto3" json:"targetDeviceID,omitempty"`        // 非空时只投递给接收者的该设备（如 ACK、同步结果只发给发起请求的设备）
+	SenderDeviceID    string                 `protobuf:"bytes,18,opt,name=senderDeviceID,proto3" json:"senderDeviceID,omitempty"`        // 发送消息的设备，由 Gateway 填写；回显给发送者其他设备时跳过该设备
+	Recalled          bool                   `protobuf:"varint,19,opt,name=recalled,proto3" json:"recalled,omitempty"`                   // 消息已被撤回，body 为空
+	Edited            bool                   `protobuf:"varint,20,opt,name=edited,proto3" json:"edited,omitempty"`                       // 消息被编辑过，body 为编辑后的内容
+	EditedAt          int64                  `protobuf:"varint,21,opt,name=editedAt,proto3" json:"editedAt,omitempty"`                   // 最后一次编辑的时间
+	Mentioned         bool                   `protobuf:"varint,22,opt,name=mentioned,proto3" json:"mentioned,omitempty"`                 // 接收者被 @ 了（含 @所有人），即使开启免打扰也应提醒
+	Muted             bool                   `protobuf:"varint,23,opt,name=muted,proto3" json:"muted,omitempty"`                         // 接收者对该会话开启了免打扰且未被 @，客户端不提醒
+	ThreadReplyCount  int64                  `protobuf:"varint,24,opt,name=threadReplyCount,proto3" json:"threadReplyCount,omitempty"`   // 话题根消息的回复数
+	ThreadLastReplyAt int64                  `protobuf:"varint,25,opt,name=threadLastReplyAt,proto3" json:"threadLastReplyAt,omitempty"` // 话题根消息最后一条回复的时间
+	ExpireAt          int64                  `protobuf:"varint,26,opt,name=expireAt,proto3" json:"expireAt,omitempty"`                   // 消息过期时间（会话开启了消息自动删除），0 表示不过期
 	// The following fields are for client display purposes and are not stored in the database.
 	SenderName    string `protobuf:"bytes,9,opt,name=senderName,proto3" json:"senderName,omitempty"`        // 发送消息用户的用户名
 	Avatar        string `protobuf:"bytes,10,opt,name=avatar,proto3" json:"avatar,omitempty"`               // 头像
@@ -128,6 +142,104 @@ func (x *Message) GetDeletedAt() *timestamppb.Timestamp {
 	return nil
 }
 
+func (x *Message) GetSeq() int64 {
+	if x != nil {
+		return x.Seq
+	}
+	return 0
+}
+
+func (x *Message) GetEventType() int32 {
+	if x != nil {
+		return x.EventType
+	}
+	return 0
+}
+
+func (x *Message) GetClientMsgID() string {
+	if x != nil {
+		return x.ClientMsgID
+	}
+	return ""
+}
+
+func (x *Message) GetOffline() bool {
+	if x != nil {
+		return x.Offline
+	}
+	return false
+}
+
+func (x *Message) GetTargetDeviceID() string {
+	if x != nil {
+		return x.TargetDeviceID
+	}
+	return ""
+}
+
+func (x *Message) GetSenderDeviceID() string {
+	if x != nil {
+		return x.SenderDeviceID
+	}
+	return ""
+}
+
+func (x *Message) GetRecalled() bool {
+	if x != nil {
+		return x.Recalled
+	}
+	return false
+}
+
+func (x *Message) GetEdited() bool {
+	if x != nil {
+		return x.Edited
+	}
+	return false
+}
+
+func (x *Message) GetEditedAt() int64 {
+	if x != nil {
+		return x.EditedAt
+	}
+	return 0
+}
+
+func (x *Message) GetMentioned() bool {
+	if x != nil {
+		return x.Mentioned
+	}
+	return false
+}
+
+func (x *Message) GetMuted() bool {
+	if x != nil {
+		return x.Muted
+	}
+	return false
+}
+
+func (x *Message) GetThreadReplyCount() int64 {
+	if x != nil {
+		return x.ThreadReplyCount
+	}
+	return 0
+}
+
+func (x *Message) GetThreadLastReplyAt() int64 {
+	if x != nil {
+		return x.ThreadLastReplyAt
+	}
+	return 0
+}
+
+func (x *Message) GetExpireAt() int64 {
+	if x != nil {
+		return x.ExpireAt
+	}
+	return 0
+}
+
 func (x *Message) GetSenderName() string {
 	if x != nil {
 		return x.SenderName
@@ -273,7 +385,12 @@ func (x *FileAttachment) GetMimeType() string {
 // MessageMetadata 用于存储回复、@ 等元数据
 type MessageMetadata struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	ReplyToMsgID  string                 `protobuf:"bytes,1,opt,name=replyToMsgID,proto3" json:"replyToMsgID,omitempty"` // 回复的消息 ID (用 string 存 ObjectID)
+	ReplyToMsgID  string                 `protobuf:"bytes,1,opt,name=replyToMsgID,proto3" json:"replyToMsgID,omitempty"`   // 回复的消息 ID (用 string 存 ObjectID)
+	Reply         *ReplySnippet          `protobuf:"bytes,2,opt,name=reply,proto3" json:"reply,omitempty"`                 // 被回复消息的摘要，由服务端在下发时填充，不入库
+	MentionUUIDs  []string               `protobuf:"bytes,3,rep,name=mentionUUIDs,proto3" json:"mentionUUIDs,omitempty"`   // @ 的群成员UUID，仅群聊
+	MentionAll    bool                   `protobuf:"varint,4,opt,name=mentionAll,proto3" json:"mentionAll,omitempty"`      // @所有人，仅群主可用
+	ThreadRootID  string                 `protobuf:"bytes,5,opt,name=threadRootID,proto3" json:"threadRootID,omitempty"`   // 话题根消息 ID，非空时该消息是话题回复，不出现在会话主时间线中
+	ForwardedFrom *ForwardInfo           `protobuf:"bytes,6,opt,name=forwardedFrom,proto3" json:"forwardedFrom,omitempty"` // 转发来源，由服务端在转发时填写
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -315,76 +432,2003 @@ func (x *MessageMetadata) GetReplyToMsgID() string {
 	return ""
 }
 
-var File_api_v1_message_proto protoreflect.FileDescriptor
+func (x *MessageMetadata) GetReply() *ReplySnippet {
+	if x != nil {
+		return x.Reply
+	}
+	return nil
+}
 
-const file_api_v1_message_proto_rawDesc = "" +
-	"\n" +
-	"\x14api/v1/message.proto\x12\x02v1\x1a\x19google/protobuf/any.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\xb1\x03\n" +
-	"\aMessage\x12\x0e\n" +
-	"\x02id\x18\x01 \x01(\tR\x02id\x12&\n" +
-	"\x0econversationID\x18\x02 \x01(\tR\x0econversationID\x12\x1e\n" +
-	"\n" +
-	"senderUUID\x18\x03 \x01(\tR\n" +
-	"senderUUID\x12\x16\n" +
-	"\x06sendAt\x18\x04 \x01(\x03R\x06sendAt\x12 \n" +
-	"\vcontentType\x18\x05 \x01(\x05R\vcontentType\x12(\n" +
-	"\x04body\x18\x06 \x01(\v2\x14.google.protobuf.AnyR\x04body\x12/\n" +
-	"\bmetadata\x18\a \x01(\v2\x13.v1.MessageMetadataR\bmetadata\x129\n" +
-	"\n" +
-	"deleted_at\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\tdeletedAt\x12\x1e\n" +
-	"\n" +
-	"senderName\x18\t \x01(\tR\n" +
-	"senderName\x12\x16\n" +
-	"\x06avatar\x18\n" +
-	" \x01(\tR\x06avatar\x12 \n" +
-	"\vmessageType\x18\v \x01(\x05R\vmessageType\x12$\n" +
-	"\rrecipientUUID\x18\f \x01(\tR\rrecipientUUID\"$\n" +
-	"\bTextBody\x12\x18\n" +
-	"\acontent\x18\x01 \x01(\tR\acontent\"n\n" +
-	"\x0eFileAttachment\x12\x10\n" +
-	"\x03url\x18\x01 \x01(\tR\x03url\x12\x1a\n" +
-	"\bfileName\x18\x02 \x01(\tR\bfileName\x12\x12\n" +
-	"\x04size\x18\x03 \x01(\x03R\x04size\x12\x1a\n" +
-	"\bmimeType\x18\x04 \x01(\tR\bmimeType\"5\n" +
-	"\x0fMessageMetadata\x12\"\n" +
-	"\freplyToMsgID\x18\x01 \x01(\tR\freplyToMsgIDB\x17Z\x15MyGoChat/pkg/pb/v1;pbb\x06proto3"
+func (x *MessageMetadata) GetMentionUUIDs() []string {
+	if x != nil {
+		return x.MentionUUIDs
+	}
+	return nil
+}
 
-var (
-	file_api_v1_message_proto_rawDescOnce sync.Once
-	file_api_v1_message_proto_rawDescData []byte
-)
+func (x *MessageMetadata) GetMentionAll() bool {
+	if x != nil {
+		return x.MentionAll
+	}
+	return false
+}
 
-func file_api_v1_message_proto_rawDescGZIP() []byte {
-	file_api_v1_message_proto_rawDescOnce.Do(func() {
-		file_api_v1_message_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_api_v1_message_proto_rawDesc), len(file_api_v1_message_proto_rawDesc)))
-	})
-	return file_api_v1_message_proto_rawDescData
+func (x *MessageMetadata) GetThreadRootID() string {
+	if x != nil {
+		return x.ThreadRootID
+	}
+	return ""
 }
 
-var file_api_v1_message_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
-var file_api_v1_message_proto_goTypes = []any{
-	(*Message)(nil),               // 0: v1.Message
-	(*TextBody)(nil),              // 1: v1.TextBody
-	(*FileAttachment)(nil),        // 2: v1.FileAttachment
-	(*MessageMetadata)(nil),       // 3: v1.MessageMetadata
-	(*anypb.Any)(nil),             // 4: google.protobuf.Any
-	(*timestamppb.Timestamp)(nil), // 5: google.protobuf.Timestamp
+func (x *MessageMetadata) GetForwardedFrom() *ForwardInfo {
+	if x != nil {
+		return x.ForwardedFrom
+	}
+	return nil
 }
-var file_api_v1_message_proto_depIdxs = []int32{
-	4, // 0: v1.Message.body:type_name -> google.protobuf.Any
-	3, // 1: v1.Message.metadata:type_name -> v1.MessageMetadata
-	5, // 2: v1.Message.deleted_at:type_name -> google.protobuf.Timestamp
-	3, // [3:3] is the sub-list for method output_type
-	3, // [3:3] is the sub-list for method input_type
-	3, // [3:3] is the sub-list for extension type_name
-	3, // [3:3] is the sub-list for extension extendee
-	0, // [0:3] is the sub-list for field type_name
+
+// ForwardInfo 被转发消息的来源；多次转发时保留最初的来源
+type ForwardInfo struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	MessageID      string                 `protobuf:"bytes,1,opt,name=messageID,proto3" json:"messageID,omitempty"`           // 原消息ID
+	ConversationID string                 `protobuf:"bytes,2,opt,name=conversationID,proto3" json:"conversationID,omitempty"` // 原消息所在会话
+	SenderUUID     string                 `protobuf:"bytes,3,opt,name=senderUUID,proto3" json:"senderUUID,omitempty"`         // 原消息发送者
+	SenderName     string                 `protobuf:"bytes,4,opt,name=senderName,proto3" json:"senderName,omitempty"`         // 原消息发送者用户名
+	SendAt         int64                  `protobuf:"varint,5,opt,name=sendAt,proto3" json:"sendAt,omitempty"`                // 原消息发送时间
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
 }
 
-func init() { file_api_v1_message_proto_init() }
-func file_api_v1_message_proto_init() {
-	if File_api_v1_message_proto != nil {
-		return
+func (x *ForwardInfo) Reset() {
+	*x = ForwardInfo{}
+	mi := &file_api_v1_message_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ForwardInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ForwardInfo) ProtoMessage() {}
+
+func (x *ForwardInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1_message_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ForwardInfo.ProtoReflect.Descriptor instead.
+func (*ForwardInfo) Descriptor() ([]byte, []int) {
+	return file_api_v1_message_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ForwardInfo) GetMessageID() string {
+	if x != nil {
+		return x.MessageID
+	}
+	return ""
+}
+
+func (x *ForwardInfo) GetConversationID() string {
+	if x != nil {
+		return x.ConversationID
+	}
+	return ""
+}
+
+func (x *ForwardInfo) GetSenderUUID() string {
+	if x != nil {
+		return x.SenderUUID
+	}
+	return ""
+}
+
+func (x *ForwardInfo) GetSenderName() string {
+	if x != nil {
+		return x.SenderName
+	}
+	return ""
+}
+
+func (x *ForwardInfo) GetSendAt() int64 {
+	if x != nil {
+		return x.SendAt
+	}
+	return 0
+}
+
+// ReplySnippet 被回复（引用）消息的摘要
+type ReplySnippet struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	MessageID     string                 `protobuf:"bytes,1,opt,name=messageID,proto3" json:"messageID,omitempty"`      // 被回复的消息ID
+	SenderUUID    string                 `protobuf:"bytes,2,opt,name=senderUUID,proto3" json:"senderUUID,omitempty"`    // 被回复消息的发送者
+	SenderName    string                 `protobuf:"bytes,3,opt,name=senderName,proto3" json:"senderName,omitempty"`    // 被回复消息的发送者用户名
+	ContentType   int32                  `protobuf:"varint,4,opt,name=contentType,proto3" json:"contentType,omitempty"` // 被回复消息的内容类型
+	Preview       string                 `protobuf:"bytes,5,opt,name=preview,proto3" json:"preview,omitempty"`          // 内容预览（文本截断，文件类为占位描述），已撤回时为空
+	Recalled      bool                   `protobuf:"varint,6,opt,name=recalled,proto3" json:"recalled,omitempty"`       // 被回复的消息已撤回
+	Seq           int64                  `protobuf:"varint,7,opt,name=seq,proto3" json:"seq,omitempty"`                 // 被回复消息的序号，客户端可据此跳转
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReplySnippet) Reset() {
+	*x = ReplySnippet{}
+	mi := &file_api_v1_message_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReplySnippet) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReplySnippet) ProtoMessage() {}
+
+func (x *ReplySnippet) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1_message_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReplySnippet.ProtoReflect.Descriptor instead.
+func (*ReplySnippet) Descriptor() ([]byte, []int) {
+	return file_api_v1_message_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ReplySnippet) GetMessageID() string {
+	if x != nil {
+		return x.MessageID
+	}
+	return ""
+}
+
+func (x *ReplySnippet) GetSenderUUID() string {
+	if x != nil {
+		return x.SenderUUID
+	}
+	return ""
+}
+
+func (x *ReplySnippet) GetSenderName() string {
+	if x != nil {
+		return x.SenderName
+	}
+	return ""
+}
+
+func (x *ReplySnippet) GetContentType() int32 {
+	if x != nil {
+		return x.ContentType
+	}
+	return 0
+}
+
+func (x *ReplySnippet) GetPreview() string {
+	if x != nil {
+		return x.Preview
+	}
+	return ""
+}
+
+func (x *ReplySnippet) GetRecalled() bool {
+	if x != nil {
+		return x.Recalled
+	}
+	return false
+}
+
+func (x *ReplySnippet) GetSeq() int64 {
+	if x != nil {
+		return x.Seq
+	}
+	return 0
+}
+
+// ReadReceipt 已读回执，eventType=1 时打包在 Message.body 中
+type ReadReceipt struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	ConversationID string                 `protobuf:"bytes,1,opt,name=conversationID,proto3" json:"conversationID,omitempty"` // 会话ID
+	ReaderUUID     string                 `protobuf:"bytes,2,opt,name=readerUUID,proto3" json:"readerUUID,omitempty"`         // 阅读者UUID
+	Seq            int64                  `protobuf:"varint,3,opt,name=seq,proto3" json:"seq,omitempty"`                      // 已读到的消息序号（含）
+	ReadCount      int64                  `protobuf:"varint,4,opt,name=readCount,proto3" json:"readCount,omitempty"`          // 该序号消息的已读人数（私聊恒为 1）
+	ReadAt         int64                  `protobuf:"varint,5,opt,name=readAt,proto3" json:"readAt,omitempty"`                // 已读时间
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *ReadReceipt) Reset() {
+	*x = ReadReceipt{}
+	mi := &file_api_v1_message_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReadReceipt) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReadReceipt) ProtoMessage() {}
+
+func (x *ReadReceipt) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1_message_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReadReceipt.ProtoReflect.Descriptor instead.
+func (*ReadReceipt) Descriptor() ([]byte, []int) {
+	return file_api_v1_message_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ReadReceipt) GetConversationID() string {
+	if x != nil {
+		return x.ConversationID
+	}
+	return ""
+}
+
+func (x *ReadReceipt) GetReaderUUID() string {
+	if x != nil {
+		return x.ReaderUUID
+	}
+	return ""
+}
+
+func (x *ReadReceipt) GetSeq() int64 {
+	if x != nil {
+		return x.Seq
+	}
+	return 0
+}
+
+func (x *ReadReceipt) GetReadCount() int64 {
+	if x != nil {
+		return x.ReadCount
+	}
+	return 0
+}
+
+func (x *ReadReceipt) GetReadAt() int64 {
+	if x != nil {
+		return x.ReadAt
+	}
+	return 0
+}
+
+// Ack 发送确认，eventType=2 (ACK) / 3 (NACK) 时打包在 Message.body 中，只推送给发送者
+type Ack struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	ClientMsgID    string                 `protobuf:"bytes,1,opt,name=clientMsgID,proto3" json:"clientMsgID,omitempty"`       // 客户端生成的消息ID
+	MessageID      string                 `protobuf:"bytes,2,opt,name=messageID,proto3" json:"messageID,omitempty"`           // 服务端消息ID（NACK 时为空）
+	ConversationID string                 `protobuf:"bytes,3,opt,name=conversationID,proto3" json:"conversationID,omitempty"` // 会话ID
+	Seq            int64                  `protobuf:"varint,4,opt,name=seq,proto3" json:"seq,omitempty"`                      // 会话内序号（NACK 时为 0）
+	SendAt         int64                  `protobuf:"varint,5,opt,name=sendAt,proto3" json:"sendAt,omitempty"`                // 服务端入库时间
+	Reason         string                 `protobuf:"bytes,6,opt,name=reason,proto3" json:"reason,omitempty"`                 // 失败原因，仅 NACK 时有值
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *Ack) Reset() {
+	*x = Ack{}
+	mi := &file_api_v1_message_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Ack) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Ack) ProtoMessage() {}
+
+func (x *Ack) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1_message_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Ack.ProtoReflect.Descriptor instead.
+func (*Ack) Descriptor() ([]byte, []int) {
+	return file_api_v1_message_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *Ack) GetClientMsgID() string {
+	if x != nil {
+		return x.ClientMsgID
+	}
+	return ""
+}
+
+func (x *Ack) GetMessageID() string {
+	if x != nil {
+		return x.MessageID
+	}
+	return ""
+}
+
+func (x *Ack) GetConversationID() string {
+	if x != nil {
+		return x.ConversationID
+	}
+	return ""
+}
+
+func (x *Ack) GetSeq() int64 {
+	if x != nil {
+		return x.Seq
+	}
+	return 0
+}
+
+func (x *Ack) GetSendAt() int64 {
+	if x != nil {
+		return x.SendAt
+	}
+	return 0
+}
+
+func (x *Ack) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+// SyncState 单个会话的增量同步结果
+type SyncState struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	ConversationID string                 `protobuf:"bytes,1,opt,name=conversationID,proto3" json:"conversationID,omitempty"` // 会话ID
+	LastSeq        int64                  `protobuf:"varint,2,opt,name=lastSeq,proto3" json:"lastSeq,omitempty"`              // 本次已下发的最大序号，下次同步以此为游标
+	MaxSeq         int64                  `protobuf:"varint,3,opt,name=maxSeq,proto3" json:"maxSeq,omitempty"`                // 会话当前的最大序号
+	HasMore        bool                   `protobuf:"varint,4,opt,name=hasMore,proto3" json:"hasMore,omitempty"`              // 是否还有更多消息，需要以 lastSeq 继续同步
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *SyncState) Reset() {
+	*x = SyncState{}
+	mi := &file_api_v1_message_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SyncState) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SyncState) ProtoMessage() {}
+
+func (x *SyncState) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1_message_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SyncState.ProtoReflect.Descriptor instead.
+func (*SyncState) Descriptor() ([]byte, []int) {
+	return file_api_v1_message_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *SyncState) GetConversationID() string {
+	if x != nil {
+		return x.ConversationID
+	}
+	return ""
+}
+
+func (x *SyncState) GetLastSeq() int64 {
+	if x != nil {
+		return x.LastSeq
+	}
+	return 0
+}
+
+func (x *SyncState) GetMaxSeq() int64 {
+	if x != nil {
+		return x.MaxSeq
+	}
+	return 0
+}
+
+func (x *SyncState) GetHasMore() bool {
+	if x != nil {
+		return x.HasMore
+	}
+	return false
+}
+
+// SyncComplete 增量同步结束标记，eventType=4 时打包在 Message.body 中
+// 本次同步的消息会先于该事件逐条下发
+type SyncComplete struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RequestID     string                 `protobuf:"bytes,1,opt,name=requestID,proto3" json:"requestID,omitempty"`         // 客户端请求ID，原样返回
+	Conversations []*SyncState           `protobuf:"bytes,2,rep,name=conversations,proto3" json:"conversations,omitempty"` // 各会话的同步结果
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SyncComplete) Reset() {
+	*x = SyncComplete{}
+	mi := &file_api_v1_message_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SyncComplete) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SyncComplete) ProtoMessage() {}
+
+func (x *SyncComplete) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1_message_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SyncComplete.ProtoReflect.Descriptor instead.
+func (*SyncComplete) Descriptor() ([]byte, []int) {
+	return file_api_v1_message_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *SyncComplete) GetRequestID() string {
+	if x != nil {
+		return x.RequestID
+	}
+	return ""
+}
+
+func (x *SyncComplete) GetConversations() []*SyncState {
+	if x != nil {
+		return x.Conversations
+	}
+	return nil
+}
+
+// MessageRecall 消息撤回，eventType=5 时打包在 Message.body 中，推送给会话所有成员
+type MessageRecall struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	ConversationID string                 `protobuf:"bytes,1,opt,name=conversationID,proto3" json:"conversationID,omitempty"` // 会话ID
+	MessageID      string                 `protobuf:"bytes,2,opt,name=messageID,proto3" json:"messageID,omitempty"`           // 被撤回的消息ID
+	Seq            int64                  `protobuf:"varint,3,opt,name=seq,proto3" json:"seq,omitempty"`                      // 被撤回消息的序号
+	SenderUUID     string                 `protobuf:"bytes,4,opt,name=senderUUID,proto3" json:"senderUUID,omitempty"`         // 原消息发送者
+	OperatorUUID   string                 `protobuf:"bytes,5,opt,name=operatorUUID,proto3" json:"operatorUUID,omitempty"`     // 执行撤回的用户（发送者本人或群主）
+	RecalledAt     int64                  `protobuf:"varint,6,opt,name=recalledAt,proto3" json:"recalledAt,omitempty"`        // 撤回时间
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *MessageRecall) Reset() {
+	*x = MessageRecall{}
+	mi := &file_api_v1_message_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MessageRecall) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MessageRecall) ProtoMessage() {}
+
+func (x *MessageRecall) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1_message_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MessageRecall.ProtoReflect.Descriptor instead.
+func (*MessageRecall) Descriptor() ([]byte, []int) {
+	return file_api_v1_message_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *MessageRecall) GetConversationID() string {
+	if x != nil {
+		return x.ConversationID
+	}
+	return ""
+}
+
+func (x *MessageRecall) GetMessageID() string {
+	if x != nil {
+		return x.MessageID
+	}
+	return ""
+}
+
+func (x *MessageRecall) GetSeq() int64 {
+	if x != nil {
+		return x.Seq
+	}
+	return 0
+}
+
+func (x *MessageRecall) GetSenderUUID() string {
+	if x != nil {
+		return x.SenderUUID
+	}
+	return ""
+}
+
+func (x *MessageRecall) GetOperatorUUID() string {
+	if x != nil {
+		return x.OperatorUUID
+	}
+	return ""
+}
+
+func (x *MessageRecall) GetRecalledAt() int64 {
+	if x != nil {
+		return x.RecalledAt
+	}
+	return 0
+}
+
+// MessageEdit 消息编辑，eventType=6 时打包在 Message.body 中，推送给会话所有成员
+type MessageEdit struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	ConversationID string                 `protobuf:"bytes,1,opt,name=conversationID,proto3" json:"conversationID,omitempty"` // 会话ID
+	MessageID      string                 `protobuf:"bytes,2,opt,name=messageID,proto3" json:"messageID,omitempty"`           // 被编辑的消息ID
+	Seq            int64                  `protobuf:"varint,3,opt,name=seq,proto3" json:"seq,omitempty"`                      // 被编辑消息的序号
+	SenderUUID     string                 `protobuf:"bytes,4,opt,name=senderUUID,proto3" json:"senderUUID,omitempty"`         // 消息发送者（只有发送者本人可以编辑）
+	Body           *anypb.Any             `protobuf:"bytes,5,opt,name=body,proto3" json:"body,omitempty"`                     // 编辑后的消息内容（TextBody）
+	EditedAt       int64                  `protobuf:"varint,6,opt,name=editedAt,proto3" json:"editedAt,omitempty"`            // 编辑时间
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *MessageEdit) Reset() {
+	*x = MessageEdit{}
+	mi := &file_api_v1_message_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MessageEdit) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MessageEdit) ProtoMessage() {}
+
+func (x *MessageEdit) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1_message_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MessageEdit.ProtoReflect.Descriptor instead.
+func (*MessageEdit) Descriptor() ([]byte, []int) {
+	return file_api_v1_message_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *MessageEdit) GetConversationID() string {
+	if x != nil {
+		return x.ConversationID
+	}
+	return ""
+}
+
+func (x *MessageEdit) GetMessageID() string {
+	if x != nil {
+		return x.MessageID
+	}
+	return ""
+}
+
+func (x *MessageEdit) GetSeq() int64 {
+	if x != nil {
+		return x.Seq
+	}
+	return 0
+}
+
+func (x *MessageEdit) GetSenderUUID() string {
+	if x != nil {
+		return x.SenderUUID
+	}
+	return ""
+}
+
+func (x *MessageEdit) GetBody() *anypb.Any {
+	if x != nil {
+		return x.Body
+	}
+	return nil
+}
+
+func (x *MessageEdit) GetEditedAt() int64 {
+	if x != nil {
+		return x.EditedAt
+	}
+	return 0
+}
+
+// ReactionEvent 表情回应变化，eventType=7 时打包在 Message.body 中，推送给会话所有在线成员
+type ReactionEvent struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	ConversationID string                 `protobuf:"bytes,1,opt,name=conversationID,proto3" json:"conversationID,omitempty"` // 会话ID
+	MessageID      string                 `protobuf:"bytes,2,opt,name=messageID,proto3" json:"messageID,omitempty"`           // 被回应的消息ID
+	UserUUID       string                 `protobuf:"bytes,3,opt,name=userUUID,proto3" json:"userUUID,omitempty"`             // 添加或取消回应的用户
+	Emoji          string                 `protobuf:"bytes,4,opt,name=emoji,proto3" json:"emoji,omitempty"`                   // 表情
+	Added          bool                   `protobuf:"varint,5,opt,name=added,proto3" json:"added,omitempty"`                  // true=添加，false=取消
+	Count          int64                  `protobuf:"varint,6,opt,name=count,proto3" json:"count,omitempty"`                  // 变化后该表情在这条消息上的回应人数
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *ReactionEvent) Reset() {
+	*x = ReactionEvent{}
+	mi := &file_api_v1_message_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReactionEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReactionEvent) ProtoMessage() {}
+
+func (x *ReactionEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1_message_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReactionEvent.ProtoReflect.Descriptor instead.
+func (*ReactionEvent) Descriptor() ([]byte, []int) {
+	return file_api_v1_message_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *ReactionEvent) GetConversationID() string {
+	if x != nil {
+		return x.ConversationID
+	}
+	return ""
+}
+
+func (x *ReactionEvent) GetMessageID() string {
+	if x != nil {
+		return x.MessageID
+	}
+	return ""
+}
+
+func (x *ReactionEvent) GetUserUUID() string {
+	if x != nil {
+		return x.UserUUID
+	}
+	return ""
+}
+
+func (x *ReactionEvent) GetEmoji() string {
+	if x != nil {
+		return x.Emoji
+	}
+	return ""
+}
+
+func (x *ReactionEvent) GetAdded() bool {
+	if x != nil {
+		return x.Added
+	}
+	return false
+}
+
+func (x *ReactionEvent) GetCount() int64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+// ThreadUpdate 话题摘要变化，eventType=8 时打包在 Message.body 中，推送给不在话题中的会话成员
+type ThreadUpdate struct {
+	state               protoimpl.MessageState `protogen:"open.v1"`
+	ConversationID      string                 `protobuf:"bytes,1,opt,name=conversationID,proto3" json:"conversationID,omitempty"`           // 会话ID
+	RootMessageID       string                 `protobuf:"bytes,2,opt,name=rootMessageID,proto3" json:"rootMessageID,omitempty"`             // 话题根消息ID
+	ReplyCount          int64                  `protobuf:"varint,3,opt,name=replyCount,proto3" json:"replyCount,omitempty"`                  // 话题回复数
+	LastReplyAt         int64                  `protobuf:"varint,4,opt,name=lastReplyAt,proto3" json:"lastReplyAt,omitempty"`                // 最后一条回复的时间
+	LastReplySenderUUID string                 `protobuf:"bytes,5,opt,name=lastReplySenderUUID,proto3" json:"lastReplySenderUUID,omitempty"` // 最后一条回复的发送者
+	unknownFields       protoimpl.UnknownFields
+	sizeCache           protoimpl.SizeCache
+}
+
+func (x *ThreadUpdate) Reset() {
+	*x = ThreadUpdate{}
+	mi := &file_api_v1_message_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ThreadUpdate) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ThreadUpdate) ProtoMessage() {}
+
+func (x *ThreadUpdate) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1_message_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ThreadUpdate.ProtoReflect.Descriptor instead.
+func (*ThreadUpdate) Descriptor() ([]byte, []int) {
+	return file_api_v1_message_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *ThreadUpdate) GetConversationID() string {
+	if x != nil {
+		return x.ConversationID
+	}
+	return ""
+}
+
+func (x *ThreadUpdate) GetRootMessageID() string {
+	if x != nil {
+		return x.RootMessageID
+	}
+	return ""
+}
+
+func (x *ThreadUpdate) GetReplyCount() int64 {
+	if x != nil {
+		return x.ReplyCount
+	}
+	return 0
+}
+
+func (x *ThreadUpdate) GetLastReplyAt() int64 {
+	if x != nil {
+		return x.LastReplyAt
+	}
+	return 0
+}
+
+func (x *ThreadUpdate) GetLastReplySenderUUID() string {
+	if x != nil {
+		return x.LastReplySenderUUID
+	}
+	return ""
+}
+
+// PinEvent 消息置顶状态变化，eventType=9 时打包在 Message.body 中，推送给会话所有在线成员
+type PinEvent struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	ConversationID string                 `protobuf:"bytes,1,opt,name=conversationID,proto3" json:"conversationID,omitempty"` // 会话ID
+	MessageID      string                 `protobuf:"bytes,2,opt,name=messageID,proto3" json:"messageID,omitempty"`           // 被置顶或取消置顶的消息ID
+	OperatorUUID   string                 `protobuf:"bytes,3,opt,name=operatorUUID,proto3" json:"operatorUUID,omitempty"`     // 执行操作的用户
+	Pinned         bool                   `protobuf:"varint,4,opt,name=pinned,proto3" json:"pinned,omitempty"`                // true=置顶，false=取消置顶
+	PinnedAt       int64                  `protobuf:"varint,5,opt,name=pinnedAt,proto3" json:"pinnedAt,omitempty"`            // 置顶时间，取消置顶时为操作时间
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *PinEvent) Reset() {
+	*x = PinEvent{}
+	mi := &file_api_v1_message_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PinEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PinEvent) ProtoMessage() {}
+
+func (x *PinEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1_message_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PinEvent.ProtoReflect.Descriptor instead.
+func (*PinEvent) Descriptor() ([]byte, []int) {
+	return file_api_v1_message_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *PinEvent) GetConversationID() string {
+	if x != nil {
+		return x.ConversationID
+	}
+	return ""
+}
+
+func (x *PinEvent) GetMessageID() string {
+	if x != nil {
+		return x.MessageID
+	}
+	return ""
+}
+
+func (x *PinEvent) GetOperatorUUID() string {
+	if x != nil {
+		return x.OperatorUUID
+	}
+	return ""
+}
+
+func (x *PinEvent) GetPinned() bool {
+	if x != nil {
+		return x.Pinned
+	}
+	return false
+}
+
+func (x *PinEvent) GetPinnedAt() int64 {
+	if x != nil {
+		return x.PinnedAt
+	}
+	return 0
+}
+
+// TypingEvent 正在输入状态，eventType=10 时打包在 Message.body 中，只推送给会话的其他在线成员，不入库
+type TypingEvent struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	ConversationID string                 `protobuf:"bytes,1,opt,name=conversationID,proto3" json:"conversationID,omitempty"` // 会话ID
+	UserUUID       string                 `protobuf:"bytes,2,opt,name=userUUID,proto3" json:"userUUID,omitempty"`             // 正在输入的用户
+	UserName       string                 `protobuf:"bytes,3,opt,name=userName,proto3" json:"userName,omitempty"`             // 正在输入的用户名
+	Typing         bool                   `protobuf:"varint,4,opt,name=typing,proto3" json:"typing,omitempty"`                // true=正在输入，false=停止输入
+	ExpireAt       int64                  `protobuf:"varint,5,opt,name=expireAt,proto3" json:"expireAt,omitempty"`            // 到期时间，客户端在此之前没有收到新的输入状态时自动清除
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *TypingEvent) Reset() {
+	*x = TypingEvent{}
+	mi := &file_api_v1_message_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TypingEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TypingEvent) ProtoMessage() {}
+
+func (x *TypingEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1_message_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TypingEvent.ProtoReflect.Descriptor instead.
+func (*TypingEvent) Descriptor() ([]byte, []int) {
+	return file_api_v1_message_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *TypingEvent) GetConversationID() string {
+	if x != nil {
+		return x.ConversationID
+	}
+	return ""
+}
+
+func (x *TypingEvent) GetUserUUID() string {
+	if x != nil {
+		return x.UserUUID
+	}
+	return ""
+}
+
+func (x *TypingEvent) GetUserName() string {
+	if x != nil {
+		return x.UserName
+	}
+	return ""
+}
+
+func (x *TypingEvent) GetTyping() bool {
+	if x != nil {
+		return x.Typing
+	}
+	return false
+}
+
+func (x *TypingEvent) GetExpireAt() int64 {
+	if x != nil {
+		return x.ExpireAt
+	}
+	return 0
+}
+
+// PresenceEvent 好友的在线状态变化，eventType=11 时打包在 Message.body 中，只推送给在线的好友
+type PresenceEvent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserUUID      string                 `protobuf:"bytes,1,opt,name=userUUID,proto3" json:"userUUID,omitempty"`  // 状态变化的用户
+	Status        string                 `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`      // online / away / offline（隐身的用户显示为 offline）
+	LastSeen      int64                  `protobuf:"varint,3,opt,name=lastSeen,proto3" json:"lastSeen,omitempty"` // 最后在线时间
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PresenceEvent) Reset() {
+	*x = PresenceEvent{}
+	mi := &file_api_v1_message_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PresenceEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PresenceEvent) ProtoMessage() {}
+
+func (x *PresenceEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1_message_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PresenceEvent.ProtoReflect.Descriptor instead.
+func (*PresenceEvent) Descriptor() ([]byte, []int) {
+	return file_api_v1_message_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *PresenceEvent) GetUserUUID() string {
+	if x != nil {
+		return x.UserUUID
+	}
+	return ""
+}
+
+func (x *PresenceEvent) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *PresenceEvent) GetLastSeen() int64 {
+	if x != nil {
+		return x.LastSeen
+	}
+	return 0
+}
+
+// Envelope WebSocket 上行命令信封，以二进制帧发送；JSON 客户端使用字段相同的 JSON 控制帧
+// 解析不出信封（或命令类型未知）的二进制帧按旧格式当作单条 Message 处理
+// 带 requestID 的命令会收到以 requestID 为 clientMsgID 的 ACK（eventType=2）或 NACK（eventType=3），增量同步以 SyncComplete 结束
+type Envelope struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Type      string                 `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`           // 命令类型：message / offline_ack / sync / read / recall / edit / react / typing / presence / ping，与载荷对应
+	RequestID string                 `protobuf:"bytes,2,opt,name=requestID,proto3" json:"requestID,omitempty"` // 客户端生成的请求ID，响应中原样返回
+	// Types that are valid to be assigned to Payload:
+	//
+	//	*Envelope_Message
+	//	*Envelope_OfflineAck
+	//	*Envelope_Sync
+	//	*Envelope_Read
+	//	*Envelope_Recall
+	//	*Envelope_Edit
+	//	*Envelope_React
+	//	*Envelope_Typing
+	//	*Envelope_Presence
+	Payload       isEnvelope_Payload `protobuf_oneof:"payload"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Envelope) Reset() {
+	*x = Envelope{}
+	mi := &file_api_v1_message_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Envelope) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Envelope) ProtoMessage() {}
+
+func (x *Envelope) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1_message_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Envelope.ProtoReflect.Descriptor instead.
+func (*Envelope) Descriptor() ([]byte, []int) {
+	return file_api_v1_message_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *Envelope) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Envelope) GetRequestID() string {
+	if x != nil {
+		return x.RequestID
+	}
+	return ""
+}
+
+func (x *Envelope) GetPayload() isEnvelope_Payload {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+func (x *Envelope) GetMessage() *Message {
+	if x != nil {
+		if x, ok := x.Payload.(*Envelope_Message); ok {
+			return x.Message
+		}
+	}
+	return nil
+}
+
+func (x *Envelope) GetOfflineAck() *OfflineAckCommand {
+	if x != nil {
+		if x, ok := x.Payload.(*Envelope_OfflineAck); ok {
+			return x.OfflineAck
+		}
+	}
+	return nil
+}
+
+func (x *Envelope) GetSync() *SyncCommand {
+	if x != nil {
+		if x, ok := x.Payload.(*Envelope_Sync); ok {
+			return x.Sync
+		}
+	}
+	return nil
+}
+
+func (x *Envelope) GetRead() *ReadCommand {
+	if x != nil {
+		if x, ok := x.Payload.(*Envelope_Read); ok {
+			return x.Read
+		}
+	}
+	return nil
+}
+
+func (x *Envelope) GetRecall() *RecallCommand {
+	if x != nil {
+		if x, ok := x.Payload.(*Envelope_Recall); ok {
+			return x.Recall
+		}
+	}
+	return nil
+}
+
+func (x *Envelope) GetEdit() *EditCommand {
+	if x != nil {
+		if x, ok := x.Payload.(*Envelope_Edit); ok {
+			return x.Edit
+		}
+	}
+	return nil
+}
+
+func (x *Envelope) GetReact() *ReactCommand {
+	if x != nil {
+		if x, ok := x.Payload.(*Envelope_React); ok {
+			return x.React
+		}
+	}
+	return nil
+}
+
+func (x *Envelope) GetTyping() *TypingCommand {
+	if x != nil {
+		if x, ok := x.Payload.(*Envelope_Typing); ok {
+			return x.Typing
+		}
+	}
+	return nil
+}
+
+func (x *Envelope) GetPresence() *PresenceCommand {
+	if x != nil {
+		if x, ok := x.Payload.(*Envelope_Presence); ok {
+			return x.Presence
+		}
+	}
+	return nil
+}
+
+type isEnvelope_Payload interface {
+	isEnvelope_Payload()
+}
+
+type Envelope_Message struct {
+	Message *Message `protobuf:"bytes,3,opt,name=message,proto3,oneof"` // 发送聊天消息，clientMsgID 为空时使用 requestID
+}
+
+type Envelope_OfflineAck struct {
+	OfflineAck *OfflineAckCommand `protobuf:"bytes,4,opt,name=offlineAck,proto3,oneof"` // 确认已收到离线消息
+}
+
+type Envelope_Sync struct {
+	Sync *SyncCommand `protobuf:"bytes,5,opt,name=sync,proto3,oneof"` // 按序号增量同步
+}
+
+type Envelope_Read struct {
+	Read *ReadCommand `protobuf:"bytes,6,opt,name=read,proto3,oneof"` // 推进会话已读位置
+}
+
+type Envelope_Recall struct {
+	Recall *RecallCommand `protobuf:"bytes,7,opt,name=recall,proto3,oneof"` // 撤回消息
+}
+
+type Envelope_Edit struct {
+	Edit *EditCommand `protobuf:"bytes,8,opt,name=edit,proto3,oneof"` // 编辑文本消息
+}
+
+type Envelope_React struct {
+	React *ReactCommand `protobuf:"bytes,9,opt,name=react,proto3,oneof"` // 添加或取消表情回应
+}
+
+type Envelope_Typing struct {
+	Typing *TypingCommand `protobuf:"bytes,10,opt,name=typing,proto3,oneof"` // 正在输入
+}
+
+type Envelope_Presence struct {
+	Presence *PresenceCommand `protobuf:"bytes,11,opt,name=presence,proto3,oneof"` // 切换在线状态
+}
+
+func (*Envelope_Message) isEnvelope_Payload() {}
+
+func (*Envelope_OfflineAck) isEnvelope_Payload() {}
+
+func (*Envelope_Sync) isEnvelope_Payload() {}
+
+func (*Envelope_Read) isEnvelope_Payload() {}
+
+func (*Envelope_Recall) isEnvelope_Payload() {}
+
+func (*Envelope_Edit) isEnvelope_Payload() {}
+
+func (*Envelope_React) isEnvelope_Payload() {}
+
+func (*Envelope_Typing) isEnvelope_Payload() {}
+
+func (*Envelope_Presence) isEnvelope_Payload() {}
+
+// OfflineAckCommand 确认已收到离线消息
+type OfflineAckCommand struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ids           []string               `protobuf:"bytes,1,rep,name=ids,proto3" json:"ids,omitempty"` // 离线消息ID
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *OfflineAckCommand) Reset() {
+	*x = OfflineAckCommand{}
+	mi := &file_api_v1_message_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OfflineAckCommand) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OfflineAckCommand) ProtoMessage() {}
+
+func (x *OfflineAckCommand) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1_message_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OfflineAckCommand.ProtoReflect.Descriptor instead.
+func (*OfflineAckCommand) Descriptor() ([]byte, []int) {
+	return file_api_v1_message_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *OfflineAckCommand) GetIds() []string {
+	if x != nil {
+		return x.Ids
+	}
+	return nil
+}
+
+// SyncCursor 单个会话的同步起点
+type SyncCursor struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	ConversationID string                 `protobuf:"bytes,1,opt,name=conversationID,proto3" json:"conversationID,omitempty"` // 会话ID
+	Seq            int64                  `protobuf:"varint,2,opt,name=seq,proto3" json:"seq,omitempty"`                      // 客户端已有的最大序号
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *SyncCursor) Reset() {
+	*x = SyncCursor{}
+	mi := &file_api_v1_message_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SyncCursor) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SyncCursor) ProtoMessage() {}
+
+func (x *SyncCursor) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1_message_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SyncCursor.ProtoReflect.Descriptor instead.
+func (*SyncCursor) Descriptor() ([]byte, []int) {
+	return file_api_v1_message_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *SyncCursor) GetConversationID() string {
+	if x != nil {
+		return x.ConversationID
+	}
+	return ""
+}
+
+func (x *SyncCursor) GetSeq() int64 {
+	if x != nil {
+		return x.Seq
+	}
+	return 0
+}
+
+// SyncCommand 按序号增量同步
+type SyncCommand struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Conversations []*SyncCursor          `protobuf:"bytes,1,rep,name=conversations,proto3" json:"conversations,omitempty"` // 需要同步的会话
+	Limit         int32                  `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`                // 每个会话最多返回的消息数，0 表示默认值
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SyncCommand) Reset() {
+	*x = SyncCommand{}
+	mi := &file_api_v1_message_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SyncCommand) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SyncCommand) ProtoMessage() {}
+
+func (x *SyncCommand) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1_message_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SyncCommand.ProtoReflect.Descriptor instead.
+func (*SyncCommand) Descriptor() ([]byte, []int) {
+	return file_api_v1_message_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *SyncCommand) GetConversations() []*SyncCursor {
+	if x != nil {
+		return x.Conversations
+	}
+	return nil
+}
+
+func (x *SyncCommand) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+// ReadCommand 推进会话已读位置，seq 与 messageID 二选一
+type ReadCommand struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	ConversationID string                 `protobuf:"bytes,1,opt,name=conversationID,proto3" json:"conversationID,omitempty"` // 会话ID
+	Seq            int64                  `protobuf:"varint,2,opt,name=seq,proto3" json:"seq,omitempty"`                      // 已读到的序号
+	MessageID      string                 `protobuf:"bytes,3,opt,name=messageID,proto3" json:"messageID,omitempty"`           // 已读到的消息ID
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *ReadCommand) Reset() {
+	*x = ReadCommand{}
+	mi := &file_api_v1_message_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReadCommand) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReadCommand) ProtoMessage() {}
+
+func (x *ReadCommand) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1_message_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReadCommand.ProtoReflect.Descriptor instead.
+func (*ReadCommand) Descriptor() ([]byte, []int) {
+	return file_api_v1_message_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *ReadCommand) GetConversationID() string {
+	if x != nil {
+		return x.ConversationID
+	}
+	return ""
+}
+
+func (x *ReadCommand) GetSeq() int64 {
+	if x != nil {
+		return x.Seq
+	}
+	return 0
+}
+
+func (x *ReadCommand) GetMessageID() string {
+	if x != nil {
+		return x.MessageID
+	}
+	return ""
+}
+
+// RecallCommand 撤回消息
+type RecallCommand struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	MessageID     string                 `protobuf:"bytes,1,opt,name=messageID,proto3" json:"messageID,omitempty"` // 被撤回的消息ID
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RecallCommand) Reset() {
+	*x = RecallCommand{}
+	mi := &file_api_v1_message_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RecallCommand) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RecallCommand) ProtoMessage() {}
+
+func (x *RecallCommand) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1_message_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RecallCommand.ProtoReflect.Descriptor instead.
+func (*RecallCommand) Descriptor() ([]byte, []int) {
+	return file_api_v1_message_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *RecallCommand) GetMessageID() string {
+	if x != nil {
+		return x.MessageID
+	}
+	return ""
+}
+
+// EditCommand 编辑文本消息
+type EditCommand struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	MessageID     string                 `protobuf:"bytes,1,opt,name=messageID,proto3" json:"messageID,omitempty"` // 被编辑的消息ID
+	Content       string                 `protobuf:"bytes,2,opt,name=content,proto3" json:"content,omitempty"`     // 编辑后的文本
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EditCommand) Reset() {
+	*x = EditCommand{}
+	mi := &file_api_v1_message_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EditCommand) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EditCommand) ProtoMessage() {}
+
+func (x *EditCommand) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1_message_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EditCommand.ProtoReflect.Descriptor instead.
+func (*EditCommand) Descriptor() ([]byte, []int) {
+	return file_api_v1_message_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *EditCommand) GetMessageID() string {
+	if x != nil {
+		return x.MessageID
+	}
+	return ""
+}
+
+func (x *EditCommand) GetContent() string {
+	if x != nil {
+		return x.Content
+	}
+	return ""
+}
+
+// ReactCommand 添加或取消表情回应
+type ReactCommand struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	MessageID     string                 `protobuf:"bytes,1,opt,name=messageID,proto3" json:"messageID,omitempty"` // 被回应的消息ID
+	Emoji         string                 `protobuf:"bytes,2,opt,name=emoji,proto3" json:"emoji,omitempty"`         // 表情
+	Remove        bool                   `protobuf:"varint,3,opt,name=remove,proto3" json:"remove,omitempty"`      // true=取消回应
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReactCommand) Reset() {
+	*x = ReactCommand{}
+	mi := &file_api_v1_message_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReactCommand) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReactCommand) ProtoMessage() {}
+
+func (x *ReactCommand) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1_message_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReactCommand.ProtoReflect.Descriptor instead.
+func (*ReactCommand) Descriptor() ([]byte, []int) {
+	return file_api_v1_message_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *ReactCommand) GetMessageID() string {
+	if x != nil {
+		return x.MessageID
+	}
+	return ""
+}
+
+func (x *ReactCommand) GetEmoji() string {
+	if x != nil {
+		return x.Emoji
+	}
+	return ""
+}
+
+func (x *ReactCommand) GetRemove() bool {
+	if x != nil {
+		return x.Remove
+	}
+	return false
+}
+
+// TypingCommand 正在输入
+type TypingCommand struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	ConversationID string                 `protobuf:"bytes,1,opt,name=conversationID,proto3" json:"conversationID,omitempty"` // 会话ID
+	Stop           bool                   `protobuf:"varint,2,opt,name=stop,proto3" json:"stop,omitempty"`                    // true=停止输入
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *TypingCommand) Reset() {
+	*x = TypingCommand{}
+	mi := &file_api_v1_message_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TypingCommand) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TypingCommand) ProtoMessage() {}
+
+func (x *TypingCommand) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1_message_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TypingCommand.ProtoReflect.Descriptor instead.
+func (*TypingCommand) Descriptor() ([]byte, []int) {
+	return file_api_v1_message_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *TypingCommand) GetConversationID() string {
+	if x != nil {
+		return x.ConversationID
+	}
+	return ""
+}
+
+func (x *TypingCommand) GetStop() bool {
+	if x != nil {
+		return x.Stop
+	}
+	return false
+}
+
+// PresenceCommand 切换在线状态
+type PresenceCommand struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Status        string                 `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"` // online / away / invisible
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PresenceCommand) Reset() {
+	*x = PresenceCommand{}
+	mi := &file_api_v1_message_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PresenceCommand) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PresenceCommand) ProtoMessage() {}
+
+func (x *PresenceCommand) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1_message_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PresenceCommand.ProtoReflect.Descriptor instead.
+func (*PresenceCommand) Descriptor() ([]byte, []int) {
+	return file_api_v1_message_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *PresenceCommand) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+var File_api_v1_message_proto protoreflect.FileDescriptor
+
+const file_api_v1_message_proto_rawDesc = "" +
+	"\n" +
+	"\x14api/v1/message.proto\x12\x02v1\x1a\x19google/protobuf/any.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\xe7\x06\n" +
+	"\aMessage\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12&\n" +
+	"\x0econversationID\x18\x02 \x01(\tR\x0econversationID\x12\x1e\n" +
+	"\n" +
+	"senderUUID\x18\x03 \x01(\tR\n" +
+	"senderUUID\x12\x16\n" +
+	"\x06sendAt\x18\x04 \x01(\x03R\x06sendAt\x12 \n" +
+	"\vcontentType\x18\x05 \x01(\x05R\vcontentType\x12(\n" +
+	"\x04body\x18\x06 \x01(\v2\x14.google.protobuf.AnyR\x04body\x12/\n" +
+	"\bmetadata\x18\a \x01(\v2\x13.v1.MessageMetadataR\bmetadata\x129\n" +
+	"\n" +
+	"deleted_at\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\tdeletedAt\x12\x10\n" +
+	"\x03seq\x18\r \x01(\x03R\x03seq\x12\x1c\n" +
+	"\teventType\x18\x0e \x01(\x05R\teventType\x12 \n" +
+	"\vclientMsgID\x18\x0f \x01(\tR\vclientMsgID\x12\x18\n" +
+	"\aoffline\x18\x10 \x01(\bR\aoffline\x12&\n" +
+	"\x0etargetDeviceID\x18\x11 \x01(\tR\x0etargetDeviceID\x12&\n" +
+	"\x0esenderDeviceID\x18\x12 \x01(\tR\x0esenderDeviceID\x12\x1a\n" +
+	"\brecalled\x18\x13 \x01(\bR\brecalled\x12\x16\n" +
+	"\x06edited\x18\x14 \x01(\bR\x06edited\x12\x1a\n" +
+	"\beditedAt\x18\x15 \x01(\x03R\beditedAt\x12\x1c\n" +
+	"\tmentioned\x18\x16 \x01(\bR\tmentioned\x12\x14\n" +
+	"\x05muted\x18\x17 \x01(\bR\x05muted\x12*\n" +
+	"\x10threadReplyCount\x18\x18 \x01(\x03R\x10threadReplyCount\x12,\n" +
+	"\x11threadLastReplyAt\x18\x19 \x01(\x03R\x11threadLastReplyAt\x12\x1a\n" +
+	"\bexpireAt\x18\x1a \x01(\x03R\bexpireAt\x12\x1e\n" +
+	"\n" +
+	"senderName\x18\t \x01(\tR\n" +
+	"senderName\x12\x16\n" +
+	"\x06avatar\x18\n" +
+	" \x01(\tR\x06avatar\x12 \n" +
+	"\vmessageType\x18\v \x01(\x05R\vmessageType\x12$\n" +
+	"\rrecipientUUID\x18\f \x01(\tR\rrecipientUUID\"$\n" +
+	"\bTextBody\x12\x18\n" +
+	"\acontent\x18\x01 \x01(\tR\acontent\"n\n" +
+	"\x0eFileAttachment\x12\x10\n" +
+	"\x03url\x18\x01 \x01(\tR\x03url\x12\x1a\n" +
+	"\bfileName\x18\x02 \x01(\tR\bfileName\x12\x12\n" +
+	"\x04size\x18\x03 \x01(\x03R\x04size\x12\x1a\n" +
+	"\bmimeType\x18\x04 \x01(\tR\bmimeType\"\xfc\x01\n" +
+	"\x0fMessageMetadata\x12\"\n" +
+	"\freplyToMsgID\x18\x01 \x01(\tR\freplyToMsgID\x12&\n" +
+	"\x05reply\x18\x02 \x01(\v2\x10.v1.ReplySnippetR\x05reply\x12\"\n" +
+	"\fmentionUUIDs\x18\x03 \x03(\tR\fmentionUUIDs\x12\x1e\n" +
+	"\n" +
+	"mentionAll\x18\x04 \x01(\bR\n" +
+	"mentionAll\x12\"\n" +
+	"\fthreadRootID\x18\x05 \x01(\tR\fthreadRootID\x125\n" +
+	"\rforwardedFrom\x18\x06 \x01(\v2\x0f.v1.ForwardInfoR\rforwardedFrom\"\xab\x01\n" +
+	"\vForwardInfo\x12\x1c\n" +
+	"\tmessageID\x18\x01 \x01(\tR\tmessageID\x12&\n" +
+	"\x0econversationID\x18\x02 \x01(\tR\x0econversationID\x12\x1e\n" +
+	"\n" +
+	"senderUUID\x18\x03 \x01(\tR\n" +
+	"senderUUID\x12\x1e\n" +
+	"\n" +
+	"senderName\x18\x04 \x01(\tR\n" +
+	"senderName\x12\x16\n" +
+	"\x06sendAt\x18\x05 \x01(\x03R\x06sendAt\"\xd6\x01\n" +
+	"\fReplySnippet\x12\x1c\n" +
+	"\tmessageID\x18\x01 \x01(\tR\tmessageID\x12\x1e\n" +
+	"\n" +
+	"senderUUID\x18\x02 \x01(\tR\n" +
+	"senderUUID\x12\x1e\n" +
+	"\n" +
+	"senderName\x18\x03 \x01(\tR\n" +
+	"senderName\x12 \n" +
+	"\vcontentType\x18\x04 \x01(\x05R\vcontentType\x12\x18\n" +
+	"\apreview\x18\x05 \x01(\tR\apreview\x12\x1a\n" +
+	"\brecalled\x18\x06 \x01(\bR\brecalled\x12\x10\n" +
+	"\x03seq\x18\a \x01(\x03R\x03seq\"\x9d\x01\n" +
+	"\vReadReceipt\x12&\n" +
+	"\x0econversationID\x18\x01 \x01(\tR\x0econversationID\x12\x1e\n" +
+	"\n" +
+	"readerUUID\x18\x02 \x01(\tR\n" +
+	"readerUUID\x12\x10\n" +
+	"\x03seq\x18\x03 \x01(\x03R\x03seq\x12\x1c\n" +
+	"\treadCount\x18\x04 \x01(\x03R\treadCount\x12\x16\n" +
+	"\x06readAt\x18\x05 \x01(\x03R\x06readAt\"\xaf\x01\n" +
+	"\x03Ack\x12 \n" +
+	"\vclientMsgID\x18\x01 \x01(\tR\vclientMsgID\x12\x1c\n" +
+	"\tmessageID\x18\x02 \x01(\tR\tmessageID\x12&\n" +
+	"\x0econversationID\x18\x03 \x01(\tR\x0econversationID\x12\x10\n" +
+	"\x03seq\x18\x04 \x01(\x03R\x03seq\x12\x16\n" +
+	"\x06sendAt\x18\x05 \x01(\x03R\x06sendAt\x12\x16\n" +
+	"\x06reason\x18\x06 \x01(\tR\x06reason\"\x7f\n" +
+	"\tSyncState\x12&\n" +
+	"\x0econversationID\x18\x01 \x01(\tR\x0econversationID\x12\x18\n" +
+	"\alastSeq\x18\x02 \x01(\x03R\alastSeq\x12\x16\n" +
+	"\x06maxSeq\x18\x03 \x01(\x03R\x06maxSeq\x12\x18\n" +
+	"\ahasMore\x18\x04 \x01(\bR\ahasMore\"a\n" +
+	"\fSyncComplete\x12\x1c\n" +
+	"\trequestID\x18\x01 \x01(\tR\trequestID\x123\n" +
+	"\rconversations\x18\x02 \x03(\v2\r.v1.SyncStateR\rconversations\"\xcb\x01\n" +
+	"\rMessageRecall\x12&\n" +
+	"\x0econversationID\x18\x01 \x01(\tR\x0econversationID\x12\x1c\n" +
+	"\tmessageID\x18\x02 \x01(\tR\tmessageID\x12\x10\n" +
+	"\x03seq\x18\x03 \x01(\x03R\x03seq\x12\x1e\n" +
+	"\n" +
+	"senderUUID\x18\x04 \x01(\tR\n" +
+	"senderUUID\x12\"\n" +
+	"\foperatorUUID\x18\x05 \x01(\tR\foperatorUUID\x12\x1e\n" +
+	"\n" +
+	"recalledAt\x18\x06 \x01(\x03R\n" +
+	"recalledAt\"\xcb\x01\n" +
+	"\vMessageEdit\x12&\n" +
+	"\x0econversationID\x18\x01 \x01(\tR\x0econversationID\x12\x1c\n" +
+	"\tmessageID\x18\x02 \x01(\tR\tmessageID\x12\x10\n" +
+	"\x03seq\x18\x03 \x01(\x03R\x03seq\x12\x1e\n" +
+	"\n" +
+	"senderUUID\x18\x04 \x01(\tR\n" +
+	"senderUUID\x12(\n" +
+	"\x04body\x18\x05 \x01(\v2\x14.google.protobuf.AnyR\x04body\x12\x1a\n" +
+	"\beditedAt\x18\x06 \x01(\x03R\beditedAt\"\xb3\x01\n" +
+	"\rReactionEvent\x12&\n" +
+	"\x0econversationID\x18\x01 \x01(\tR\x0econversationID\x12\x1c\n" +
+	"\tmessageID\x18\x02 \x01(\tR\tmessageID\x12\x1a\n" +
+	"\buserUUID\x18\x03 \x01(\tR\buserUUID\x12\x14\n" +
+	"\x05emoji\x18\x04 \x01(\tR\x05emoji\x12\x14\n" +
+	"\x05added\x18\x05 \x01(\bR\x05added\x12\x14\n" +
+	"\x05count\x18\x06 \x01(\x03R\x05count\"\xd0\x01\n" +
+	"\fThreadUpdate\x12&\n" +
+	"\x0econversationID\x18\x01 \x01(\tR\x0econversationID\x12$\n" +
+	"\rrootMessageID\x18\x02 \x01(\tR\rrootMessageID\x12\x1e\n" +
+	"\n" +
+	"replyCount\x18\x03 \x01(\x03R\n" +
+	"replyCount\x12 \n" +
+	"\vlastReplyAt\x18\x04 \x01(\x03R\vlastReplyAt\x120\n" +
+	"\x13lastReplySenderUUID\x18\x05 \x01(\tR\x13lastReplySenderUUID\"\xa8\x01\n" +
+	"\bPinEvent\x12&\n" +
+	"\x0econversationID\x18\x01 \x01(\tR\x0econversationID\x12\x1c\n" +
+	"\tmessageID\x18\x02 \x01(\tR\tmessageID\x12\"\n" +
+	"\foperatorUUID\x18\x03 \x01(\tR\foperatorUUID\x12\x16\n" +
+	"\x06pinned\x18\x04 \x01(\bR\x06pinned\x12\x1a\n" +
+	"\bpinnedAt\x18\x05 \x01(\x03R\bpinnedAt\"\xa1\x01\n" +
+	"\vTypingEvent\x12&\n" +
+	"\x0econversationID\x18\x01 \x01(\tR\x0econversationID\x12\x1a\n" +
+	"\buserUUID\x18\x02 \x01(\tR\buserUUID\x12\x1a\n" +
+	"\buserName\x18\x03 \x01(\tR\buserName\x12\x16\n" +
+	"\x06typing\x18\x04 \x01(\bR\x06typing\x12\x1a\n" +
+	"\bexpireAt\x18\x05 \x01(\x03R\bexpireAt\"_\n" +
+	"\rPresenceEvent\x12\x1a\n" +
+	"\buserUUID\x18\x01 \x01(\tR\buserUUID\x12\x16\n" +
+	"\x06status\x18\x02 \x01(\tR\x06status\x12\x1a\n" +
+	"\blastSeen\x18\x03 \x01(\x03R\blastSeen\"\xd5\x03\n" +
+	"\bEnvelope\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\tR\x04type\x12\x1c\n" +
+	"\trequestID\x18\x02 \x01(\tR\trequestID\x12'\n" +
+	"\amessage\x18\x03 \x01(\v2\v.v1.MessageH\x00R\amessage\x127\n" +
+	"\n" +
+	"offlineAck\x18\x04 \x01(\v2\x15.v1.OfflineAckCommandH\x00R\n" +
+	"offlineAck\x12%\n" +
+	"\x04sync\x18\x05 \x01(\v2\x0f.v1.SyncCommandH\x00R\x04sync\x12%\n" +
+	"\x04read\x18\x06 \x01(\v2\x0f.v1.ReadCommandH\x00R\x04read\x12+\n" +
+	"\x06recall\x18\a \x01(\v2\x11.v1.RecallCommandH\x00R\x06recall\x12%\n" +
+	"\x04edit\x18\b \x01(\v2\x0f.v1.EditCommandH\x00R\x04edit\x12(\n" +
+	"\x05react\x18\t \x01(\v2\x10.v1.ReactCommandH\x00R\x05react\x12+\n" +
+	"\x06typing\x18\n" +
+	" \x01(\v2\x11.v1.TypingCommandH\x00R\x06typing\x121\n" +
+	"\bpresence\x18\v \x01(\v2\x13.v1.PresenceCommandH\x00R\bpresenceB\t\n" +
+	"\apayload\"%\n" +
+	"\x11OfflineAckCommand\x12\x10\n" +
+	"\x03ids\x18\x01 \x03(\tR\x03ids\"F\n" +
+	"\n" +
+	"SyncCursor\x12&\n" +
+	"\x0econversationID\x18\x01 \x01(\tR\x0econversationID\x12\x10\n" +
+	"\x03seq\x18\x02 \x01(\x03R\x03seq\"Y\n" +
+	"\vSyncCommand\x124\n" +
+	"\rconversations\x18\x01 \x03(\v2\x0e.v1.SyncCursorR\rconversations\x12\x14\n" +
+	"\x05limit\x18\x02 \x01(\x05R\x05limit\"e\n" +
+	"\vReadCommand\x12&\n" +
+	"\x0econversationID\x18\x01 \x01(\tR\x0econversationID\x12\x10\n" +
+	"\x03seq\x18\x02 \x01(\x03R\x03seq\x12\x1c\n" +
+	"\tmessageID\x18\x03 \x01(\tR\tmessageID\"-\n" +
+	"\rRecallCommand\x12\x1c\n" +
+	"\tmessageID\x18\x01 \x01(\tR\tmessageID\"E\n" +
+	"\vEditCommand\x12\x1c\n" +
+	"\tmessageID\x18\x01 \x01(\tR\tmessageID\x12\x18\n" +
+	"\acontent\x18\x02 \x01(\tR\acontent\"Z\n" +
+	"\fReactCommand\x12\x1c\n" +
+	"\tmessageID\x18\x01 \x01(\tR\tmessageID\x12\x14\n" +
+	"\x05emoji\x18\x02 \x01(\tR\x05emoji\x12\x16\n" +
+	"\x06remove\x18\x03 \x01(\bR\x06remove\"K\n" +
+	"\rTypingCommand\x12&\n" +
+	"\x0econversationID\x18\x01 \x01(\tR\x0econversationID\x12\x12\n" +
+	"\x04stop\x18\x02 \x01(\bR\x04stop\")\n" +
+	"\x0fPresenceCommand\x12\x16\n" +
+	"\x06status\x18\x01 \x01(\tR\x06statusB\x17Z\x15MyGoChat/pkg/pb/v1;pbb\x06proto3"
+
+var (
+	file_api_v1_message_proto_rawDescOnce sync.Once
+	file_api_v1_message_proto_rawDescData []byte
+)
+
+func file_api_v1_message_proto_rawDescGZIP() []byte {
+	file_api_v1_message_proto_rawDescOnce.Do(func() {
+		file_api_v1_message_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_api_v1_message_proto_rawDesc), len(file_api_v1_message_proto_rawDesc)))
+	})
+	return file_api_v1_message_proto_rawDescData
+}
+
+var file_api_v1_message_proto_msgTypes = make([]protoimpl.MessageInfo, 27)
+var file_api_v1_message_proto_goTypes = []any{
+	(*Message)(nil),               // 0: v1.Message
+	(*TextBody)(nil),              // 1: v1.TextBody
+	(*FileAttachment)(nil),        // 2: v1.FileAttachment
+	(*MessageMetadata)(nil),       // 3: v1.MessageMetadata
+	(*ForwardInfo)(nil),           // 4: v1.ForwardInfo
+	(*ReplySnippet)(nil),          // 5: v1.ReplySnippet
+	(*ReadReceipt)(nil),           // 6: v1.ReadReceipt
+	(*Ack)(nil),                   // 7: v1.Ack
+	(*SyncState)(nil),             // 8: v1.SyncState
+	(*SyncComplete)(nil),          // 9: v1.SyncComplete
+	(*MessageRecall)(nil),         // 10: v1.MessageRecall
+	(*MessageEdit)(nil),           // 11: v1.MessageEdit
+	(*ReactionEvent)(nil),         // 12: v1.ReactionEvent
+	(*ThreadUpdate)(nil),          // 13: v1.ThreadUpdate
+	(*PinEvent)(nil),              // 14: v1.PinEvent
+	(*TypingEvent)(nil),           // 15: v1.TypingEvent
+	(*PresenceEvent)(nil),         // 16: v1.PresenceEvent
+	(*Envelope)(nil),              // 17: v1.Envelope
+	(*OfflineAckCommand)(nil),     // 18: v1.OfflineAckCommand
+	(*SyncCursor)(nil),            // 19: v1.SyncCursor
+	(*SyncCommand)(nil),           // 20: v1.SyncCommand
+	(*ReadCommand)(nil),           // 21: v1.ReadCommand
+	(*RecallCommand)(nil),         // 22: v1.RecallCommand
+	(*EditCommand)(nil),           // 23: v1.EditCommand
+	(*ReactCommand)(nil),          // 24: v1.ReactCommand
+	(*TypingCommand)(nil),         // 25: v1.TypingCommand
+	(*PresenceCommand)(nil),       // 26: v1.PresenceCommand
+	(*anypb.Any)(nil),             // 27: google.protobuf.Any
+	(*timestamppb.Timestamp)(nil), // 28: google.protobuf.Timestamp
+}
+var file_api_v1_message_proto_depIdxs = []int32{
+	27, // 0: v1.Message.body:type_name -> google.protobuf.Any
+	3,  // 1: v1.Message.metadata:type_name -> v1.MessageMetadata
+	28, // 2: v1.Message.deleted_at:type_name -> google.protobuf.Timestamp
+	5,  // 3: v1.MessageMetadata.reply:type_name -> v1.ReplySnippet
+	4,  // 4: v1.MessageMetadata.forwardedFrom:type_name -> v1.ForwardInfo
+	8,  // 5: v1.SyncComplete.conversations:type_name -> v1.SyncState
+	27, // 6: v1.MessageEdit.body:type_name -> google.protobuf.Any
+	0,  // 7: v1.Envelope.message:type_name -> v1.Message
+	18, // 8: v1.Envelope.offlineAck:type_name -> v1.OfflineAckCommand
+	20, // 9: v1.Envelope.sync:type_name -> v1.SyncCommand
+	21, // 10: v1.Envelope.read:type_name -> v1.ReadCommand
+	22, // 11: v1.Envelope.recall:type_name -> v1.RecallCommand
+	23, // 12: v1.Envelope.edit:type_name -> v1.EditCommand
+	24, // 13: v1.Envelope.react:type_name -> v1.ReactCommand
+	25, // 14: v1.Envelope.typing:type_name -> v1.TypingCommand
+	26, // 15: v1.Envelope.presence:type_name -> v1.PresenceCommand
+	19, // 16: v1.SyncCommand.conversations:type_name -> v1.SyncCursor
+	17, // [17:17] is the sub-list for method output_type
+	17, // [17:17] is the sub-list for method input_type
+	17, // [17:17] is the sub-list for extension type_name
+	17, // [17:17] is the sub-list for extension extendee
+	0,  // [0:17] is the sub-list for field type_name
+}
+
+func init() { file_api_v1_message_proto_init() }
+func file_api_v1_message_proto_init() {
+	if File_api_v1_message_proto != nil {
+		return
+	}
+	file_api_v1_message_proto_msgTypes[17].OneofWrappers = []any{
+		(*Envelope_Message)(nil),
+		(*Envelope_OfflineAck)(nil),
+		(*Envelope_Sync)(nil),
+		(*Envelope_Read)(nil),
+		(*Envelope_Recall)(nil),
+		(*Envelope_Edit)(nil),
+		(*Envelope_React)(nil),
+		(*Envelope_Typing)(nil),
+		(*Envelope_Presence)(nil),
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
@@ -392,7 +2436,7 @@ func file_api_v1_message_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_api_v1_message_proto_rawDesc), len(file_api_v1_message_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   4,
+			NumMessages:   27,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
@@ -6,4 +6,29 @@ type SendMessageRequest struct {
 	ContentType    int32       `json:"content_type" binding:"required"`
 	Body           interface{} `json:"body" binding:"required"`
 	MessageType    int32       `json:"message_type" binding:"required"` // 1=私聊, 2=群聊
+	ReplyToMsgID   string      `json:"reply_to_msg_id"`                 // 可选，回复的消息ID，必须属于同一会话
+	MentionUUIDs   []string    `json:"mention_uuids"`                   // 可选，@ 的群成员UUID，仅群聊
+	MentionAll     bool        `json:"mention_all"`                     // 可选，@所有人，仅群主可用
+	ThreadRootID   string      `json:"thread_root_id"`                  // 可选，话题根消息ID，仅群聊
+}
+
+type ForwardMessageRequest struct {
+	MessageIDs []string        `json:"message_ids" binding:"required"` // 要转发的消息ID，按顺序转发
+	Targets    []ForwardTarget `json:"targets" binding:"required"`     // 转发目标
+}
+
+type ForwardTarget struct {
+	TargetName  string `json:"target_name" binding:"required"`  // 好友用户名或群号
+	MessageType int32  `json:"message_type" binding:"required"` // 1=私聊, 2=群聊
+}
+
+type ScheduleMessageRequest struct {
+	SendMessageRequest
+	SendAt int64 `json:"send_at" binding:"required"` // 计划发送时间（Unix 秒）
+}
+
+type UpdateScheduledMessageRequest struct {
+	ContentType int32       `json:"content_type"` // 可选，与 body 一起修改
+	Body        interface{} `json:"body"`         // 可选，新的消息内容
+	SendAt      int64       `json:"send_at"`      // 可选，新的计划发送时间（Unix 秒）
 }
@@ -18,6 +18,13 @@ type (
 		Kafka       KafkaConfig       `yaml:"Kafka"`
 		Mongo       MongoConfig       `yaml:"MongoDB"`
 		Redis       RedisConfig       `yaml:"Redis"`
+		Chat        ChatConfig        `yaml:"Chat"`
+	}
+
+	// ChatConfig 消息相关的业务配置
+	ChatConfig struct {
+		RecallWindow int `yaml:"recallWindow"` // 允许撤回消息的时间窗口（分钟），未配置时使用默认值
+		MaxPins      int `yaml:"maxPins"`      // 每个会话最多置顶的消息数，未配置时使用默认值
 	}
 
 	RedisConfig struct {
@@ -48,6 +55,7 @@ type (
 		Ingest       string `yaml:"ingest"`
 		Sync_request string `yaml:"sync_request"`
 		Delivery     string `yaml:"delivery"`
+		Signal       string `yaml:"signal"` // 正在输入等瞬时信号，不入库
 	}
 
 	KafkaConfig struct {
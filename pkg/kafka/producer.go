@@ -8,6 +8,12 @@ import (
 	"github.com/segmentio/kafka-go"
 )
 
+// MessageProducer 发送消息到 Kafka 主题，由 Producer 实现，测试中可替换
+type MessageProducer interface {
+	SendMessage(topic string, message []byte) error
+	SendMessageWithKey(topic string, key, message []byte) error
+}
+
 type Producer struct {
 	writer *kafka.Writer
 }
@@ -0,0 +1,112 @@
+package offline
+
+import (
+	pb "MyGoChat/pkg/api/v1"
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"google.golang.org/protobuf/proto"
+)
+
+// 离线消息存储，按用户划分：
+//   - offline_queue:{uuid}   ZSet，member 为消息ID，score 为入队顺序，保证按到达顺序投递
+//   - offline_body:{uuid}    Hash，消息ID -> 序列化后的 pb.Message
+//   - offline_seq:{uuid}     入队计数器，为 ZSet 提供单调递增的 score
+//   - offline_pending:{uuid} 已推送但尚未确认的最大 score，带超时
+//
+// 写入方有两个：Logic 服务在用户所有设备都离线时写入；
+// Gateway 在设备处理过慢、发送队列已满时将无法投递的消息转存（见 Spillable）。
+// 推送与确认由 Logic 服务负责；推送窗口超时仍未确认时，由 Gateway 定期检查（见 Stalled）并请求重新推送
+const (
+	queuePrefix   = "offline_queue:"
+	bodyPrefix    = "offline_body:"
+	seqPrefix     = "offline_seq:"
+	pendingPrefix = "offline_pending:"
+
+	TTL            = time.Hour * 24 * 7
+	PendingTimeout = 60 * time.Second // 推送窗口的超时时间，超时后窗口内未确认的消息需要重新推送
+)
+
+// QueueKey 返回用户离线队列的 Redis Key
+func QueueKey(userUUID string) string {
+	return queuePrefix + userUUID
+}
+
+// BodyKey 返回用户离线消息体的 Redis Key
+func BodyKey(userUUID string) string {
+	return bodyPrefix + userUUID
+}
+
+// PendingKey 返回用户推送窗口位置的 Redis Key
+func PendingKey(userUUID string) string {
+	return pendingPrefix + userUUID
+}
+
+// storeScript 原子地分配入队序号并写入 ZSet 与 Hash，消息已在队列中时不重复写入
+// KEYS: queue, body, seq  ARGV: msgID, msgData, ttl(秒)
+var storeScript = redis.NewScript(`
+if redis.call("HEXISTS", KEYS[2], ARGV[1]) == 1 then
+	return 0
+end
+local score = redis.call("INCR", KEYS[3])
+redis.call("ZADD", KEYS[1], score, ARGV[1])
+redis.call("HSET", KEYS[2], ARGV[1], ARGV[2])
+for i = 1, 3 do
+	redis.call("EXPIRE", KEYS[i], ARGV[3])
+end
+return score
+`)
+
+// Store 将消息存入用户的离线队列，消息会被标记为离线消息，客户端收到后需要确认
+func Store(ctx context.Context, rdb *redis.Client, userUUID string, msg *pb.Message) error {
+	msg.Offline = true
+	msgData, err := proto.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	keys := []string{QueueKey(userUUID), BodyKey(userUUID), seqPrefix + userUUID}
+	return storeScript.Run(ctx, rdb, keys, msg.Id, msgData, int(TTL.Seconds())).Err()
+}
+
+// Spillable 消息在设备发送队列已满时是否应转存到离线队列
+// 只转存 Logic 服务在用户离线时同样会存储的消息：发给他人的聊天消息、撤回和编辑事件。
+// 离线消息本身仍在队列中等待确认，指定设备的消息（同步结果、ACK）与其余瞬时事件由客户端重新同步补齐
+func Spillable(msg *pb.Message) bool {
+	if msg.Offline || msg.TargetDeviceID != "" || msg.RecipientUUID == msg.SenderUUID {
+		return false
+	}
+	switch msg.EventType {
+	case pb.EventTypeMessage, pb.EventTypeRecall, pb.EventTypeEdit:
+		return true
+	}
+	return false
+}
+
+// Stalled 从 userUUIDs 中筛选离线队列中仍有消息、但推送窗口已超时或从未推送的用户
+// 没有新的确认或重连时，这些用户的离线消息不会再被推送
+func Stalled(ctx context.Context, rdb *redis.Client, userUUIDs []string) ([]string, error) {
+	if len(userUUIDs) == 0 {
+		return nil, nil
+	}
+
+	pipe := rdb.Pipeline()
+	queued := make([]*redis.IntCmd, len(userUUIDs))
+	pending := make([]*redis.IntCmd, len(userUUIDs))
+	for i, userUUID := range userUUIDs {
+		queued[i] = pipe.ZCard(ctx, QueueKey(userUUID))
+		pending[i] = pipe.Exists(ctx, PendingKey(userUUID))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	var stalled []string
+	for i, userUUID := range userUUIDs {
+		if queued[i].Val() > 0 && pending[i].Val() == 0 {
+			stalled = append(stalled, userUUID)
+		}
+	}
+	return stalled, nil
+}
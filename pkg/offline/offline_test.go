@@ -0,0 +1,53 @@
+package offline
+
+import (
+	pb "MyGoChat/pkg/api/v1"
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSpillable 测试只有用户离线时同样会存储的消息才转存到离线队列
+func TestSpillable(t *testing.T) {
+	msg := &pb.Message{SenderUUID: "user-a", RecipientUUID: "user-b"}
+	assert.True(t, Spillable(msg))
+
+	msg.EventType = pb.EventTypeRecall
+	assert.True(t, Spillable(msg))
+
+	for _, eventType := range []int32{pb.EventTypeAck, pb.EventTypeReadReceipt, pb.EventTypeTyping, pb.EventTypePresence} {
+		msg.EventType = eventType
+		assert.False(t, Spillable(msg))
+	}
+
+	// 回显给发送者、指定设备和已经是离线消息的都不转存
+	assert.False(t, Spillable(&pb.Message{SenderUUID: "user-a", RecipientUUID: "user-a"}))
+	assert.False(t, Spillable(&pb.Message{RecipientUUID: "user-b", TargetDeviceID: "web"}))
+	assert.False(t, Spillable(&pb.Message{RecipientUUID: "user-b", Offline: true}))
+}
+
+// TestStalled 测试只有队列非空且推送窗口已超时（或从未推送）的用户需要续推
+func TestStalled(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+
+	for _, userUUID := range []string{"waiting", "pushed"} {
+		require.NoError(t, Store(ctx, rdb, userUUID, &pb.Message{Id: "msg-1", RecipientUUID: userUUID}))
+	}
+	require.NoError(t, rdb.Set(ctx, PendingKey("pushed"), 1, PendingTimeout).Err())
+
+	stalled, err := Stalled(ctx, rdb, []string{"waiting", "pushed", "empty"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"waiting"}, stalled)
+
+	// 推送窗口超时后同样需要续推
+	mr.FastForward(PendingTimeout)
+	stalled, err = Stalled(ctx, rdb, []string{"waiting", "pushed", "empty"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"waiting", "pushed"}, stalled)
+}
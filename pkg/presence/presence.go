@@ -0,0 +1,153 @@
+package presence
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// 用户在线状态，由 Gateway 的 Hub 在设备连接、心跳、断开时维护，Logic 服务只读：
+//   - presence:{userUUID}  Hash，deviceID -> "{status}|{心跳时间}"，设备连接时写入、心跳时刷新、断开时删除
+//   - presence_mode:{userUUID}  String，用户选择隐身时为 "invisible"
+//   - last_seen:{userUUID}  String，用户最后一次在线的时间（Unix 秒），隐身期间不更新
+//
+// 用户的状态由所有设备汇总：任一设备在线即为在线，所有设备都离开时为离开，没有设备时为离线。
+// Gateway 崩溃时来不及删除的设备记录在 StaleAfter 内没有心跳后不再计入
+const (
+	devicesPrefix  = "presence:"
+	modePrefix     = "presence_mode:"
+	lastSeenPrefix = "last_seen:"
+	sep            = "|"
+
+	// StaleAfter 设备超过该时间没有心跳视为已离线，应大于 Gateway 的心跳周期
+	StaleAfter = 2 * time.Minute
+)
+
+// Status 在线状态
+type Status string
+
+const (
+	StatusOnline    Status = "online"    // 至少一个设备在线
+	StatusAway      Status = "away"      // 所有在线设备都处于离开状态
+	StatusInvisible Status = "invisible" // 隐身：用户在线，但对其他人显示为离线
+	StatusOffline   Status = "offline"   // 没有在线设备
+)
+
+// Presence 用户的在线状态与最后在线时间
+type Presence struct {
+	UserUUID string `json:"user_uuid"`
+	Status   Status `json:"status"`
+	LastSeen int64  `json:"last_seen"` // Unix 秒，从未上线过时为 0
+}
+
+// Visible 其他用户看到的状态：隐身显示为离线
+func (p Presence) Visible() Presence {
+	if p.Status == StatusInvisible {
+		p.Status = StatusOffline
+	}
+	return p
+}
+
+// setDeviceScript 写入设备状态，并在用户不是隐身时刷新最后在线时间
+// KEYS: devices, mode, lastSeen  ARGV: deviceID, status|now, now, ttl(秒)
+var setDeviceScript = redis.NewScript(`
+redis.call("HSET", KEYS[1], ARGV[1], ARGV[2])
+redis.call("EXPIRE", KEYS[1], ARGV[4])
+if redis.call("GET", KEYS[2]) ~= "invisible" then
+	redis.call("SET", KEYS[3], ARGV[3])
+end
+return 1
+`)
+
+// removeDeviceScript 删除设备状态，并在用户不是隐身时刷新最后在线时间
+// KEYS: devices, mode, lastSeen  ARGV: deviceID, now
+var removeDeviceScript = redis.NewScript(`
+redis.call("HDEL", KEYS[1], ARGV[1])
+if redis.call("GET", KEYS[2]) ~= "invisible" then
+	redis.call("SET", KEYS[3], ARGV[2])
+end
+return 1
+`)
+
+func keys(userUUID string) []string {
+	return []string{devicesPrefix + userUUID, modePrefix + userUUID, lastSeenPrefix + userUUID}
+}
+
+// SetDevice 登记设备在线（连接、心跳或切换在线/离开时调用），status 只能是 StatusOnline 或 StatusAway
+func SetDevice(ctx context.Context, rdb *redis.Client, userUUID, deviceID string, status Status, now time.Time) error {
+	unix := strconv.FormatInt(now.Unix(), 10)
+	value := string(status) + sep + unix
+	return setDeviceScript.Run(ctx, rdb, keys(userUUID), deviceID, value, unix, int(StaleAfter.Seconds())).Err()
+}
+
+// RemoveDevice 登记设备下线
+func RemoveDevice(ctx context.Context, rdb *redis.Client, userUUID, deviceID string, now time.Time) error {
+	return removeDeviceScript.Run(ctx, rdb, keys(userUUID), deviceID, now.Unix()).Err()
+}
+
+// SetInvisible 开启或关闭隐身；关闭隐身时用户仍在线，最后在线时间由下一次心跳刷新
+func SetInvisible(ctx context.Context, rdb *redis.Client, userUUID string, invisible bool) error {
+	if invisible {
+		return rdb.Set(ctx, modePrefix+userUUID, string(StatusInvisible), 0).Err()
+	}
+	return rdb.Del(ctx, modePrefix+userUUID).Err()
+}
+
+// Get 获取用户的真实状态（含隐身），展示给其他用户时应使用 Visible
+func Get(ctx context.Context, rdb *redis.Client, userUUID string, now time.Time) (Presence, error) {
+	list, err := GetMany(ctx, rdb, []string{userUUID}, now)
+	if err != nil {
+		return Presence{}, err
+	}
+	return list[0], nil
+}
+
+// GetMany 批量获取用户的真实状态，结果与 userUUIDs 顺序一致
+func GetMany(ctx context.Context, rdb *redis.Client, userUUIDs []string, now time.Time) ([]Presence, error) {
+	pipe := rdb.Pipeline()
+	devices := make([]*redis.StringStringMapCmd, len(userUUIDs))
+	modes := make([]*redis.StringCmd, len(userUUIDs))
+	lastSeens := make([]*redis.StringCmd, len(userUUIDs))
+	for i, uuid := range userUUIDs {
+		devices[i] = pipe.HGetAll(ctx, devicesPrefix+uuid)
+		modes[i] = pipe.Get(ctx, modePrefix+uuid)
+		lastSeens[i] = pipe.Get(ctx, lastSeenPrefix+uuid)
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	result := make([]Presence, len(userUUIDs))
+	for i, uuid := range userUUIDs {
+		status := Aggregate(devices[i].Val(), now)
+		if status != StatusOffline && modes[i].Val() == string(StatusInvisible) {
+			status = StatusInvisible
+		}
+		lastSeen, _ := strconv.ParseInt(lastSeens[i].Val(), 10, 64)
+		result[i] = Presence{UserUUID: uuid, Status: status, LastSeen: lastSeen}
+	}
+	return result, nil
+}
+
+// Aggregate 汇总用户各设备的状态（deviceID -> "{status}|{心跳时间}"），忽略超过 StaleAfter 没有心跳的设备
+func Aggregate(devices map[string]string, now time.Time) Status {
+	status := StatusOffline
+	for _, value := range devices {
+		s, at, ok := strings.Cut(value, sep)
+		if !ok {
+			continue
+		}
+		heartbeat, err := strconv.ParseInt(at, 10, 64)
+		if err != nil || now.Sub(time.Unix(heartbeat, 0)) > StaleAfter {
+			continue
+		}
+		if Status(s) == StatusOnline {
+			return StatusOnline
+		}
+		status = StatusAway
+	}
+	return status
+}
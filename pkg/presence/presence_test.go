@@ -0,0 +1,32 @@
+package presence
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestPresenceAggregate 测试多设备状态汇总：任一设备在线即在线，全部离开为离开，超时没有心跳的设备不计入
+func TestPresenceAggregate(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	fresh := "|1700000000"
+	stale := "|1699999000"
+
+	assert.Equal(t, StatusOffline, Aggregate(nil, now))
+	assert.Equal(t, StatusOnline, Aggregate(map[string]string{
+		"web":   "away" + fresh,
+		"phone": "online" + fresh,
+	}, now))
+	assert.Equal(t, StatusAway, Aggregate(map[string]string{
+		"web":   "away" + fresh,
+		"phone": "online" + stale,
+	}, now))
+	assert.Equal(t, StatusOffline, Aggregate(map[string]string{
+		"web": "online" + stale,
+		"bad": "online",
+	}, now))
+
+	p := Presence{UserUUID: "a", Status: StatusInvisible, LastSeen: 1}
+	assert.Equal(t, StatusOffline, p.Visible().Status)
+}
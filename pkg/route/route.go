@@ -0,0 +1,174 @@
+package route
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// 用户路由表：一个用户可以同时在多个设备上连接，每个设备连接在某个 Gateway 上
+// Key: user_routes:{userUUID}  类型: Set  成员: "{gatewayID}|{instanceID}|{deviceID}"
+// 由 Gateway 的 Hub 在设备连接时 SADD，断开时 SREM；Logic 服务据此决定投递到哪些 Gateway
+//
+// Gateway 崩溃时来不及删除路由，因此：
+//   - 路由表带有 RouteTTL，由设备心跳刷新，用户所有设备都不再有心跳后整个路由表过期
+//   - 每个 Gateway 定期刷新存活键 gateway_alive:{gatewayID}（带 GatewayTTL），值为当前进程的实例ID，
+//     List 只返回指向存活 Gateway 当前进程的路由，并顺带删除其余的路由
+//
+// 实例ID在 Gateway 进程启动时生成（见 NewInstanceID）：以相同 GATEWAY_ID 重启的 Gateway 不会让崩溃前留下的路由复活
+const (
+	keyPrefix      = "user_routes:"
+	alivePrefix    = "gateway_alive:"
+	RouteTTL       = 2 * time.Minute  // 路由表过期时间，应大于设备心跳周期
+	GatewayTTL     = 30 * time.Second // Gateway 存活键过期时间
+	GatewayRefresh = 10 * time.Second // Gateway 刷新存活键的周期，应小于 GatewayTTL
+)
+
+const sep = "|"
+
+// Route 用户的一个在线设备
+type Route struct {
+	GatewayID string
+	Instance  string // 登记路由的 Gateway 进程实例ID，旧格式的路由为空
+	DeviceID  string
+}
+
+func (r Route) String() string {
+	return r.GatewayID + sep + r.Instance + sep + r.DeviceID
+}
+
+// Parse 解析路由表成员，格式不正确时返回 false
+// 兼容没有实例ID的旧格式 "{gatewayID}|{deviceID}"
+func Parse(member string) (Route, bool) {
+	parts := strings.SplitN(member, sep, 3)
+	if len(parts) < 2 || parts[0] == "" {
+		return Route{}, false
+	}
+	if len(parts) == 2 {
+		return Route{GatewayID: parts[0], DeviceID: parts[1]}, true
+	}
+	return Route{GatewayID: parts[0], Instance: parts[1], DeviceID: parts[2]}, true
+}
+
+// NewInstanceID 生成 Gateway 进程的实例ID，每次启动都不同
+func NewInstanceID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Key 返回用户路由表的 Redis Key
+func Key(userUUID string) string {
+	return keyPrefix + userUUID
+}
+
+// Add 登记设备上线
+func Add(ctx context.Context, rdb *redis.Client, userUUID string, r Route) error {
+	pipe := rdb.TxPipeline()
+	pipe.SAdd(ctx, Key(userUUID), r.String())
+	pipe.Expire(ctx, Key(userUUID), RouteTTL)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// Refresh 设备心跳时延长路由表的过期时间
+func Refresh(ctx context.Context, rdb *redis.Client, userUUID string) error {
+	return rdb.Expire(ctx, Key(userUUID), RouteTTL).Err()
+}
+
+// Remove 登记设备下线
+func Remove(ctx context.Context, rdb *redis.Client, userUUID string, r Route) error {
+	return rdb.SRem(ctx, Key(userUUID), r.String()).Err()
+}
+
+// List 获取用户所有在线设备，用户离线时返回空列表
+// 指向已失效 Gateway 的路由视为离线，并从路由表中删除
+func List(ctx context.Context, rdb *redis.Client, userUUID string) ([]Route, error) {
+	members, err := rdb.SMembers(ctx, Key(userUUID)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	routes := make([]Route, 0, len(members))
+	for _, m := range members {
+		if r, ok := Parse(m); ok {
+			routes = append(routes, r)
+		}
+	}
+	if len(routes) == 0 {
+		return routes, nil
+	}
+
+	alive, err := aliveGateways(ctx, rdb, routes)
+	if err != nil {
+		return nil, err
+	}
+	live, dead := FilterAlive(routes, alive)
+	if len(dead) > 0 {
+		members := make([]interface{}, len(dead))
+		for i, r := range dead {
+			members[i] = r.String()
+		}
+		rdb.SRem(ctx, Key(userUUID), members...)
+	}
+	return live, nil
+}
+
+// FilterAlive 按 Gateway 是否存活划分路由，alive 为 gatewayID -> 当前存活的实例ID
+// 路由必须指向 Gateway 当前的进程；旧格式的路由没有实例ID，只要 Gateway 存活即视为在线
+func FilterAlive(routes []Route, alive map[string]string) (live, dead []Route) {
+	live = make([]Route, 0, len(routes))
+	for _, r := range routes {
+		instance := alive[r.GatewayID]
+		if instance != "" && (r.Instance == "" || r.Instance == instance) {
+			live = append(live, r)
+		} else {
+			dead = append(dead, r)
+		}
+	}
+	return live, dead
+}
+
+// aliveGateways 查询路由涉及的 Gateway 当前存活的实例ID，已失效的 Gateway 不出现在结果中
+func aliveGateways(ctx context.Context, rdb *redis.Client, routes []Route) (map[string]string, error) {
+	pipe := rdb.Pipeline()
+	checks := make(map[string]*redis.StringCmd)
+	for _, r := range routes {
+		if _, ok := checks[r.GatewayID]; !ok {
+			checks[r.GatewayID] = pipe.Get(ctx, alivePrefix+r.GatewayID)
+		}
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	alive := make(map[string]string, len(checks))
+	for gatewayID, cmd := range checks {
+		if instance := cmd.Val(); instance != "" {
+			alive[gatewayID] = instance
+		}
+	}
+	return alive, nil
+}
+
+// KeepAlive 登记或刷新 Gateway 存活键，记录当前进程的实例ID，Gateway 应每隔 GatewayRefresh 调用一次
+func KeepAlive(ctx context.Context, rdb *redis.Client, gatewayID, instance string) error {
+	return rdb.Set(ctx, alivePrefix+gatewayID, instance, GatewayTTL).Err()
+}
+
+// shutdownScript 只删除本进程登记的存活键，不影响以相同 gatewayID 启动的新进程
+var shutdownScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// Shutdown Gateway 正常关闭时删除存活键，指向它的路由立即视为离线
+func Shutdown(ctx context.Context, rdb *redis.Client, gatewayID, instance string) error {
+	return shutdownScript.Run(ctx, rdb, []string{alivePrefix + gatewayID}, instance).Err()
+}
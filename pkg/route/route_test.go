@@ -0,0 +1,80 @@
+package route
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestFilterAlive 测试指向已失效 Gateway 或其旧进程的路由被划为离线
+func TestFilterAlive(t *testing.T) {
+	routes := []Route{
+		{GatewayID: "gw-1", Instance: "a", DeviceID: "web"},
+		{GatewayID: "gw-2", Instance: "b", DeviceID: "phone"},
+		{GatewayID: "gw-1", Instance: "a", DeviceID: "pad"},
+		{GatewayID: "gw-1", Instance: "old", DeviceID: "desktop"},
+	}
+
+	live, dead := FilterAlive(routes, map[string]string{"gw-1": "a"})
+	assert.Equal(t, []Route{routes[0], routes[2]}, live)
+	assert.Equal(t, []Route{routes[1], routes[3]}, dead)
+
+	live, dead = FilterAlive(routes, map[string]string{})
+	assert.Empty(t, live)
+	assert.Len(t, dead, 4)
+
+	// 旧格式的路由没有实例ID，Gateway 存活即视为在线
+	live, _ = FilterAlive([]Route{{GatewayID: "gw-1", DeviceID: "web"}}, map[string]string{"gw-1": "a"})
+	assert.Len(t, live, 1)
+}
+
+// TestParse 测试路由表成员的解析，兼容没有实例ID的旧格式
+func TestParse(t *testing.T) {
+	r := Route{GatewayID: "gw-1", Instance: "a", DeviceID: "web|1"}
+	parsed, ok := Parse(r.String())
+	assert.True(t, ok)
+	assert.Equal(t, r, parsed)
+
+	parsed, ok = Parse("gw-1|phone")
+	assert.True(t, ok)
+	assert.Equal(t, Route{GatewayID: "gw-1", DeviceID: "phone"}, parsed)
+
+	_, ok = Parse("no-separator")
+	assert.False(t, ok)
+}
+
+// TestList_RestartedGateway 测试以相同 gatewayID 重启的 Gateway 不会让崩溃前留下的路由复活
+func TestList_RestartedGateway(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+
+	stale := Route{GatewayID: "gw-1", Instance: "before-crash", DeviceID: "web"}
+	require.NoError(t, Add(ctx, rdb, "user-a", stale))
+
+	// 崩溃后以相同 gatewayID 重启，用户在新进程上重新连接了另一台设备
+	require.NoError(t, KeepAlive(ctx, rdb, "gw-1", "after-restart"))
+	current := Route{GatewayID: "gw-1", Instance: "after-restart", DeviceID: "phone"}
+	require.NoError(t, Add(ctx, rdb, "user-a", current))
+
+	routes, err := List(ctx, rdb, "user-a")
+	assert.NoError(t, err)
+	assert.Equal(t, []Route{current}, routes)
+	members, _ := rdb.SMembers(ctx, Key("user-a")).Result()
+	assert.Equal(t, []string{current.String()}, members, "stale routes are removed")
+
+	// 旧进程迟到的关闭不影响新进程的存活键
+	require.NoError(t, Shutdown(ctx, rdb, "gw-1", "before-crash"))
+	routes, err = List(ctx, rdb, "user-a")
+	assert.NoError(t, err)
+	assert.Len(t, routes, 1)
+
+	require.NoError(t, Shutdown(ctx, rdb, "gw-1", "after-restart"))
+	routes, err = List(ctx, rdb, "user-a")
+	assert.NoError(t, err)
+	assert.Empty(t, routes)
+}